@@ -25,7 +25,13 @@ func WrapAttributeFuncs(af ...AttributeFunc) AttributeFunc {
 func AttrRemoveFullSource(groups []string, a slog.Attr) slog.Attr {
 	// Remove the directory from the source's filename.
 	if a.Key == slog.SourceKey {
-		a.Value = slog.StringValue(filepath.Base(a.Value.String()))
+		if src, ok := a.Value.Any().(*slog.Source); ok && src != nil {
+			trimmed := *src
+			trimmed.File = filepath.Base(src.File)
+			a.Value = slog.AnyValue(&trimmed)
+		} else {
+			a.Value = slog.StringValue(filepath.Base(a.Value.String()))
+		}
 	}
 
 	return a
@@ -34,13 +40,9 @@ func AttrRemoveFullSource(groups []string, a slog.Attr) slog.Attr {
 func AttrFixCustomLogLevelNames(groups []string, a slog.Attr) slog.Attr {
 	// Properly name log levels in output such as NOTICE rather than INFO+2
 	if a.Key == slog.LevelKey {
-		level := a.Value.Any().(slog.Level)
-		levelLabel, exists := CustomLevelNames[level]
-		if !exists {
-			levelLabel = level.String()
+		if level, ok := a.Value.Any().(slog.Level); ok {
+			a.Value = slog.StringValue(levelName(level))
 		}
-
-		a.Value = slog.StringValue(levelLabel)
 	}
 	return a
 }
@@ -0,0 +1,49 @@
+package nmcslog
+
+import (
+	"log/slog"
+)
+
+// OutputKeys overrides the names of the built-in record keys, an empty value keeps the slog default.
+type OutputKeys struct {
+	// Time is the key of the record timestamp.
+	Time string `json:",omitempty" jsonschema:"title=Time Key,example=@timestamp,default=time"`
+	// Level is the key of the record level.
+	Level string `json:",omitempty" jsonschema:"title=Level Key,example=severity,default=level"`
+	// Message is the key of the record message.
+	Message string `json:",omitempty" jsonschema:"title=Message Key,example=message,default=msg"`
+	// Source is the key of the source code position, only present if IncludeSource is enabled.
+	Source string `json:",omitempty" jsonschema:"title=Source Key,example=caller,default=source"`
+}
+
+// IsZero reports whether all keys are left at their defaults.
+func (k OutputKeys) IsZero() bool {
+	return k.Time == "" && k.Level == "" && k.Message == "" && k.Source == ""
+}
+
+// AttrRenameKeys returns an AttributeFunc that renames the built-in record keys according to the given OutputKeys.
+func AttrRenameKeys(keys OutputKeys) AttributeFunc {
+	names := map[string]string{}
+	for key, name := range map[string]string{
+		slog.TimeKey:    keys.Time,
+		slog.LevelKey:   keys.Level,
+		slog.MessageKey: keys.Message,
+		slog.SourceKey:  keys.Source,
+	} {
+		if name != "" && name != key {
+			names[key] = name
+		}
+	}
+
+	return func(groups []string, a slog.Attr) slog.Attr {
+		// Built-in keys are only ever passed at the top level.
+		if len(groups) > 0 {
+			return a
+		}
+		if name, exists := names[a.Key]; exists {
+			a.Key = name
+		}
+
+		return a
+	}
+}
@@ -1,8 +1,11 @@
 package nmcslog
 
 import (
+	"errors"
+	"fmt"
 	"log/slog"
 	"path/filepath"
+	"strings"
 
 	"github.com/mdobak/go-xerrors"
 )
@@ -75,3 +78,57 @@ func fmtErr(err error) slog.Value {
 
 	return slog.GroupValue(groupValues...)
 }
+
+// errorDetails holds the message, type and stack frames of an error attribute.
+type errorDetails struct {
+	Message string
+	Type    string
+	Frames  []stackFrame
+}
+
+// extractError returns the details of the attribute if it holds an error, either as the error value itself or as
+// the `msg` and `trace` group produced by AttrStackTrace.
+func extractError(a slog.Attr) (details errorDetails, ok bool) {
+	switch a.Value.Kind() {
+	case slog.KindAny:
+		err, isErr := a.Value.Any().(error)
+		if !isErr || err == nil {
+			return details, false
+		}
+		details.Message = err.Error()
+		details.Frames = marshalStack(err)
+		// Report the type of the innermost error as the outer ones are usually wrappers.
+		for unwrapped := err; unwrapped != nil; unwrapped = errors.Unwrap(unwrapped) {
+			err = unwrapped
+		}
+		details.Type = fmt.Sprintf("%T", err)
+		return details, true
+	case slog.KindGroup:
+		var hasMessage bool
+		for _, member := range a.Value.Group() {
+			switch member.Key {
+			case "msg":
+				details.Message = member.Value.String()
+				hasMessage = true
+			case "trace":
+				details.Frames, _ = member.Value.Any().([]stackFrame)
+			}
+		}
+		if !hasMessage || (details.Frames == nil && a.Key != "error" && a.Key != "err") {
+			return errorDetails{}, false
+		}
+		return details, true
+	default:
+		return details, false
+	}
+}
+
+// fmtStack formats the stack frames similar to the trace of a Go panic.
+func fmtStack(frames []stackFrame) string {
+	var sb strings.Builder
+	for _, frame := range frames {
+		fmt.Fprintf(&sb, "%s\n\t%s:%d\n", frame.Func, frame.Source, frame.Line)
+	}
+
+	return sb.String()
+}
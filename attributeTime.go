@@ -0,0 +1,85 @@
+package nmcslog
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	// TimeFormatRFC3339 formats the timestamp as RFC3339 with second precision.
+	TimeFormatRFC3339 = "RFC3339"
+	// TimeFormatRFC3339Nano formats the timestamp as RFC3339 with nanosecond precision.
+	TimeFormatRFC3339Nano = "RFC3339NANO"
+	// TimeFormatUnix formats the timestamp as an integer of seconds since the Unix epoch.
+	TimeFormatUnix = "UNIX"
+	// TimeFormatUnixMilli formats the timestamp as an integer of milliseconds since the Unix epoch.
+	TimeFormatUnixMilli = "UNIXMILLI"
+	// TimeFormatUnixMicro formats the timestamp as an integer of microseconds since the Unix epoch.
+	TimeFormatUnixMicro = "UNIXMICRO"
+	// TimeFormatUnixNano formats the timestamp as an integer of nanoseconds since the Unix epoch.
+	TimeFormatUnixNano = "UNIXNANO"
+)
+
+// AttrFormatTime returns an AttributeFunc that converts the record timestamp to the given location (if not nil)
+// and formats it according to the given format, which is either one of the TimeFormat constants or a Go time layout.
+// An empty format keeps the timestamp as a time value for the handler to format.
+func AttrFormatTime(format string, loc *time.Location) AttributeFunc {
+	var formatter func(time.Time) slog.Value
+
+	switch strings.ToUpper(format) {
+	case "":
+		formatter = slog.TimeValue
+	case TimeFormatRFC3339:
+		formatter = func(t time.Time) slog.Value { return slog.StringValue(t.Format(time.RFC3339)) }
+	case TimeFormatRFC3339Nano:
+		formatter = func(t time.Time) slog.Value { return slog.StringValue(t.Format(time.RFC3339Nano)) }
+	case TimeFormatUnix:
+		formatter = func(t time.Time) slog.Value { return slog.Int64Value(t.Unix()) }
+	case TimeFormatUnixMilli:
+		formatter = func(t time.Time) slog.Value { return slog.Int64Value(t.UnixMilli()) }
+	case TimeFormatUnixMicro:
+		formatter = func(t time.Time) slog.Value { return slog.Int64Value(t.UnixMicro()) }
+	case TimeFormatUnixNano:
+		formatter = func(t time.Time) slog.Value { return slog.Int64Value(t.UnixNano()) }
+	default:
+		formatter = func(t time.Time) slog.Value { return slog.StringValue(t.Format(format)) }
+	}
+
+	return func(groups []string, a slog.Attr) slog.Attr {
+		// The record timestamp is only ever passed at the top level.
+		if len(groups) > 0 || a.Key != slog.TimeKey || a.Value.Kind() != slog.KindTime {
+			return a
+		}
+
+		t := a.Value.Time()
+		if loc != nil {
+			t = t.In(loc)
+		}
+		a.Value = formatter(t)
+
+		return a
+	}
+}
+
+// loadTimeZone returns the location for the given zone name, an empty name returns a nil location.
+func loadTimeZone(name string) (loc *time.Location, err error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	switch strings.ToUpper(name) {
+	case "UTC":
+		return time.UTC, nil
+	case "LOCAL":
+		return time.Local, nil
+	}
+
+	loc, err = time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone [%s]: %w", name, err)
+	}
+
+	return loc, nil
+}
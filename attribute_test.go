@@ -0,0 +1,109 @@
+package nmcslog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestOutputHandler_KeysAndTimeFormat(t *testing.T) {
+	stamp := time.Date(2026, 10, 17, 12, 30, 45, 123456789, time.UTC)
+	tests := []struct {
+		name    string
+		handler OutputHandler
+		want    map[string]any
+	}{
+		{
+			name:    "defaults",
+			handler: OutputHandler{Format: FormatJSON},
+			want: map[string]any{
+				"time":  "2026-10-17T12:30:45.123456789Z",
+				"level": "NOTICE",
+				"msg":   "hello",
+			},
+		},
+		{
+			name: "renamed keys",
+			handler: OutputHandler{
+				Format: FormatJSON,
+				Keys:   OutputKeys{Time: "@timestamp", Level: "severity", Message: "message"},
+			},
+			want: map[string]any{
+				"@timestamp": "2026-10-17T12:30:45.123456789Z",
+				"severity":   "NOTICE",
+				"message":    "hello",
+			},
+		},
+		{
+			name:    "unix millis",
+			handler: OutputHandler{Format: FormatJSON, TimeFormat: "unixmilli"},
+			want: map[string]any{
+				"time":  float64(stamp.UnixMilli()),
+				"level": "NOTICE",
+				"msg":   "hello",
+			},
+		},
+		{
+			name:    "layout and zone",
+			handler: OutputHandler{Format: FormatJSON, TimeFormat: time.DateTime, TimeZone: "Asia/Tokyo"},
+			want: map[string]any{
+				"time":  "2026-10-17 21:30:45",
+				"level": "NOTICE",
+				"msg":   "hello",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.handler.Validate(); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			w := &bytes.Buffer{}
+			handler, err := tt.handler.GetHandler(w)
+			if err != nil {
+				t.Fatalf("GetHandler() error = %v", err)
+			}
+			record := slog.NewRecord(stamp, LevelNotice, "hello", 0)
+			if err := handler.Handle(context.Background(), record); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			got := map[string]any{}
+			if err := json.Unmarshal(w.Bytes(), &got); err != nil {
+				t.Fatalf("invalid JSON output %q: %v", w.String(), err)
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("output = %v, want %v", got, tt.want)
+			}
+			for key, value := range tt.want {
+				if got[key] != value {
+					t.Errorf("output[%q] = %v, want %v", key, got[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestOutputHandler_InvalidTimeZone(t *testing.T) {
+	handler := OutputHandler{Format: FormatJSON, TimeZone: "Nowhere/Invalid"}
+	if err := handler.Validate(); err == nil {
+		t.Errorf("Validate() expected error for time zone %q", handler.TimeZone)
+	}
+}
+
+func TestAttrRemoveFullSource(t *testing.T) {
+	src := &slog.Source{Function: "main.main", File: "/home/user/project/main.go", Line: 12}
+	got := AttrRemoveFullSource(nil, slog.Any(slog.SourceKey, src))
+	trimmed, ok := got.Value.Any().(*slog.Source)
+	if !ok {
+		t.Fatalf("AttrRemoveFullSource() returned %T, want *slog.Source", got.Value.Any())
+	}
+	if trimmed.File != "main.go" || trimmed.Line != 12 {
+		t.Errorf("AttrRemoveFullSource() = %+v", trimmed)
+	}
+	if src.File != "/home/user/project/main.go" {
+		t.Errorf("AttrRemoveFullSource() modified the original source: %+v", src)
+	}
+}
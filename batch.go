@@ -0,0 +1,436 @@
+package nmcslog
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultBatchSize is the default max number of records per batch.
+	DefaultBatchSize = 100
+	// DefaultBatchAge is the default max time a record waits before its batch is sent.
+	DefaultBatchAge = time.Second
+	// DefaultBatchBufferSize is the default max number of records waiting to be sent.
+	DefaultBatchBufferSize = 10000
+	// DefaultMaxRetries is the default number of retries of a failed batch.
+	DefaultMaxRetries = 5
+	// DefaultInitialBackoff is the default delay before the first retry, doubling for each following retry.
+	DefaultInitialBackoff = 500 * time.Millisecond
+	// DefaultMaxBackoff is the default max delay between retries.
+	DefaultMaxBackoff = 30 * time.Second
+	// DefaultRetryBudget is the default max time spent retrying a single batch.
+	DefaultRetryBudget = time.Minute
+)
+
+// BatchOptions defines how the records of a batching output are grouped and retried.
+type BatchOptions struct {
+	// Size is the max number of records per batch.
+	Size int `json:",omitempty" jsonschema:"title=Batch Size,example=500,default=100"`
+	// Age is the max time a record waits before its batch is sent, as a Go duration.
+	Age string `json:",omitempty" jsonschema:"title=Batch Age,example=5s,default=1s"`
+	// BufferSize is the max number of records waiting to be sent, further records are dropped.
+	BufferSize int `json:",omitempty" jsonschema:"title=Buffer Size,example=50000,default=10000"`
+	// MaxRetries of a failed batch before it is dropped.
+	MaxRetries int `json:",omitempty" jsonschema:"title=Max Retries,example=10,default=5"`
+	// InitialBackoff is the delay before the first retry, doubling for each following retry with a random jitter.
+	InitialBackoff string `json:",omitempty" jsonschema:"title=Initial Backoff,example=1s,default=500ms"`
+	// MaxBackoff is the max delay between retries.
+	MaxBackoff string `json:",omitempty" jsonschema:"title=Max Backoff,example=1m,default=30s"`
+	// RetryBudget is the max time spent retrying a single batch before it is dropped, and the max time spent sending the
+	// pending batches to a failing destination when closed.
+	RetryBudget string `json:",omitempty" jsonschema:"title=Retry Budget,example=5m,default=1m"`
+}
+
+func (bo *BatchOptions) Validate() (err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: validate config [batch options]: %w", err)
+		}
+	}()
+
+	if bo.Size < 0 {
+		return fmt.Errorf("invalid Size [%d]", bo.Size)
+	}
+	if bo.BufferSize < 0 {
+		return fmt.Errorf("invalid BufferSize [%d]", bo.BufferSize)
+	}
+	if bo.MaxRetries < 0 {
+		return fmt.Errorf("invalid MaxRetries [%d]", bo.MaxRetries)
+	}
+	for name, value := range map[string]string{
+		"Age":            bo.Age,
+		"InitialBackoff": bo.InitialBackoff,
+		"MaxBackoff":     bo.MaxBackoff,
+		"RetryBudget":    bo.RetryBudget,
+	} {
+		if _, err = parseDuration(value, 0); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// settings returns the options with the defaults applied.
+func (bo *BatchOptions) settings() (batchSettings, error) {
+	s := batchSettings{
+		size:       bo.Size,
+		bufferSize: bo.BufferSize,
+		maxRetries: bo.MaxRetries,
+	}
+	if s.size == 0 {
+		s.size = DefaultBatchSize
+	}
+	if s.bufferSize == 0 {
+		s.bufferSize = DefaultBatchBufferSize
+	}
+	if s.maxRetries == 0 {
+		s.maxRetries = DefaultMaxRetries
+	}
+
+	var err error
+	if s.age, err = parseDuration(bo.Age, DefaultBatchAge); err != nil {
+		return s, err
+	}
+	if s.initialBackoff, err = parseDuration(bo.InitialBackoff, DefaultInitialBackoff); err != nil {
+		return s, err
+	}
+	if s.maxBackoff, err = parseDuration(bo.MaxBackoff, DefaultMaxBackoff); err != nil {
+		return s, err
+	}
+	if s.retryBudget, err = parseDuration(bo.RetryBudget, DefaultRetryBudget); err != nil {
+		return s, err
+	}
+
+	return s, nil
+}
+
+// parseDuration parses the Go duration, returning the fallback for an empty value and an error if not positive.
+func parseDuration(value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	// None of the durations can be zero, such as the period of a ticker or the delay of a retry loop.
+	if duration <= 0 {
+		return 0, fmt.Errorf("duration [%s] must be positive", value)
+	}
+
+	return duration, nil
+}
+
+// batchSettings are the BatchOptions with the defaults applied and the durations parsed.
+type batchSettings struct {
+	size           int
+	age            time.Duration
+	bufferSize     int
+	maxRetries     int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	retryBudget    time.Duration
+}
+
+// backoff returns the delay before the given retry, starting at 0, with a random jitter of up to half the delay.
+func (bs batchSettings) backoff(retry int) time.Duration {
+	delay := bs.maxBackoff
+	if retry < 32 && bs.initialBackoff<<retry > 0 && bs.initialBackoff<<retry < bs.maxBackoff {
+		delay = bs.initialBackoff << retry
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// BatchStats are the delivery statistics of a batching output.
+type BatchStats struct {
+	// Records is the number of records delivered.
+	Records uint64
+	// Batches is the number of batches delivered.
+	Batches uint64
+	// Retries is the number of failed attempts that were retried.
+	Retries uint64
+	// FailedBatches is the number of batches dropped after a permanent failure or exhausting the retries.
+	FailedBatches uint64
+	// DroppedRecords is the number of records dropped, either in a failed batch or because the buffer was full.
+	DroppedRecords uint64
+}
+
+// batchError is returned by a batch send function to control whether the batch is retried.
+type batchError struct {
+	err error
+	// permanent failures are not retried.
+	permanent bool
+	// retryAfter overrides the backoff before the next attempt if set.
+	retryAfter time.Duration
+}
+
+func (be *batchError) Error() string {
+	return be.err.Error()
+}
+
+func (be *batchError) Unwrap() error {
+	return be.err
+}
+
+// batchPartialError is returned by a batch send function if only some items of the batch were delivered. The
+// rejected items are dropped while the retry items are sent again, the error controls their retry as usual.
+type batchPartialError[T any] struct {
+	*batchError
+	retry    []T
+	rejected int
+}
+
+// sendEach sends the items of the batch one at a time, for the APIs accepting a single item per request. After a
+// failure only the items not yet delivered are retried.
+func sendEach[T any](batch []T, send func(item T) error) error {
+	for i, item := range batch {
+		if err := send(item); err != nil {
+			if i == 0 {
+				return err
+			}
+			var be *batchError
+			if !errors.As(err, &be) {
+				be = &batchError{err: err}
+			}
+			return &batchPartialError[T]{batchError: be, retry: batch[i:]}
+		}
+	}
+
+	return nil
+}
+
+// batcher groups the items into batches, sending them from a background goroutine once the batch is full or the
+// oldest item reached the max age. Failed batches are retried with an exponential backoff.
+type batcher[T any] struct {
+	settings batchSettings
+	send     func(batch []T) error
+
+	mu      sync.Mutex
+	pending []T
+	closed  bool
+	lastErr error
+
+	flush   chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	// drainDeadline bounds the sending of the pending items once closed and failing, it is only used by the run
+	// goroutine.
+	drainDeadline time.Time
+
+	records        atomic.Uint64
+	batches        atomic.Uint64
+	retries        atomic.Uint64
+	failedBatches  atomic.Uint64
+	droppedRecords atomic.Uint64
+}
+
+func newBatcher[T any](settings batchSettings, send func(batch []T) error) *batcher[T] {
+	b := &batcher[T]{
+		settings: settings,
+		send:     send,
+		flush:    make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go b.run()
+
+	return b
+}
+
+// add queues the item, it is dropped if the buffer is full or the batcher was closed.
+func (b *batcher[T]) add(item T) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		b.droppedRecords.Add(1)
+		return errors.New("batcher closed")
+	}
+	if len(b.pending) >= b.settings.bufferSize {
+		b.droppedRecords.Add(1)
+		return fmt.Errorf("buffer of %d records full", b.settings.bufferSize)
+	}
+
+	b.pending = append(b.pending, item)
+	if len(b.pending) >= b.settings.size {
+		select {
+		case b.flush <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+func (b *batcher[T]) run() {
+	defer close(b.stopped)
+
+	ticker := time.NewTicker(b.settings.age)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.done:
+			b.sendPending()
+			return
+		case <-b.flush:
+		case <-ticker.C:
+		}
+		b.sendPending()
+	}
+}
+
+// sendPending sends the pending items in batches of the max size.
+func (b *batcher[T]) sendPending() {
+	for {
+		b.mu.Lock()
+		n := min(len(b.pending), b.settings.size)
+		batch := b.pending[:n:n]
+		b.pending = b.pending[n:]
+		if len(b.pending) == 0 {
+			b.pending = nil
+		}
+		b.mu.Unlock()
+
+		if n == 0 {
+			return
+		}
+		b.sendBatch(batch)
+	}
+}
+
+// sendBatch sends the batch, retrying failures until the retries or the retry budget are exhausted. Once closed, the
+// batches are dropped a retry budget after the first failure, so Close cannot block for long against an unreachable
+// destination.
+func (b *batcher[T]) sendBatch(batch []T) {
+	if b.drainExpired() {
+		b.failedBatches.Add(1)
+		b.droppedRecords.Add(uint64(len(batch)))
+		b.mu.Lock()
+		b.lastErr = fmt.Errorf("dropped batch of %d records, the retry budget was exhausted while closing", len(batch))
+		b.mu.Unlock()
+		return
+	}
+
+	start := time.Now()
+	for retry := 0; ; retry++ {
+		err := b.send(batch)
+		if err == nil {
+			b.records.Add(uint64(len(batch)))
+			b.batches.Add(1)
+			return
+		}
+
+		var partial *batchPartialError[T]
+		if errors.As(err, &partial) {
+			b.records.Add(uint64(len(batch) - len(partial.retry) - partial.rejected))
+			if partial.rejected > 0 {
+				b.droppedRecords.Add(uint64(partial.rejected))
+				b.mu.Lock()
+				b.lastErr = fmt.Errorf("dropped %d rejected records: %w", partial.rejected, err)
+				b.mu.Unlock()
+			}
+			if len(partial.retry) == 0 {
+				b.batches.Add(1)
+				return
+			}
+			batch = partial.retry
+			err = partial.batchError
+		}
+
+		if b.closing() && b.drainDeadline.IsZero() {
+			b.drainDeadline = time.Now().Add(b.settings.retryBudget)
+		}
+
+		var delay time.Duration
+		var be *batchError
+		permanent := errors.As(err, &be) && be.permanent
+		if be != nil && be.retryAfter > 0 {
+			delay = be.retryAfter
+		} else {
+			delay = b.settings.backoff(retry)
+		}
+
+		exhausted := retry >= b.settings.maxRetries || time.Since(start)+delay > b.settings.retryBudget
+		if permanent || exhausted || b.drainExpired() {
+			b.failedBatches.Add(1)
+			b.droppedRecords.Add(uint64(len(batch)))
+			b.mu.Lock()
+			b.lastErr = fmt.Errorf("dropped batch of %d records after %d attempts: %w", len(batch), retry+1, err)
+			b.mu.Unlock()
+			return
+		}
+
+		b.retries.Add(1)
+		b.wait(delay)
+	}
+}
+
+// wait sleeps for the retry delay. Closing cuts the delay short, the delays while closing end at the drain deadline.
+func (b *batcher[T]) wait(delay time.Duration) {
+	if !b.drainDeadline.IsZero() {
+		time.Sleep(min(delay, time.Until(b.drainDeadline)))
+		return
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-b.done:
+	case <-timer.C:
+	}
+}
+
+// drainExpired reports whether the retry budget elapsed since the first failure once closed.
+func (b *batcher[T]) drainExpired() bool {
+	return !b.drainDeadline.IsZero() && time.Now().After(b.drainDeadline)
+}
+
+func (b *batcher[T]) closing() bool {
+	select {
+	case <-b.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (b *batcher[T]) stats() BatchStats {
+	return BatchStats{
+		Records:        b.records.Load(),
+		Batches:        b.batches.Load(),
+		Retries:        b.retries.Load(),
+		FailedBatches:  b.failedBatches.Load(),
+		DroppedRecords: b.droppedRecords.Load(),
+	}
+}
+
+// Close sends the pending items and stops the background goroutine, the error of the last dropped batch is returned.
+// Against a failing destination, it returns after about a retry budget.
+func (b *batcher[T]) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	b.mu.Unlock()
+
+	close(b.done)
+	<-b.stopped
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.lastErr
+}
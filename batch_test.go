@@ -0,0 +1,135 @@
+package nmcslog
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBatchSettings_Backoff(t *testing.T) {
+	settings := batchSettings{initialBackoff: 100 * time.Millisecond, maxBackoff: time.Second}
+
+	for retry, want := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		want *= time.Millisecond
+		for i := 0; i < 10; i++ {
+			if got := settings.backoff(retry); got < want/2 || got > want {
+				t.Errorf("backoff(%d) = %v, want between %v and %v", retry, got, want/2, want)
+			}
+		}
+	}
+	if got := settings.backoff(100); got < settings.maxBackoff/2 || got > settings.maxBackoff {
+		t.Errorf("backoff(100) = %v, want at most %v", got, settings.maxBackoff)
+	}
+}
+
+func TestBatcher_BufferFull(t *testing.T) {
+	release := make(chan struct{})
+	var sent [][]int
+	b := newBatcher(batchSettings{size: 2, age: time.Hour, bufferSize: 3}, func(batch []int) error {
+		<-release
+		sent = append(sent, batch)
+		return nil
+	})
+
+	// The first batch is held by the send function, so only the buffer size is queued afterward.
+	_ = b.add(1)
+	_ = b.add(2)
+	time.Sleep(50 * time.Millisecond)
+	for i := 3; i <= 6; i++ {
+		err := b.add(i)
+		if (err != nil) != (i == 6) {
+			t.Errorf("add(%d) error = %v", i, err)
+		}
+	}
+
+	close(release)
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if len(sent) != 3 || len(sent[0]) != 2 || len(sent[1]) != 2 || len(sent[2]) != 1 {
+		t.Errorf("sent batches = %v", sent)
+	}
+	if stats := b.stats(); stats.Records != 5 || stats.Batches != 3 || stats.DroppedRecords != 1 {
+		t.Errorf("stats() = %+v", stats)
+	}
+	if err := b.add(7); err == nil {
+		t.Error("add() after Close() should fail")
+	}
+}
+
+func TestBatcher_CloseDrainDeadline(t *testing.T) {
+	var attempts atomic.Int32
+	settings := batchSettings{
+		size: 1, age: time.Hour, bufferSize: 10, maxRetries: 5,
+		initialBackoff: time.Second, maxBackoff: time.Second, retryBudget: 250 * time.Millisecond,
+	}
+	release := make(chan struct{})
+	b := newBatcher(settings, func(batch []int) error {
+		if attempts.Add(1) == 1 {
+			<-release
+		}
+		time.Sleep(100 * time.Millisecond)
+		return errors.New("unreachable")
+	})
+
+	// The first batch is held so the others are still pending once closed.
+	for i := range 10 {
+		_ = b.add(i)
+	}
+	close(release)
+	if err := b.Close(); err == nil {
+		t.Error("Close() error = nil, want the dropped batches")
+	}
+
+	// The batches left once the retry budget has elapsed are dropped without an attempt.
+	if n := attempts.Load(); n >= 10 {
+		t.Errorf("attempts = %d, want fewer than the 10 batches", n)
+	}
+	if stats := b.stats(); stats.DroppedRecords != 10 || stats.FailedBatches != 10 {
+		t.Errorf("stats() = %+v, want all the records dropped", stats)
+	}
+}
+
+func TestSendEach(t *testing.T) {
+	var sent []int
+	err := sendEach([]int{1, 2, 3}, func(item int) error {
+		if item == 2 {
+			return errors.New("unavailable")
+		}
+		sent = append(sent, item)
+		return nil
+	})
+
+	var partial *batchPartialError[int]
+	if !errors.As(err, &partial) {
+		t.Fatalf("sendEach() error = %v, want a partial error", err)
+	}
+	if len(sent) != 1 || len(partial.retry) != 2 || partial.retry[0] != 2 || partial.batchError.permanent {
+		t.Errorf("sent = %v, retry = %v, want the undelivered items retried", sent, partial.retry)
+	}
+}
+
+func TestBatchOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options BatchOptions
+		wantErr bool
+	}{
+		{name: "defaults"},
+		{name: "durations", options: BatchOptions{Age: "5s", InitialBackoff: "1s", MaxBackoff: "1m", RetryBudget: "5m"}},
+		{name: "zero age", options: BatchOptions{Age: "0s"}, wantErr: true},
+		{name: "zero initial backoff", options: BatchOptions{InitialBackoff: "0"}, wantErr: true},
+		{name: "negative retry budget", options: BatchOptions{RetryBudget: "-1m"}, wantErr: true},
+		{name: "invalid max backoff", options: BatchOptions{MaxBackoff: "later"}, wantErr: true},
+		{name: "negative size", options: BatchOptions{Size: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.options.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -18,6 +18,24 @@ const (
 	FormatText OutputFormat = "TEXT"
 	// FormatJSON specifies the usage of the slog JSON output handler.
 	FormatJSON OutputFormat = "JSON"
+	// FormatECS specifies JSON output following the Elastic Common Schema.
+	FormatECS OutputFormat = "ECS"
+	// FormatGCP specifies JSON output following the Google Cloud Logging structured logging fields.
+	FormatGCP OutputFormat = "GCP"
+	// FormatOTEL specifies OTLP/JSON output following the OpenTelemetry log data model.
+	FormatOTEL OutputFormat = "OTEL"
+	// FormatGELF specifies JSON output following the Graylog Extended Log Format.
+	FormatGELF OutputFormat = "GELF"
+	// FormatCEF specifies output in the ArcSight Common Event Format.
+	FormatCEF OutputFormat = "CEF"
+	// FormatLEEF specifies output in the QRadar Log Event Extended Format.
+	FormatLEEF OutputFormat = "LEEF"
+	// FormatTemplate specifies output rendered by the Go text/template of the OutputHandler.
+	FormatTemplate OutputFormat = "TEMPLATE"
+	// FormatMsgPack specifies length-delimited binary MessagePack output, readable with a RecordDecoder.
+	FormatMsgPack OutputFormat = "MSGPACK"
+	// FormatGitHub specifies GitHub Actions workflow commands, so warnings and errors are annotated in CI.
+	FormatGitHub OutputFormat = "GITHUB"
 
 	// LevelTrace defines the Trace Log Level (-8)
 	LevelTrace = slog.LevelDebug - 4
@@ -58,9 +76,63 @@ var CustomLevelNames = map[slog.Leveler]string{
 
 // Config is the root configuration for the logging library.
 type Config struct {
-	Console  ConsoleOutput
-	File     FileOutput
-	Handlers []slog.Handler
+	Console       ConsoleOutput
+	File          FileOutput
+	GELF          GELFOutput
+	Syslog        SyslogOutput
+	Journald      JournaldOutput
+	HTTP          HTTPOutput
+	Loki          LokiOutput
+	Elasticsearch ElasticsearchOutput
+	Splunk        SplunkOutput
+	OTLP          OTLPOutput
+	Fluentd       FluentdOutput
+	Sentry        SentryOutput
+	Webhook       WebhookOutput
+	Network       NetworkOutput
+	Exec          ExecOutput
+	SQL           SQLOutput
+	Memory        MemoryOutput
+	Live          LiveOutput
+	Handlers      []slog.Handler `json:"-"`
+	subscriptions *subscriberHub[slog.Record]
+}
+
+// output is implemented by each of the configurable outputs.
+type output interface {
+	Validate() error
+	GetHandler() (slog.Handler, error)
+	enabled() bool
+}
+
+// namedOutput is an output along with its name for error messages.
+type namedOutput struct {
+	name   string
+	output output
+}
+
+// outputs returns the configurable outputs, in the order their handlers are added.
+func (c *Config) outputs() []namedOutput {
+	return []namedOutput{
+		{"console", &c.Console},
+		{"file", &c.File},
+		{"gelf", &c.GELF},
+		{"syslog", &c.Syslog},
+		{"journald", &c.Journald},
+		{"http", &c.HTTP},
+		{"loki", &c.Loki},
+		{"elasticsearch", &c.Elasticsearch},
+		{"splunk", &c.Splunk},
+		{"otlp", &c.OTLP},
+		{"fluentd", &c.Fluentd},
+		{"sentry", &c.Sentry},
+		{"webhook", &c.Webhook},
+		{"network", &c.Network},
+		{"exec", &c.Exec},
+		{"sql", &c.SQL},
+		{"memory", &c.Memory},
+		{"live", &c.Live},
+	}
 }
 
 // Validate will check for common errors in the configuration.
@@ -71,11 +143,10 @@ func (c *Config) Validate() (err error) {
 		}
 	}()
 
-	if err = c.Console.Validate(); err != nil {
-		return err
-	}
-	if err = c.File.Validate(); err != nil {
-		return err
+	for _, o := range c.outputs() {
+		if err = o.output.Validate(); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -89,20 +160,15 @@ func (c *Config) GetHandlers() (logger *slog.Logger, err error) {
 	}()
 
 	logHandlers := c.Handlers
-	if !c.Console.Disable {
-		var handler slog.Handler
-		handler, err = c.Console.GetHandler()
-		if err != nil {
-			return nil, fmt.Errorf("getting console log handler: %w", err)
+	for _, o := range c.outputs() {
+		if !o.output.enabled() {
+			continue
 		}
-		logHandlers = append(logHandlers, handler)
-	}
 
-	if !c.File.Disable {
 		var handler slog.Handler
-		handler, err = c.File.GetHandler()
+		handler, err = o.output.GetHandler()
 		if err != nil {
-			return nil, fmt.Errorf("getting file log handler: %w", err)
+			return nil, fmt.Errorf("getting %s log handler: %w", o.name, err)
 		}
 		logHandlers = append(logHandlers, handler)
 	}
@@ -119,7 +185,27 @@ func (c *Config) GetHandlers() (logger *slog.Logger, err error) {
 		logHandler = slogmulti.Fanout(logHandlers...)
 	}
 
-	return slog.New(logHandler), nil
+	return slog.New(c.subscriptionTee(logHandler)), nil
+}
+
+// Close releases the resources held by the outputs, such as network connections, and flushes any buffered records.
+func (c *Config) Close() error {
+	var errs []error
+	for _, o := range c.outputs() {
+		if closer, ok := o.output.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("closing %s output: %w", o.name, err))
+			}
+		}
+	}
+
+	c.subscriptionHub().Close()
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("nmcslog: close [root]: %w", err)
+	}
+
+	return nil
 }
 
 // ConsoleOutput defines the settings specific to the console base output.
@@ -127,6 +213,12 @@ type ConsoleOutput struct {
 	OutputHandler
 	// StdOut should only be enabled as a user preference, StdErr is designated for logging and non-interactive output.
 	StdOut bool
+	// DisableCIDetection keeps FormatText when running in GitHub Actions, rather than switching to FormatGitHub.
+	DisableCIDetection bool `json:",omitempty" jsonschema:"title=Disable CI Detection,example=true,default=false"`
+}
+
+func (co *ConsoleOutput) enabled() bool {
+	return !co.Disable
 }
 
 func (co *ConsoleOutput) Validate() (err error) {
@@ -150,7 +242,20 @@ func (co *ConsoleOutput) GetHandler() (handler slog.Handler, err error) {
 		return nil, fmt.Errorf("[%s] %w", co.Format, ErrHandlerDisabled)
 	}
 
-	handler, err = co.OutputHandler.GetHandler(os.Stderr)
+	outputHandler := &co.OutputHandler
+	// An empty format defaults to text as well.
+	textFormat := co.Format == FormatText || co.Format == ""
+	if textFormat && !co.DisableCIDetection && githubActions() {
+		// Decode the level first so the copy shares the LevelVar of the configured output.
+		if err = co.LogLevel.Validate(); err != nil {
+			return nil, err
+		}
+		ciHandler := co.OutputHandler
+		ciHandler.Format = FormatGitHub
+		outputHandler = &ciHandler
+	}
+
+	handler, err = outputHandler.GetHandler(os.Stderr)
 	if err != nil {
 		return nil, fmt.Errorf("getting handler [console]: %w", err)
 	}
@@ -168,6 +273,10 @@ type FileOutput struct {
 	loggingFile string
 }
 
+func (fo *FileOutput) enabled() bool {
+	return !fo.Disable
+}
+
 func (fo *FileOutput) Validate() (err error) {
 	defer func() {
 		if err != nil {
@@ -175,6 +284,9 @@ func (fo *FileOutput) Validate() (err error) {
 		}
 	}()
 
+	if fo.Disable {
+		return nil
+	}
 	if err = fo.OutputHandler.Validate(); err != nil {
 		return err
 	}
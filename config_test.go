@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"log/slog"
 	"reflect"
+	"sync"
 	"testing"
 
 	"github.com/invopop/jsonschema"
@@ -27,10 +28,10 @@ import (
 
 func TestFileOutput_GetPath(t *testing.T) {
 	type fields struct {
-		OutputBase  OutputBase
-		Path        string
-		Rotate      Rotate
-		loggingFile string
+		OutputHandler OutputHandler
+		Path          string
+		Rotate        Rotate
+		loggingFile   string
 	}
 	tests := []struct {
 		name   string
@@ -42,10 +43,10 @@ func TestFileOutput_GetPath(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			fo := &FileOutput{
-				OutputBase:  tt.fields.OutputBase,
-				Path:        tt.fields.Path,
-				Rotate:      tt.fields.Rotate,
-				loggingFile: tt.fields.loggingFile,
+				OutputHandler: tt.fields.OutputHandler,
+				Path:          tt.fields.Path,
+				Rotate:        tt.fields.Rotate,
+				loggingFile:   tt.fields.loggingFile,
 			}
 			if got := fo.GetPath(); got != tt.want {
 				t.Errorf("GetPath() = %v, want %v", got, tt.want)
@@ -56,10 +57,10 @@ func TestFileOutput_GetPath(t *testing.T) {
 
 func TestFileOutput_JSONSchemaExtend(t *testing.T) {
 	type fields struct {
-		OutputBase  OutputBase
-		Path        string
-		Rotate      Rotate
-		loggingFile string
+		OutputHandler OutputHandler
+		Path          string
+		Rotate        Rotate
+		loggingFile   string
 	}
 	type args struct {
 		schema *jsonschema.Schema
@@ -74,10 +75,10 @@ func TestFileOutput_JSONSchemaExtend(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			fo := &FileOutput{
-				OutputBase:  tt.fields.OutputBase,
-				Path:        tt.fields.Path,
-				Rotate:      tt.fields.Rotate,
-				loggingFile: tt.fields.loggingFile,
+				OutputHandler: tt.fields.OutputHandler,
+				Path:          tt.fields.Path,
+				Rotate:        tt.fields.Rotate,
+				loggingFile:   tt.fields.loggingFile,
 			}
 			fo.JSONSchemaExtend(tt.args.schema)
 		})
@@ -215,22 +216,57 @@ func TestSetDebugLogger(t *testing.T) {
 	type args struct {
 		l *slog.Logger
 	}
+	custom := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
 	tests := []struct {
 		name string
 		args args
 		want *slog.Logger
 	}{
-		// TODO: Add test cases.
+		{name: "custom logger", args: args{l: custom}, want: custom},
 	}
+	previousDebug, previousDefault := debugLogger.Load(), defaultLogger
+	t.Cleanup(func() {
+		debugLogger.Store(previousDebug)
+		defaultLogger = previousDefault
+	})
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			if got := SetDebugLogger(tt.args.l); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("SetDebugLogger() = %v, want %v", got, tt.want)
 			}
+			if DebugLogger() != tt.want || Logger() != previousDefault {
+				t.Errorf("DebugLogger() = %v, Logger() = %v, want only the debug logger replaced", DebugLogger(), Logger())
+			}
 		})
 	}
 }
 
+func TestDebugLogger_Concurrent(t *testing.T) {
+	previous := debugLogger.Load()
+	t.Cleanup(func() { debugLogger.Store(previous) })
+	debugLogger.Store(nil)
+
+	// The outputs log to the DebugLogger from their goroutines while the application may replace it.
+	custom := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if i == 0 {
+				SetDebugLogger(custom)
+			} else if DebugLogger() == nil {
+				t.Error("DebugLogger() = nil")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if DebugLogger() != custom {
+		t.Errorf("DebugLogger() = %v, want the logger set", DebugLogger())
+	}
+}
+
 func TestSetDefaultLogger(t *testing.T) {
 	type args struct {
 		l *slog.Logger
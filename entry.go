@@ -0,0 +1,367 @@
+package nmcslog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// entry is a log record with the handler groups and attributes resolved, it is the common input of the
+// structured formats and of the record based outputs.
+type entry struct {
+	Time    time.Time
+	Level   slog.Level
+	Message string
+	// PC is the program counter of the logging call, zero if unknown.
+	PC uintptr
+	// Source is only set if the handler was configured to include the source.
+	Source *slog.Source
+	// Attrs are the resolved attributes of the handler and the record, groups are nested as slog.KindGroup values.
+	Attrs []slog.Attr
+	// Context the record was logged with.
+	Context context.Context
+}
+
+// entryWriter receives the resolved entries of an entryHandler.
+type entryWriter interface {
+	writeEntry(e *entry) error
+}
+
+// entryEncoder appends the encoded entry to the buffer.
+type entryEncoder func(buf *bytes.Buffer, e *entry) error
+
+// groupOrAttrs holds either a group name or the attributes added by WithGroup and WithAttrs respectively.
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
+}
+
+// entryHandler is a slog.Handler that resolves records into entries for an entryWriter.
+// The ReplaceAttr function is only applied to the attributes, the built-in time, level, message and source
+// values are passed to the entryWriter as is so the formats can map them onto their own fields.
+type entryHandler struct {
+	writer     entryWriter
+	opts       slog.HandlerOptions
+	fullSource bool
+	goas       []groupOrAttrs
+}
+
+func newEntryHandler(writer entryWriter, opts *slog.HandlerOptions, fullSource bool) *entryHandler {
+	h := &entryHandler{
+		writer:     writer,
+		fullSource: fullSource,
+	}
+	if opts != nil {
+		h.opts = *opts
+	}
+
+	return h
+}
+
+func (h *entryHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+
+	return level >= minLevel
+}
+
+func (h *entryHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	return h.withGroupOrAttrs(groupOrAttrs{group: name})
+}
+
+func (h *entryHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	// Resolve the attributes once, using the groups that are open at this point.
+	var resolved []slog.Attr
+	groups := h.groups()
+	for _, a := range attrs {
+		resolved = h.appendAttr(resolved, groups, a)
+	}
+
+	return h.withGroupOrAttrs(groupOrAttrs{attrs: resolved})
+}
+
+func (h *entryHandler) withGroupOrAttrs(goa groupOrAttrs) *entryHandler {
+	h2 := *h
+	h2.goas = make([]groupOrAttrs, len(h.goas)+1)
+	copy(h2.goas, h.goas)
+	h2.goas[len(h2.goas)-1] = goa
+
+	return &h2
+}
+
+func (h *entryHandler) groups() []string {
+	var groups []string
+	for _, goa := range h.goas {
+		if goa.group != "" {
+			groups = append(groups, goa.group)
+		}
+	}
+
+	return groups
+}
+
+func (h *entryHandler) Handle(ctx context.Context, record slog.Record) error {
+	e := &entry{
+		Time:    record.Time,
+		Level:   record.Level,
+		Message: record.Message,
+		PC:      record.PC,
+		Context: ctx,
+	}
+
+	if h.opts.AddSource && record.PC != 0 {
+		frames := runtime.CallersFrames([]uintptr{record.PC})
+		frame, _ := frames.Next()
+		e.Source = &slog.Source{
+			Function: frame.Function,
+			File:     frame.File,
+			Line:     frame.Line,
+		}
+		if !h.fullSource {
+			e.Source.File = filepath.Base(e.Source.File)
+		}
+	}
+
+	groups := h.groups()
+	var attrs []slog.Attr
+	record.Attrs(func(a slog.Attr) bool {
+		attrs = h.appendAttr(attrs, groups, a)
+		return true
+	})
+
+	// Nest the record attributes within the open groups, adding the handler attributes along the way.
+	for i := len(h.goas) - 1; i >= 0; i-- {
+		goa := h.goas[i]
+		if goa.group == "" {
+			attrs = append(slices.Clip(goa.attrs), attrs...)
+		} else if len(attrs) > 0 {
+			attrs = []slog.Attr{slog.Attr{Key: goa.group, Value: slog.GroupValue(attrs...)}}
+		}
+	}
+	e.Attrs = attrs
+
+	return h.writer.writeEntry(e)
+}
+
+// appendAttr resolves the attribute and appends it to dst, empty attributes and groups are dropped and
+// groups without a key are inlined.
+func (h *entryHandler) appendAttr(dst []slog.Attr, groups []string, a slog.Attr) []slog.Attr {
+	a.Value = a.Value.Resolve()
+
+	if a.Value.Kind() == slog.KindGroup {
+		members := a.Value.Group()
+		if len(members) == 0 {
+			return dst
+		}
+		if a.Key == "" {
+			for _, member := range members {
+				dst = h.appendAttr(dst, groups, member)
+			}
+			return dst
+		}
+
+		groupPath := append(slices.Clip(groups), a.Key)
+		var resolved []slog.Attr
+		for _, member := range members {
+			resolved = h.appendAttr(resolved, groupPath, member)
+		}
+		if len(resolved) == 0 {
+			return dst
+		}
+
+		return append(dst, slog.Attr{Key: a.Key, Value: slog.GroupValue(resolved...)})
+	}
+
+	if h.opts.ReplaceAttr != nil {
+		a = h.opts.ReplaceAttr(groups, a)
+		a.Value = a.Value.Resolve()
+	}
+	if a.Equal(slog.Attr{}) {
+		return dst
+	}
+
+	return append(dst, a)
+}
+
+// encodedWriter encodes the entries and writes each of them to the underlying writer with a single call.
+type encodedWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	encode entryEncoder
+}
+
+func newEncodedWriter(w io.Writer, encode entryEncoder) *encodedWriter {
+	return &encodedWriter{
+		mu:     &sync.Mutex{},
+		w:      w,
+		encode: encode,
+	}
+}
+
+func (ew *encodedWriter) writeEntry(e *entry) error {
+	buf := &bytes.Buffer{}
+	if err := ew.encode(buf, e); err != nil {
+		return fmt.Errorf("encoding log entry: %w", err)
+	}
+
+	ew.mu.Lock()
+	defer ew.mu.Unlock()
+
+	_, err := ew.w.Write(buf.Bytes())
+	return err
+}
+
+// attrValue converts the value into a type that can be marshalled by encoding/json, groups become maps and
+// errors become their message.
+func attrValue(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindGroup:
+		return attrsMap(v.Group())
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339Nano)
+	case slog.KindDuration:
+		return v.Duration().Nanoseconds()
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			if _, marshaler := err.(json.Marshaler); !marshaler {
+				return err.Error()
+			}
+		}
+		return v.Any()
+	default:
+		return v.Any()
+	}
+}
+
+// attrsMap converts the attributes into a map, if a key is repeated the last value wins.
+func attrsMap(attrs []slog.Attr) map[string]any {
+	m := make(map[string]any, len(attrs))
+	for _, a := range attrs {
+		m[a.Key] = attrValue(a.Value)
+	}
+
+	return m
+}
+
+// flattenAttrs calls fn for each attribute that is not a group, the keys of grouped attributes are prefixed by the
+// group keys joined with the separator.
+func flattenAttrs(attrs []slog.Attr, separator string, fn func(key string, value slog.Value)) {
+	var walk func(prefix string, attrs []slog.Attr)
+	walk = func(prefix string, attrs []slog.Attr) {
+		for _, a := range attrs {
+			if a.Value.Kind() == slog.KindGroup {
+				walk(prefix+a.Key+separator, a.Value.Group())
+				continue
+			}
+			fn(prefix+a.Key, a.Value)
+		}
+	}
+	walk("", attrs)
+}
+
+// quoteValue quotes the value if it is empty or contains spaces or special characters, as the TextHandler does.
+func quoteValue(s string) string {
+	if s == "" || strings.ContainsFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '"' || r == '=' || !unicode.IsPrint(r)
+	}) {
+		return strconv.Quote(s)
+	}
+
+	return s
+}
+
+// takeAttr removes the attribute at the dotted group path and returns its value along with the remaining
+// attributes, groups left empty are removed as well. The given attributes are not modified.
+func takeAttr(attrs []slog.Attr, path string) ([]slog.Attr, slog.Value, bool) {
+	key, rest, nested := strings.Cut(path, ".")
+	for i, a := range attrs {
+		if nested && a.Value.Kind() == slog.KindGroup && a.Key == key {
+			members, value, found := takeAttr(a.Value.Group(), rest)
+			if !found {
+				continue
+			}
+			remaining := slices.Delete(slices.Clone(attrs), i, i+1)
+			if len(members) > 0 {
+				remaining = slices.Insert(remaining, i, slog.Attr{Key: a.Key, Value: slog.GroupValue(members...)})
+			}
+			return remaining, value, true
+		}
+		if a.Key == path && a.Value.Kind() != slog.KindGroup {
+			return slices.Delete(slices.Clone(attrs), i, i+1), a.Value, true
+		}
+	}
+
+	return attrs, slog.Value{}, false
+}
+
+// setPath sets the value at the dotted path within the map, creating or replacing the intermediate maps.
+func setPath(m map[string]any, path string, value any) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := m[key].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			m[key] = next
+		}
+		m = next
+	}
+	m[keys[len(keys)-1]] = value
+}
+
+// writeJSONObject writes the map as a JSON object, the leading keys are written first in the given order
+// and the remaining keys follow sorted.
+func writeJSONObject(buf *bytes.Buffer, m map[string]any, leading ...string) error {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		if !slices.Contains(leading, key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	buf.WriteByte('{')
+	first := true
+	for _, key := range slices.Concat(leading, keys) {
+		value, exists := m[key]
+		if !exists {
+			continue
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("marshalling %q: %w", key, err)
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		name, _ := json.Marshal(key)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(data)
+	}
+	buf.WriteByte('}')
+
+	return nil
+}
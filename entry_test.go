@@ -0,0 +1,51 @@
+package nmcslog
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+)
+
+type collectEntries struct {
+	entries []*entry
+}
+
+func (ce *collectEntries) writeEntry(e *entry) error {
+	ce.entries = append(ce.entries, e)
+	return nil
+}
+
+func TestEntryHandler_Groups(t *testing.T) {
+	collector := &collectEntries{}
+	replace := func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == "secret" {
+			return slog.Attr{}
+		}
+		return a
+	}
+	handler := newEntryHandler(collector, &slog.HandlerOptions{ReplaceAttr: replace}, false)
+
+	logger := slog.New(handler).With("app", "demo").WithGroup("req").With("id", 1).WithGroup("empty")
+	logger.InfoContext(context.Background(), "first", "secret", "hidden")
+	logger.Info("second", "status", 200, slog.Group("", "inline", true))
+
+	if len(collector.entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(collector.entries))
+	}
+
+	tests := []struct {
+		name  string
+		entry *entry
+		want  string
+	}{
+		{"empty group dropped", collector.entries[0], "[app=demo req=[id=1]]"},
+		{"nested group", collector.entries[1], "[app=demo req=[id=1 empty=[status=200 inline=true]]]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := slog.GroupValue(tt.entry.Attrs...).String(); got != tt.want {
+				t.Errorf("attrs = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
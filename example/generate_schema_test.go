@@ -59,7 +59,7 @@ func Example_generateSchema() {
 	f, _ := os.OpenFile("./config.schema.json", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
 	defer func() {
 		if err := f.Close(); err != nil {
-			slog.Error("Closing Schema File", err)
+			slog.Error("Closing Schema File", "error", err)
 			panic(err)
 		}
 	}()
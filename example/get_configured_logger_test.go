@@ -9,7 +9,7 @@ import (
 
 func Example_getConfiguredLogger() {
 	logLevel := nmcslog.LogLevel{Level: nmcslog.LevelNotice.String()}
-	baseConfig := nmcslog.OutputBase{
+	baseConfig := nmcslog.OutputHandler{
 		Disable:           false,
 		LogLevel:          logLevel,
 		Format:            nmcslog.FormatText,
@@ -17,8 +17,8 @@ func Example_getConfiguredLogger() {
 		IncludeFullSource: true,
 	}
 	consoleConfig := nmcslog.ConsoleOutput{
-		OutputBase: baseConfig,
-		StdOut:     false,
+		OutputHandler: baseConfig,
+		StdOut:        false,
 	}
 	rotateConfig := nmcslog.Rotate{
 		Disable: false,
@@ -28,15 +28,15 @@ func Example_getConfiguredLogger() {
 		MaxAge:  7,
 	}
 	fileConfig := nmcslog.FileOutput{
-		OutputBase: baseConfig,
-		Path:       "/tmp",
-		Rotate:     rotateConfig,
+		OutputHandler: baseConfig,
+		Path:          "/tmp",
+		Rotate:        rotateConfig,
 	}
 	customHandlerConfig := nmcslog.FileOutput{
-		OutputBase: nmcslog.OutputBase{},
-		Path:       "/tmp",
-		Filename:   "custom",
-		Rotate:     rotateConfig,
+		OutputHandler: nmcslog.OutputHandler{},
+		Path:          "/tmp",
+		Filename:      "custom",
+		Rotate:        rotateConfig,
 	}
 	customHandler, err := customHandlerConfig.GetHandler()
 	if err != nil {
@@ -68,7 +68,7 @@ func Example_getConfiguredLogger() {
 	namedLogger.Log(context.Background(), nmcslog.LevelNotice, "NOTICE MESSAGE")
 	namedLogger.Warn("WARNING MESSAGE")
 	namedLogger.Error("ERROR MESSAGE")
-	namedLogger.Log(context.Background(), nmcslog.LevelEmergency, "EMERGENCY MESSAGE")
+	namedLogger.Log(context.Background(), nmcslog.LevelFatal, "EMERGENCY MESSAGE")
 	print("LOGGER COMPLETE")
 	// NOTE: print() does not count as output!
 	// NOTE: The // Output: comment must be "alone"
@@ -0,0 +1,134 @@
+package nmcslog
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LogEntry is a resolved record as kept by the memory output and sent by the live output, encoded as JSON.
+type LogEntry struct {
+	Time    time.Time      `json:"time"`
+	Level   string         `json:"level"`
+	Message string         `json:"msg"`
+	Source  string         `json:"source,omitempty"`
+	Attrs   map[string]any `json:"attrs,omitempty"`
+	level   slog.Level
+}
+
+func newLogEntry(e *entry) LogEntry {
+	le := LogEntry{
+		Time:    e.Time,
+		Level:   levelName(e.Level),
+		Message: e.Message,
+		level:   e.Level,
+	}
+	if e.Source != nil {
+		le.Source = fmt.Sprintf("%s:%d", e.Source.File, e.Source.Line)
+	}
+	if len(e.Attrs) > 0 {
+		le.Attrs = attrsMap(e.Attrs)
+	}
+
+	return le
+}
+
+// RecordFilter selects the records by level, time, message and attributes, the zero value matches all the records.
+type RecordFilter struct {
+	// Level is the min level of the records, nil matches all the levels.
+	Level slog.Leveler
+	// Since excludes the records before the time if set.
+	Since time.Time
+	// Until excludes the records after the time if set.
+	Until time.Time
+	// Message is a substring of the record messages.
+	Message string
+	// Attrs are the string values of the record attributes, the keys of grouped attributes are joined with a dot.
+	Attrs map[string]string
+	// Limit returns only the most recent matching records if positive, it only applies to queries.
+	Limit int
+}
+
+func (rf *RecordFilter) match(le *LogEntry) bool {
+	if rf.Level != nil && le.level < rf.Level.Level() {
+		return false
+	}
+	if !rf.Since.IsZero() && le.Time.Before(rf.Since) {
+		return false
+	}
+	if !rf.Until.IsZero() && le.Time.After(rf.Until) {
+		return false
+	}
+	if rf.Message != "" && !strings.Contains(le.Message, rf.Message) {
+		return false
+	}
+	for key, want := range rf.Attrs {
+		value, ok := entryAttr(le.Attrs, key)
+		if !ok || fmt.Sprint(value) != want {
+			return false
+		}
+	}
+
+	return true
+}
+
+// entryAttr returns the value of the attribute, looking up the groups of a dotted key.
+func entryAttr(attrs map[string]any, key string) (any, bool) {
+	if value, ok := attrs[key]; ok {
+		return value, true
+	}
+
+	group, rest, found := strings.Cut(key, ".")
+	if !found {
+		return nil, false
+	}
+	nested, ok := attrs[group].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+
+	return entryAttr(nested, rest)
+}
+
+// recordFilterFromQuery parses the filter from the query parameters level, since and until as RFC 3339 times,
+// message, attr as key=value which can be repeated, and limit.
+func recordFilterFromQuery(r *http.Request) (filter RecordFilter, err error) {
+	query := r.URL.Query()
+
+	if value := query.Get("level"); value != "" {
+		ll := LogLevel{Level: value}
+		level, err := ll.GetSlogLevel()
+		if err != nil {
+			return filter, fmt.Errorf("invalid level [%s]", value)
+		}
+		filter.Level = level
+	}
+	for name, t := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if value := query.Get(name); value != "" {
+			if *t, err = time.Parse(time.RFC3339Nano, value); err != nil {
+				return filter, fmt.Errorf("invalid %s [%s], must be an RFC 3339 time", name, value)
+			}
+		}
+	}
+	filter.Message = query.Get("message")
+	for _, attr := range query["attr"] {
+		key, value, found := strings.Cut(attr, "=")
+		if !found || key == "" {
+			return filter, fmt.Errorf("invalid attr [%s], must be key=value", attr)
+		}
+		if filter.Attrs == nil {
+			filter.Attrs = map[string]string{}
+		}
+		filter.Attrs[key] = value
+	}
+	if value := query.Get("limit"); value != "" {
+		if filter.Limit, err = strconv.Atoi(value); err != nil || filter.Limit < 0 {
+			return filter, fmt.Errorf("invalid limit [%s]", value)
+		}
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,58 @@
+package nmcslog
+
+import (
+	"bytes"
+	"strings"
+	"time"
+)
+
+// ecsVersion is the version of the Elastic Common Schema the FormatECS output conforms to.
+const ecsVersion = "8.11.0"
+
+// encodeECS returns an entryEncoder writing the entry as an Elastic Common Schema JSON document.
+// The resource attributes, such as service.name, are added as nested ECS fields.
+func encodeECS(resource map[string]string) entryEncoder {
+	return func(buf *bytes.Buffer, e *entry) error {
+		doc := map[string]any{}
+
+		for _, a := range e.Attrs {
+			if _, isError := doc["error"]; !isError {
+				if details, ok := extractError(a); ok {
+					ecsError := map[string]any{"message": details.Message}
+					if details.Type != "" {
+						ecsError["type"] = details.Type
+					}
+					if len(details.Frames) > 0 {
+						ecsError["stack_trace"] = fmtStack(details.Frames)
+					}
+					doc["error"] = ecsError
+					continue
+				}
+			}
+			doc[a.Key] = attrValue(a.Value)
+		}
+
+		for key, value := range resource {
+			setPath(doc, key, value)
+		}
+
+		doc["@timestamp"] = e.Time.UTC().Format(time.RFC3339Nano)
+		doc["log.level"] = strings.ToLower(levelName(e.Level))
+		doc["message"] = e.Message
+		doc["ecs.version"] = ecsVersion
+		setPath(doc, "log.syslog.severity.code", syslogSeverity(e.Level))
+
+		if e.Source != nil {
+			setPath(doc, "log.origin.file.name", e.Source.File)
+			setPath(doc, "log.origin.file.line", e.Source.Line)
+			setPath(doc, "log.origin.function", e.Source.Function)
+		}
+
+		if err := writeJSONObject(buf, doc, "@timestamp", "log.level", "message"); err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+
+		return nil
+	}
+}
@@ -0,0 +1,83 @@
+package nmcslog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/mdobak/go-xerrors"
+)
+
+func TestFormatECS(t *testing.T) {
+	w := &bytes.Buffer{}
+	output := OutputHandler{
+		Format:        FormatECS,
+		LogLevel:      LogLevel{Level: "TRACE"},
+		IncludeSource: true,
+		Resource:      map[string]string{"service.name": "api"},
+	}
+	handler, err := output.GetHandler(w)
+	if err != nil {
+		t.Fatalf("GetHandler() error = %v", err)
+	}
+
+	logger := slog.New(handler).With("request", 7)
+	logger.Log(context.Background(), LevelNotice, "served",
+		slog.Group("http", "status", 200),
+		slog.Any("error", xerrors.New("broken pipe")),
+	)
+
+	doc := map[string]any{}
+	if err := json.Unmarshal(w.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", w.String(), err)
+	}
+	if !strings.HasPrefix(w.String(), `{"@timestamp":`) {
+		t.Errorf("output does not start with @timestamp: %s", w.String())
+	}
+
+	tests := []struct {
+		path string
+		want any
+	}{
+		{"log.level", "notice"},
+		{"message", "served"},
+		{"ecs.version", ecsVersion},
+		{"request", float64(7)},
+		{"http.status", float64(200)},
+		{"service.name", "api"},
+		{"log.origin.file.name", "formatECS_test.go"},
+		{"log.syslog.severity.code", float64(syslogNotice)},
+		{"error.message", "broken pipe"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := lookupPath(doc, tt.path); got != tt.want {
+				t.Errorf("%s = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	if trace, _ := lookupPath(doc, "error.stack_trace").(string); !strings.Contains(trace, "formatECS_test.go") {
+		t.Errorf("error.stack_trace = %q, want the test file", trace)
+	}
+}
+
+// lookupPath returns the value at the dotted path, preferring literal dotted keys over nested maps.
+func lookupPath(m map[string]any, path string) any {
+	if value, exists := m[path]; exists {
+		return value
+	}
+	key, rest, found := strings.Cut(path, ".")
+	if !found {
+		return nil
+	}
+	next, ok := m[key].(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	return lookupPath(next, rest)
+}
@@ -0,0 +1,90 @@
+package nmcslog
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	gcpSourceLocationKey = "logging.googleapis.com/sourceLocation"
+	gcpTraceKey          = "logging.googleapis.com/trace"
+	gcpSpanIDKey         = "logging.googleapis.com/spanId"
+	gcpTraceSampledKey   = "logging.googleapis.com/trace_sampled"
+	gcpLabelsKey         = "logging.googleapis.com/labels"
+	// gcpProjectResource is the resource attribute holding the Google Cloud project ID.
+	gcpProjectResource = "cloud.account.id"
+)
+
+// gcpSeverity maps the level onto the Google Cloud Logging LogSeverity names.
+func gcpSeverity(level slog.Level) string {
+	switch {
+	case level >= LevelFatal+8:
+		return "EMERGENCY"
+	case level >= LevelFatal+4:
+		return "ALERT"
+	case level >= LevelFatal:
+		return "CRITICAL"
+	case level >= LevelError:
+		return "ERROR"
+	case level >= LevelWarn:
+		return "WARNING"
+	case level >= LevelNotice:
+		return "NOTICE"
+	case level >= LevelInfo:
+		return "INFO"
+	default:
+		return "DEBUG"
+	}
+}
+
+// encodeGCP returns an entryEncoder writing the entry as a Google Cloud Logging structured JSON payload.
+// The resource attributes are added as labels, the project used for the trace name is taken from the
+// cloud.account.id resource attribute or else the GOOGLE_CLOUD_PROJECT environment variable.
+func encodeGCP(resource map[string]string) entryEncoder {
+	project := resource[gcpProjectResource]
+	if project == "" {
+		project = os.Getenv("GOOGLE_CLOUD_PROJECT")
+	}
+
+	return func(buf *bytes.Buffer, e *entry) error {
+		doc := attrsMap(e.Attrs)
+
+		doc["time"] = e.Time.Format(time.RFC3339Nano)
+		doc["severity"] = gcpSeverity(e.Level)
+		doc["message"] = e.Message
+
+		if e.Source != nil {
+			doc[gcpSourceLocationKey] = map[string]any{
+				"file":     e.Source.File,
+				"line":     strconv.Itoa(e.Source.Line),
+				"function": e.Source.Function,
+			}
+		}
+
+		if trace, ok := TraceFromContext(e.Context); ok {
+			if project != "" {
+				doc[gcpTraceKey] = "projects/" + project + "/traces/" + trace.TraceID
+			} else {
+				doc[gcpTraceKey] = trace.TraceID
+			}
+			if trace.SpanID != "" {
+				doc[gcpSpanIDKey] = trace.SpanID
+			}
+			doc[gcpTraceSampledKey] = trace.Sampled
+		}
+
+		if len(resource) > 0 {
+			doc[gcpLabelsKey] = resource
+		}
+
+		if err := writeJSONObject(buf, doc, "time", "severity", "message"); err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+
+		return nil
+	}
+}
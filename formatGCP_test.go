@@ -0,0 +1,77 @@
+package nmcslog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestGCPSeverity(t *testing.T) {
+	tests := []struct {
+		level slog.Level
+		want  string
+	}{
+		{LevelTrace, "DEBUG"},
+		{LevelDebug, "DEBUG"},
+		{LevelInfo, "INFO"},
+		{LevelNotice, "NOTICE"},
+		{LevelWarn, "WARNING"},
+		{LevelError, "ERROR"},
+		{LevelFatal, "CRITICAL"},
+		{LevelFatal + 4, "ALERT"},
+		{LevelFatal + 8, "EMERGENCY"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			if got := gcpSeverity(tt.level); got != tt.want {
+				t.Errorf("gcpSeverity(%v) = %v, want %v", tt.level, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatGCP(t *testing.T) {
+	w := &bytes.Buffer{}
+	output := OutputHandler{
+		Format:        FormatGCP,
+		IncludeSource: true,
+		Resource:      map[string]string{gcpProjectResource: "my-project"},
+	}
+	handler, err := output.GetHandler(w)
+	if err != nil {
+		t.Fatalf("GetHandler() error = %v", err)
+	}
+
+	ctx := ContextWithTrace(context.Background(), TraceContext{
+		TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:  "00f067aa0ba902b7",
+		Sampled: true,
+	})
+	slog.New(handler).Log(ctx, LevelNotice, "deployed", "version", "1.2.3")
+
+	doc := map[string]any{}
+	if err := json.Unmarshal(w.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", w.String(), err)
+	}
+
+	want := map[string]any{
+		"severity":         "NOTICE",
+		"message":          "deployed",
+		"version":          "1.2.3",
+		gcpTraceKey:        "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736",
+		gcpSpanIDKey:       "00f067aa0ba902b7",
+		gcpTraceSampledKey: true,
+	}
+	for key, value := range want {
+		if doc[key] != value {
+			t.Errorf("%s = %v, want %v", key, doc[key], value)
+		}
+	}
+
+	location, _ := doc[gcpSourceLocationKey].(map[string]any)
+	if location["file"] != "formatGCP_test.go" || location["line"] == "" {
+		t.Errorf("%s = %v", gcpSourceLocationKey, location)
+	}
+}
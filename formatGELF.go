@@ -0,0 +1,99 @@
+package nmcslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// gelfVersion is the version of the Graylog Extended Log Format written by FormatGELF.
+const gelfVersion = "1.1"
+
+// gelfInvalidFieldChars matches the characters not allowed in the name of a GELF additional field.
+var gelfInvalidFieldChars = regexp.MustCompile(`[^\w.\-]`)
+
+// encodeGELF returns an entryEncoder writing the entry as a GELF message, attributes become additional fields with
+// nested groups joined by an underscore. The host is taken from the host.name resource attribute or else the hostname.
+func encodeGELF(resource map[string]string) entryEncoder {
+	host := resource["host.name"]
+	if host == "" {
+		host, _ = os.Hostname()
+	}
+
+	return func(buf *bytes.Buffer, e *entry) error {
+		msg := map[string]any{}
+		for key, value := range resource {
+			if key != "host.name" {
+				msg[gelfFieldName(key)] = value
+			}
+		}
+
+		var fullMessage string
+		for _, a := range e.Attrs {
+			if fullMessage == "" {
+				if details, ok := extractError(a); ok && len(details.Frames) > 0 {
+					fullMessage = e.Message + "\n" + details.Message + "\n" + fmtStack(details.Frames)
+				}
+			}
+			gelfAddField(msg, "", a)
+		}
+
+		if e.Source != nil {
+			msg["_file"] = e.Source.File
+			msg["_line"] = e.Source.Line
+			msg["_function"] = e.Source.Function
+		}
+
+		msg["version"] = gelfVersion
+		msg["host"] = host
+		msg["short_message"] = e.Message
+		msg["timestamp"] = json.Number(gelfTimestamp(e))
+		msg["level"] = syslogSeverity(e.Level)
+		msg["_level_name"] = levelName(e.Level)
+		if fullMessage != "" {
+			msg["full_message"] = fullMessage
+		}
+
+		if err := writeJSONObject(buf, msg, "version", "host", "short_message", "full_message", "timestamp", "level"); err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+
+		return nil
+	}
+}
+
+// gelfTimestamp returns the timestamp of the entry as seconds since the Unix epoch with millisecond precision.
+func gelfTimestamp(e *entry) string {
+	return strconv.FormatFloat(float64(e.Time.UnixMilli())/1e3, 'f', 3, 64)
+}
+
+// gelfAddField adds the attribute as an additional field, flattening groups.
+func gelfAddField(msg map[string]any, prefix string, a slog.Attr) {
+	name := a.Key
+	if prefix != "" {
+		name = prefix + "_" + a.Key
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		for _, member := range a.Value.Group() {
+			gelfAddField(msg, name, member)
+		}
+		return
+	}
+
+	msg[gelfFieldName(name)] = attrValue(a.Value)
+}
+
+// gelfFieldName returns the name of the additional field, the reserved id field is renamed as it is not allowed.
+func gelfFieldName(name string) string {
+	name = gelfInvalidFieldChars.ReplaceAllString(name, "_")
+	if name == "id" {
+		name = "id_"
+	}
+
+	return "_" + name
+}
@@ -0,0 +1,111 @@
+package nmcslog
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// githubActionsEnv is set to true by GitHub Actions for every step of a workflow run.
+	githubActionsEnv = "GITHUB_ACTIONS"
+	// githubWorkspaceEnv is the checkout directory, annotation file paths are relative to it.
+	githubWorkspaceEnv = "GITHUB_WORKSPACE"
+)
+
+var (
+	githubDataEscaper     = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	githubPropertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+// githubActions reports whether the process is running within a GitHub Actions workflow.
+func githubActions() bool {
+	return os.Getenv(githubActionsEnv) == "true"
+}
+
+// githubCommand maps the level onto the workflow command of the record, records below NOTICE and above DEBUG are
+// written as plain lines.
+func githubCommand(level slog.Level) string {
+	switch {
+	case level >= LevelError:
+		return "error"
+	case level >= LevelWarn:
+		return "warning"
+	case level >= LevelNotice:
+		return "notice"
+	case level < LevelInfo:
+		return "debug"
+	default:
+		return ""
+	}
+}
+
+// githubFile returns the source file relative to the workspace, so the annotation is attached to the file of the
+// repository. Files outside the workspace are returned as is.
+func githubFile(file string) string {
+	workspace := os.Getenv(githubWorkspaceEnv)
+	if workspace == "" || !filepath.IsAbs(file) {
+		return file
+	}
+
+	rel, err := filepath.Rel(workspace, file)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return file
+	}
+
+	return filepath.ToSlash(rel)
+}
+
+// encodeGitHub returns an entryEncoder writing the entry as a GitHub Actions workflow command, so WARN and ERROR
+// records are shown as annotations of the workflow run. The source, if included, sets the file and line of the
+// annotation. Group attributes are written as key=value lines within a collapsible ::group:: block following
+// the record.
+func encodeGitHub() entryEncoder {
+	return func(buf *bytes.Buffer, e *entry) error {
+		var groups []slog.Attr
+		message := &strings.Builder{}
+		message.WriteString(e.Message)
+		for _, a := range e.Attrs {
+			if a.Value.Kind() == slog.KindGroup {
+				groups = append(groups, a)
+				continue
+			}
+			fmt.Fprintf(message, " %s=%s", a.Key, quoteValue(fmt.Sprint(attrValue(a.Value))))
+		}
+
+		switch command := githubCommand(e.Level); command {
+		case "":
+		case "debug":
+			buf.WriteString("::debug::")
+		default:
+			properties := []string{"title=" + githubPropertyEscaper.Replace(levelName(e.Level))}
+			if e.Source != nil {
+				properties = append(properties,
+					"file="+githubPropertyEscaper.Replace(githubFile(e.Source.File)),
+					"line="+strconv.Itoa(e.Source.Line),
+				)
+			}
+			buf.WriteString("::" + command + " " + strings.Join(properties, ",") + "::")
+		}
+		// Escaping the newlines of plain lines as well keeps every record on a single line.
+		buf.WriteString(githubDataEscaper.Replace(message.String()))
+		buf.WriteByte('\n')
+
+		for _, group := range groups {
+			buf.WriteString("::group::")
+			buf.WriteString(githubDataEscaper.Replace(group.Key))
+			buf.WriteByte('\n')
+			flattenAttrs(group.Value.Group(), ".", func(key string, value slog.Value) {
+				buf.WriteString(githubDataEscaper.Replace(key + "=" + quoteValue(fmt.Sprint(attrValue(value)))))
+				buf.WriteByte('\n')
+			})
+			buf.WriteString("::endgroup::\n")
+		}
+
+		return nil
+	}
+}
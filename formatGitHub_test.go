@@ -0,0 +1,131 @@
+package nmcslog
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFormatGitHub(t *testing.T) {
+	t.Setenv(githubWorkspaceEnv, "/home/runner/work/repo")
+
+	source := &slog.Source{File: "/home/runner/work/repo/cmd/main.go", Line: 42}
+	tests := []struct {
+		name  string
+		entry entry
+		want  string
+	}{
+		{
+			name:  "info",
+			entry: entry{Level: LevelInfo, Message: "starting", Attrs: []slog.Attr{slog.String("mode", "dry run")}},
+			want:  "starting mode=\"dry run\"\n",
+		},
+		{
+			name:  "debug",
+			entry: entry{Level: LevelTrace, Message: "details"},
+			want:  "::debug::details\n",
+		},
+		{
+			name:  "notice without source",
+			entry: entry{Level: LevelNotice, Message: "deprecated"},
+			want:  "::notice title=NOTICE::deprecated\n",
+		},
+		{
+			name:  "warning",
+			entry: entry{Level: LevelWarn, Message: "slow: 100%", Source: source, Attrs: []slog.Attr{slog.Int("ms", 900)}},
+			want:  "::warning title=WARN,file=cmd/main.go,line=42::slow: 100%25 ms=900\n",
+		},
+		{
+			name: "error with groups",
+			entry: entry{Level: LevelFatal, Message: "failed\nbadly", Source: &slog.Source{File: "main.go", Line: 7}, Attrs: []slog.Attr{
+				slog.String("step", "build"),
+				slog.Group("request", slog.String("method", "GET"), slog.Group("header", slog.String("accept", "*/*"))),
+			}},
+			want: "::error title=FATAL,file=main.go,line=7::failed%0Abadly step=build\n" +
+				"::group::request\nmethod=GET\nheader.accept=*/*\n::endgroup::\n",
+		},
+	}
+
+	encode := encodeGitHub()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			if err := encode(buf, &tt.entry); err != nil {
+				t.Fatalf("encode() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("encode() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatGitHub_ConsoleDetection(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		format  OutputFormat
+		disable bool
+		want    string
+	}{
+		{name: "github actions", env: "true", format: FormatText, want: "::warning title=WARN::careful\n"},
+		{name: "default format", env: "true", want: "::warning title=WARN::careful\n"},
+		{name: "detection disabled", env: "true", format: FormatText, disable: true, want: "level=WARN msg=careful\n"},
+		{name: "local", env: "", format: FormatText, want: "level=WARN msg=careful\n"},
+		{name: "json format", env: "true", format: FormatJSON, want: `{"level":"WARN","msg":"careful"}` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(githubActionsEnv, tt.env)
+
+			r, w, err := os.Pipe()
+			if err != nil {
+				t.Fatal(err)
+			}
+			stderr := os.Stderr
+			os.Stderr = w
+			defer func() { os.Stderr = stderr }()
+
+			console := ConsoleOutput{
+				OutputHandler: OutputHandler{
+					Format: tt.format,
+					AttributeFuncs: []AttributeFunc{func(_ []string, a slog.Attr) slog.Attr {
+						if a.Key == slog.TimeKey {
+							return slog.Attr{}
+						}
+						return a
+					}},
+				},
+				DisableCIDetection: tt.disable,
+			}
+			handler, err := console.GetHandler()
+			if err != nil {
+				t.Fatalf("GetHandler() error = %v", err)
+			}
+			slog.New(handler).Log(context.Background(), LevelWarn, "careful")
+			_ = w.Close()
+
+			got, _ := io.ReadAll(r)
+			if string(got) != tt.want {
+				t.Errorf("console output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitHubFile(t *testing.T) {
+	workspace := t.TempDir()
+	t.Setenv(githubWorkspaceEnv, workspace)
+
+	if got := githubFile(filepath.Join(workspace, "pkg", "file.go")); got != "pkg/file.go" {
+		t.Errorf("githubFile() = %q, want pkg/file.go", got)
+	}
+	if got := githubFile("/usr/local/go/src/log/slog/logger.go"); got != "/usr/local/go/src/log/slog/logger.go" {
+		t.Errorf("githubFile() outside the workspace = %q", got)
+	}
+}
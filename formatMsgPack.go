@@ -0,0 +1,200 @@
+package nmcslog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+)
+
+// Keys of the MessagePack record map.
+const (
+	msgpackTimeKey   = "time"
+	msgpackLevelKey  = "level"
+	msgpackMsgKey    = "msg"
+	msgpackSourceKey = "source"
+	msgpackAttrsKey  = "attrs"
+)
+
+// encodeMsgPack returns an entryEncoder writing the entry as a MessagePack map prefixed by its length as an
+// unsigned varint. The map holds the time, level, msg, the optional source and the attrs as a nested map.
+func encodeMsgPack() entryEncoder {
+	return func(buf *bytes.Buffer, e *entry) error {
+		fields := 3
+		if e.Source != nil {
+			fields++
+		}
+		if len(e.Attrs) > 0 {
+			fields++
+		}
+
+		record := appendMsgpackMapHeader(nil, fields)
+		record = appendMsgpackString(record, msgpackTimeKey)
+		record = appendMsgpackTime(record, e.Time)
+		record = appendMsgpackString(record, msgpackLevelKey)
+		record = appendMsgpackInt(record, int64(e.Level))
+		record = appendMsgpackString(record, msgpackMsgKey)
+		record = appendMsgpackString(record, e.Message)
+		if e.Source != nil {
+			record = appendMsgpackString(record, msgpackSourceKey)
+			record = appendMsgpackAttrs(record, []slog.Attr{
+				slog.String("function", e.Source.Function),
+				slog.String("file", e.Source.File),
+				slog.Int("line", e.Source.Line),
+			})
+		}
+		if len(e.Attrs) > 0 {
+			record = appendMsgpackString(record, msgpackAttrsKey)
+			record = appendMsgpackAttrs(record, e.Attrs)
+		}
+
+		buf.Write(binary.AppendUvarint(nil, uint64(len(record))))
+		buf.Write(record)
+
+		return nil
+	}
+}
+
+// RecordDecoder reads the log records written by FormatMsgPack.
+type RecordDecoder struct {
+	r *bufio.Reader
+}
+
+// NewRecordDecoder returns a RecordDecoder reading from r.
+func NewRecordDecoder(r io.Reader) *RecordDecoder {
+	return &RecordDecoder{r: bufio.NewReader(r)}
+}
+
+// Decode reads the next record and returns io.EOF at the end of the stream. As the program counter cannot be
+// restored, the source of a record is added as a source attribute holding a *slog.Source.
+func (rd *RecordDecoder) Decode() (record slog.Record, err error) {
+	defer func() {
+		if err != nil && !errors.Is(err, io.EOF) {
+			err = fmt.Errorf("nmcslog: decode record: %w", err)
+		}
+	}()
+
+	size, err := binary.ReadUvarint(rd.r)
+	if err != nil {
+		return record, err
+	}
+	// The buffer grows as the data is read, so a corrupt size does not allocate more than the stream holds.
+	data := &bytes.Buffer{}
+	if _, err = io.CopyN(data, rd.r, int64(min(size, math.MaxInt64))); err != nil {
+		return record, unexpectedEOF(err)
+	}
+
+	mr := newMsgpackReader(data, data.Len())
+	fields, err := mr.readMapHeader()
+	if err != nil {
+		return record, err
+	}
+
+	var source *slog.Source
+	var attrs []slog.Attr
+	for i := 0; i < fields; i++ {
+		key, err := mr.readString()
+		if err != nil {
+			return record, unexpectedEOF(err)
+		}
+		value, err := mr.readValue()
+		if err != nil {
+			return record, unexpectedEOF(err)
+		}
+
+		switch key {
+		case msgpackTimeKey:
+			if value.Kind() != slog.KindTime {
+				return record, msgpackFieldError(key, value)
+			}
+			record.Time = value.Time()
+		case msgpackLevelKey:
+			level, ok := msgpackInt(value)
+			if !ok {
+				return record, msgpackFieldError(key, value)
+			}
+			record.Level = slog.Level(level)
+		case msgpackMsgKey:
+			if value.Kind() != slog.KindString {
+				return record, msgpackFieldError(key, value)
+			}
+			record.Message = value.String()
+		case msgpackSourceKey:
+			if value.Kind() != slog.KindGroup {
+				return record, msgpackFieldError(key, value)
+			}
+			source = &slog.Source{}
+			for _, a := range value.Group() {
+				switch a.Key {
+				case "function":
+					source.Function = a.Value.String()
+				case "file":
+					source.File = a.Value.String()
+				case "line":
+					line, ok := msgpackInt(a.Value)
+					if !ok {
+						return record, msgpackFieldError("source line", a.Value)
+					}
+					source.Line = int(line)
+				}
+			}
+		case msgpackAttrsKey:
+			if value.Kind() != slog.KindGroup {
+				return record, msgpackFieldError(key, value)
+			}
+			attrs = value.Group()
+		}
+	}
+
+	if source != nil {
+		record.AddAttrs(slog.Any(slog.SourceKey, source))
+	}
+	record.AddAttrs(attrs...)
+
+	return record, nil
+}
+
+// msgpackInt returns the value of an integer, which is decoded as a uint64 value if it is positive and encoded on
+// more than 7 bits.
+func msgpackInt(v slog.Value) (int64, bool) {
+	switch v.Kind() {
+	case slog.KindInt64:
+		return v.Int64(), true
+	case slog.KindUint64:
+		if v.Uint64() > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(v.Uint64()), true
+	default:
+		return 0, false
+	}
+}
+
+func msgpackFieldError(key string, v slog.Value) error {
+	return fmt.Errorf("%w: unexpected %s value for %s", errMsgpackInvalid, v.Kind(), key)
+}
+
+// Replay decodes the remaining records of the stream and passes those enabled to the handler, for example a
+// slog.JSONHandler to convert the stream into JSON.
+func (rd *RecordDecoder) Replay(ctx context.Context, handler slog.Handler) error {
+	for {
+		record, err := rd.Decode()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !handler.Enabled(ctx, record.Level) {
+			continue
+		}
+		if err := handler.Handle(ctx, record); err != nil {
+			return fmt.Errorf("nmcslog: replay record: %w", err)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package nmcslog
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestFormatMsgPack_RoundTrip(t *testing.T) {
+	w := &bytes.Buffer{}
+	output := OutputHandler{Format: FormatMsgPack, LogLevel: LogLevel{Level: "TRACE"}, IncludeSource: true}
+	handler, err := output.GetHandler(w)
+	if err != nil {
+		t.Fatalf("GetHandler() error = %v", err)
+	}
+
+	logger := slog.New(handler).With("service", "api")
+	logger.Log(context.Background(), LevelTrace, "first", "count", -300, "ratio", 0.5, "ok", true)
+	logger.WithGroup("http").Error("second", "status", uint64(503), "latency", time.Second, "tags", []string{"a", "b"})
+
+	decoder := NewRecordDecoder(bytes.NewReader(w.Bytes()))
+	first, err := decoder.Decode()
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if first.Level != LevelTrace || first.Message != "first" || first.Time.IsZero() {
+		t.Errorf("first record = %v", first)
+	}
+
+	var got []string
+	first.Attrs(func(a slog.Attr) bool {
+		if a.Key == slog.SourceKey {
+			if src, ok := a.Value.Any().(*slog.Source); !ok || src.File != "formatMsgPack_test.go" {
+				t.Errorf("source = %v", a.Value)
+			}
+			return true
+		}
+		got = append(got, a.String())
+		return true
+	})
+	want := []string{"service=api", "count=-300", "ratio=0.5", "ok=true"}
+	if len(got) != len(want) {
+		t.Fatalf("attrs = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("attrs[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	// Convert the remaining record to JSON.
+	jsonOutput := &bytes.Buffer{}
+	if err := decoder.Replay(context.Background(), slog.NewJSONHandler(jsonOutput, nil)); err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	doc := map[string]any{}
+	if err := json.Unmarshal(jsonOutput.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", jsonOutput.String(), err)
+	}
+	http, _ := doc["http"].(map[string]any)
+	if doc["msg"] != "second" || doc["service"] != "api" || http["status"] != float64(503) || http["latency"] != float64(time.Second) {
+		t.Errorf("JSON output = %s", jsonOutput.String())
+	}
+
+	if _, err := decoder.Decode(); !errors.Is(err, io.EOF) {
+		t.Errorf("Decode() at end of stream error = %v, want io.EOF", err)
+	}
+}
+
+func TestFormatMsgPack_Truncated(t *testing.T) {
+	w := &bytes.Buffer{}
+	handler := newEntryHandler(newEncodedWriter(w, encodeMsgPack()), nil, false)
+	slog.New(handler).Info("message", "key", "value")
+
+	decoder := NewRecordDecoder(bytes.NewReader(w.Bytes()[:w.Len()-3]))
+	if _, err := decoder.Decode(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Decode() error = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestFormatMsgPack_LargeIntegers(t *testing.T) {
+	// Positive integers above 127 are encoded as unsigned integers.
+	e := &entry{
+		Time:    time.Now(),
+		Level:   LevelFatal + 200,
+		Message: "large",
+		Source:  &slog.Source{Function: "main.run", File: "main.go", Line: 4242},
+	}
+	buf := &bytes.Buffer{}
+	if err := encodeMsgPack()(buf, e); err != nil {
+		t.Fatalf("encode error = %v", err)
+	}
+
+	record, err := NewRecordDecoder(buf).Decode()
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if record.Level != e.Level {
+		t.Errorf("Level = %v, want %v", record.Level, e.Level)
+	}
+	record.Attrs(func(a slog.Attr) bool {
+		if src, ok := a.Value.Any().(*slog.Source); !ok || src.Line != 4242 {
+			t.Errorf("source = %v, want line 4242", a.Value)
+		}
+		return true
+	})
+}
+
+func TestFormatMsgPack_Invalid(t *testing.T) {
+	// record prefixes the map with the header of its fields and the record with its size.
+	record := func(fields int, data ...byte) []byte {
+		b := appendMsgpackMapHeader(nil, fields)
+		b = append(b, data...)
+		return append(binary.AppendUvarint(nil, uint64(len(b))), b...)
+	}
+	field := func(key string, value []byte) []byte {
+		return append(appendMsgpackString(nil, key), value...)
+	}
+	nested := append(bytes.Repeat([]byte{0x91}, 200), 0xc0)
+
+	tests := map[string][]byte{
+		"time kind":        record(1, field(msgpackTimeKey, appendMsgpackString(nil, "now"))...),
+		"level kind":       record(1, field(msgpackLevelKey, appendMsgpackString(nil, "INFO"))...),
+		"message kind":     record(1, field(msgpackMsgKey, appendMsgpackInt(nil, 1))...),
+		"source kind":      record(1, field(msgpackSourceKey, appendMsgpackInt(nil, 1))...),
+		"source line kind": record(1, field(msgpackSourceKey, appendMsgpackAttrs(nil, []slog.Attr{slog.String("line", "1")}))...),
+		"attrs kind":       record(1, field(msgpackAttrsKey, appendMsgpackBool(nil, true))...),
+		"string length":    record(1, field(msgpackMsgKey, []byte{0xdb, 0xff, 0xff, 0xff, 0xff})...),
+		"array length":     record(1, field(msgpackAttrsKey, []byte{0xdd, 0x7f, 0xff, 0xff, 0xff})...),
+		"map length":       record(1, field(msgpackAttrsKey, []byte{0xdf, 0xff, 0xff, 0xff, 0xff})...),
+		"map header":       append(binary.AppendUvarint(nil, 5), 0xdf, 0xff, 0xff, 0xff, 0xff),
+		"nesting":          record(1, field(msgpackAttrsKey, nested)...),
+		"record size":      binary.AppendUvarint(nil, math.MaxUint64),
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewRecordDecoder(bytes.NewReader(data)).Decode()
+			if !errors.Is(err, errMsgpackInvalid) && !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Errorf("Decode() error = %v, want invalid data", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package nmcslog
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// otelScopeName is the instrumentation scope reported for every log record.
+const otelScopeName = "github.com/notmycloud/slog"
+
+// The OTLP/JSON encoding of the OpenTelemetry logs data model.
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+
+type otlpLogsData struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         string         `json:"timeUnixNano"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+	SeverityNumber       int            `json:"severityNumber"`
+	SeverityText         string         `json:"severityText"`
+	Body                 otlpAnyValue   `json:"body"`
+	Attributes           []otlpKeyValue `json:"attributes,omitempty"`
+	Flags                uint32         `json:"flags,omitempty"`
+	TraceID              string         `json:"traceId,omitempty"`
+	SpanID               string         `json:"spanId,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+// otlpAnyValue holds exactly one of its fields, 64-bit integers are encoded as strings as required by OTLP/JSON.
+type otlpAnyValue struct {
+	StringValue *string          `json:"stringValue,omitempty"`
+	BoolValue   *bool            `json:"boolValue,omitempty"`
+	IntValue    *string          `json:"intValue,omitempty"`
+	DoubleValue *float64         `json:"doubleValue,omitempty"`
+	ArrayValue  *otlpArrayValue  `json:"arrayValue,omitempty"`
+	KvlistValue *otlpKvlistValue `json:"kvlistValue,omitempty"`
+	BytesValue  []byte           `json:"bytesValue,omitempty"`
+}
+
+type otlpArrayValue struct {
+	Values []otlpAnyValue `json:"values"`
+}
+
+type otlpKvlistValue struct {
+	Values []otlpKeyValue `json:"values"`
+}
+
+// otelSeverityNumber maps the level onto the OpenTelemetry severity number, the slog levels are offset by 9 so
+// that TRACE..FATAL land on TRACE(1), DEBUG(5), INFO(9), INFO3(11), WARN(13), ERROR(17) and FATAL(21).
+func otelSeverityNumber(level slog.Level) int {
+	return min(max(int(level)+9, 1), 24)
+}
+
+// otelResource converts the resource attributes into an OTLP resource.
+func otelResource(resource map[string]string) otlpResource {
+	var r otlpResource
+	for key, value := range resource {
+		r.Attributes = append(r.Attributes, otlpKeyValue{Key: key, Value: otlpString(value)})
+	}
+	slices.SortFunc(r.Attributes, func(a, b otlpKeyValue) int { return cmp.Compare(a.Key, b.Key) })
+
+	return r
+}
+
+// otelLogRecord converts the entry into an OTLP log record, the source and error attributes follow the
+// OpenTelemetry semantic conventions.
+func otelLogRecord(e *entry) otlpLogRecord {
+	record := otlpLogRecord{
+		TimeUnixNano:         strconv.FormatInt(e.Time.UnixNano(), 10),
+		ObservedTimeUnixNano: strconv.FormatInt(time.Now().UnixNano(), 10),
+		SeverityNumber:       otelSeverityNumber(e.Level),
+		SeverityText:         levelName(e.Level),
+		Body:                 otlpString(e.Message),
+	}
+
+	var exceptionRecorded bool
+	for _, a := range e.Attrs {
+		if !exceptionRecorded {
+			if details, ok := extractError(a); ok {
+				exceptionRecorded = true
+				record.Attributes = append(record.Attributes, otlpKeyValue{Key: "exception.message", Value: otlpString(details.Message)})
+				if details.Type != "" {
+					record.Attributes = append(record.Attributes, otlpKeyValue{Key: "exception.type", Value: otlpString(details.Type)})
+				}
+				if len(details.Frames) > 0 {
+					record.Attributes = append(record.Attributes, otlpKeyValue{Key: "exception.stacktrace", Value: otlpString(fmtStack(details.Frames))})
+				}
+				continue
+			}
+		}
+		record.Attributes = append(record.Attributes, otlpKeyValue{Key: a.Key, Value: otelValue(a.Value)})
+	}
+
+	if e.Source != nil {
+		record.Attributes = append(record.Attributes,
+			otlpKeyValue{Key: "code.file.path", Value: otlpString(e.Source.File)},
+			otlpKeyValue{Key: "code.line.number", Value: otelValue(slog.IntValue(e.Source.Line))},
+			otlpKeyValue{Key: "code.function.name", Value: otlpString(e.Source.Function)},
+		)
+	}
+
+	if trace, ok := TraceFromContext(e.Context); ok {
+		record.TraceID = trace.TraceID
+		record.SpanID = trace.SpanID
+		if trace.Sampled {
+			record.Flags = 1
+		}
+	}
+
+	return record
+}
+
+// otelLogsData wraps the log records into the OTLP logs data of a single resource and scope.
+func otelLogsData(resource otlpResource, records ...otlpLogRecord) otlpLogsData {
+	return otlpLogsData{
+		ResourceLogs: []otlpResourceLogs{{
+			Resource: resource,
+			ScopeLogs: []otlpScopeLogs{{
+				Scope:      otlpScope{Name: otelScopeName},
+				LogRecords: records,
+			}},
+		}},
+	}
+}
+
+// otelValue converts the value into an OTLP AnyValue, groups become key value lists and slices become arrays.
+func otelValue(v slog.Value) otlpAnyValue {
+	switch v.Kind() {
+	case slog.KindString:
+		return otlpString(v.String())
+	case slog.KindInt64:
+		value := strconv.FormatInt(v.Int64(), 10)
+		return otlpAnyValue{IntValue: &value}
+	case slog.KindUint64:
+		value := strconv.FormatUint(v.Uint64(), 10)
+		return otlpAnyValue{IntValue: &value}
+	case slog.KindFloat64:
+		value := v.Float64()
+		return otlpAnyValue{DoubleValue: &value}
+	case slog.KindBool:
+		value := v.Bool()
+		return otlpAnyValue{BoolValue: &value}
+	case slog.KindDuration:
+		value := strconv.FormatInt(v.Duration().Nanoseconds(), 10)
+		return otlpAnyValue{IntValue: &value}
+	case slog.KindTime:
+		return otlpString(v.Time().Format(time.RFC3339Nano))
+	case slog.KindGroup:
+		list := &otlpKvlistValue{Values: []otlpKeyValue{}}
+		for _, a := range v.Group() {
+			list.Values = append(list.Values, otlpKeyValue{Key: a.Key, Value: otelValue(a.Value)})
+		}
+		return otlpAnyValue{KvlistValue: list}
+	}
+
+	switch value := v.Any().(type) {
+	case nil:
+		return otlpString("")
+	case error:
+		return otlpString(value.Error())
+	case []byte:
+		return otlpAnyValue{BytesValue: value}
+	case fmt.Stringer:
+		return otlpString(value.String())
+	}
+
+	rv := reflect.ValueOf(v.Any())
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		array := &otlpArrayValue{Values: make([]otlpAnyValue, rv.Len())}
+		for i := range rv.Len() {
+			array.Values[i] = otelValue(slog.AnyValue(rv.Index(i).Interface()))
+		}
+		return otlpAnyValue{ArrayValue: array}
+	}
+
+	if data, err := json.Marshal(v.Any()); err == nil {
+		return otlpString(string(data))
+	}
+
+	return otlpString(fmt.Sprintf("%+v", v.Any()))
+}
+
+func otlpString(s string) otlpAnyValue {
+	return otlpAnyValue{StringValue: &s}
+}
+
+// encodeOTEL returns an entryEncoder writing each entry as a single line of OTLP/JSON logs data, the format of the
+// OpenTelemetry collector file exporter and receiver.
+func encodeOTEL(resource map[string]string) entryEncoder {
+	otlpRes := otelResource(resource)
+
+	return func(buf *bytes.Buffer, e *entry) error {
+		data, err := json.Marshal(otelLogsData(otlpRes, otelLogRecord(e)))
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+
+		return nil
+	}
+}
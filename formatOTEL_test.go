@@ -0,0 +1,90 @@
+package nmcslog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+)
+
+func TestOtelSeverityNumber(t *testing.T) {
+	tests := []struct {
+		level slog.Level
+		want  int
+	}{
+		{LevelTrace - 4, 1},
+		{LevelTrace, 1},
+		{LevelDebug, 5},
+		{LevelInfo, 9},
+		{LevelNotice, 11},
+		{LevelWarn, 13},
+		{LevelError, 17},
+		{LevelFatal, 21},
+		{LevelFatal + 8, 24},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			if got := otelSeverityNumber(tt.level); got != tt.want {
+				t.Errorf("otelSeverityNumber(%v) = %v, want %v", tt.level, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatOTEL(t *testing.T) {
+	w := &bytes.Buffer{}
+	output := OutputHandler{
+		Format:   FormatOTEL,
+		Resource: map[string]string{"service.name": "api"},
+	}
+	handler, err := output.GetHandler(w)
+	if err != nil {
+		t.Fatalf("GetHandler() error = %v", err)
+	}
+
+	ctx := ContextWithTrace(context.Background(), TraceContext{
+		TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:  "00f067aa0ba902b7",
+		Sampled: true,
+	})
+	slog.New(handler).WithGroup("db").ErrorContext(ctx, "query failed", "rows", 3, "tags", []string{"a", "b"})
+	slog.New(handler).Error("query failed", "error", errors.New("timeout"))
+
+	lines := bytes.Split(bytes.TrimSpace(w.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2: %s", len(lines), w.String())
+	}
+
+	var data otlpLogsData
+	if err := json.Unmarshal(lines[0], &data); err != nil {
+		t.Fatalf("invalid OTLP/JSON output %q: %v", lines[0], err)
+	}
+	resource := data.ResourceLogs[0].Resource
+	if len(resource.Attributes) != 1 || *resource.Attributes[0].Value.StringValue != "api" {
+		t.Errorf("resource = %+v", resource)
+	}
+	record := data.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	if record.SeverityNumber != 17 || record.SeverityText != "ERROR" || *record.Body.StringValue != "query failed" {
+		t.Errorf("record = %+v", record)
+	}
+	if record.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || record.SpanID != "00f067aa0ba902b7" || record.Flags != 1 {
+		t.Errorf("record trace = %s/%s/%d", record.TraceID, record.SpanID, record.Flags)
+	}
+	if len(record.Attributes) != 1 || record.Attributes[0].Key != "db" {
+		t.Fatalf("record attributes = %+v", record.Attributes)
+	}
+	group := record.Attributes[0].Value.KvlistValue.Values
+	if *group[0].Value.IntValue != "3" || len(group[1].Value.ArrayValue.Values) != 2 {
+		t.Errorf("group attributes = %+v", group)
+	}
+
+	if err := json.Unmarshal(lines[1], &data); err != nil {
+		t.Fatalf("invalid OTLP/JSON output %q: %v", lines[1], err)
+	}
+	record = data.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	if record.Attributes[0].Key != "exception.message" || *record.Attributes[0].Value.StringValue != "timeout" {
+		t.Errorf("exception attributes = %+v", record.Attributes)
+	}
+}
@@ -0,0 +1,199 @@
+package nmcslog
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	cefVersion  = "0"
+	leefVersion = "1.0"
+	// leefTimeLayout is the Go layout matching the leefTimeFormat given in the devTimeFormat attribute.
+	leefTimeLayout = "Jan 02 2006 15:04:05.000 MST"
+	leefTimeFormat = "MMM dd yyyy HH:mm:ss.SSS z"
+)
+
+var (
+	siemHeaderEscaper = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\n", " ", "\r", " ")
+	siemValueEscaper  = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	siemKeyReplacer   = strings.NewReplacer(" ", "_", "=", "_", "|", "_", `\`, "_", "\t", "_")
+)
+
+// SIEMOptions defines the headers and the attribute mapping of the CEF and LEEF formats.
+type SIEMOptions struct {
+	// Vendor of the device reported in the header.
+	Vendor string `json:",omitempty" jsonschema:"title=Device Vendor,example=NotMyCloud"`
+	// Product of the device reported in the header, defaults to the executable name.
+	Product string `json:",omitempty" jsonschema:"title=Device Product,example=api"`
+	// Version of the device reported in the header.
+	Version string `json:",omitempty" jsonschema:"title=Device Version,example=1.2.3"`
+	// EventIDAttribute is the attribute used as the CEF Signature ID and LEEF Event ID, defaults to the message.
+	EventIDAttribute string `json:",omitempty" jsonschema:"title=Event ID Attribute,example=event.id"`
+	// Extensions maps the attributes, by their dotted group path, onto extension keys such as suser or dst.
+	// Attributes that are not mapped are added under their dotted group path.
+	Extensions map[string]string `json:",omitempty" jsonschema:"title=Extension Keys"`
+}
+
+func (so *SIEMOptions) Validate() (err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: validate config [siem options]: %w", err)
+		}
+	}()
+
+	for attribute, key := range so.Extensions {
+		if key == "" || siemKeyReplacer.Replace(key) != key {
+			return fmt.Errorf("invalid extension key [%s] for attribute [%s]", key, attribute)
+		}
+	}
+
+	return nil
+}
+
+func (so *SIEMOptions) product() string {
+	if so.Product != "" {
+		return so.Product
+	}
+
+	return filepath.Base(os.Args[0])
+}
+
+// extensions flattens the entry attributes into the extension key value pairs in a stable order, the attribute
+// selected as the event ID is returned separately.
+func (so *SIEMOptions) extensions(e *entry) (pairs [][2]string, eventID string) {
+	values := map[string]string{}
+	var flatten func(prefix string, attrs []slog.Attr)
+	flatten = func(prefix string, attrs []slog.Attr) {
+		for _, a := range attrs {
+			path := prefix + a.Key
+			if a.Value.Kind() == slog.KindGroup {
+				flatten(path+".", a.Value.Group())
+				continue
+			}
+			values[path] = fmt.Sprint(attrValue(a.Value))
+		}
+	}
+	flatten("", e.Attrs)
+
+	if e.Source != nil {
+		values[slog.SourceKey] = e.Source.File + ":" + strconv.Itoa(e.Source.Line)
+	}
+
+	eventID = e.Message
+	if so.EventIDAttribute != "" {
+		if value, exists := values[so.EventIDAttribute]; exists {
+			eventID = value
+			delete(values, so.EventIDAttribute)
+		}
+	}
+
+	paths := make([]string, 0, len(values))
+	for path := range values {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		key, mapped := so.Extensions[path]
+		if !mapped {
+			key = siemKeyReplacer.Replace(path)
+		}
+		pairs = append(pairs, [2]string{key, values[path]})
+	}
+
+	return pairs, eventID
+}
+
+// cefSeverity maps the level onto the CEF severity of 0 (lowest) to 10 (highest).
+func cefSeverity(level slog.Level) int {
+	switch {
+	case level >= LevelFatal:
+		return 10
+	case level >= LevelError:
+		return 8
+	case level >= LevelWarn:
+		return 6
+	case level >= LevelNotice:
+		return 4
+	case level >= LevelInfo:
+		return 3
+	case level >= LevelDebug:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// encodeCEF returns an entryEncoder writing the entry in the ArcSight Common Event Format.
+func encodeCEF(options SIEMOptions) entryEncoder {
+	header := "CEF:" + cefVersion + "|" +
+		siemHeaderEscaper.Replace(options.Vendor) + "|" +
+		siemHeaderEscaper.Replace(options.product()) + "|" +
+		siemHeaderEscaper.Replace(options.Version) + "|"
+
+	return func(buf *bytes.Buffer, e *entry) error {
+		pairs, eventID := options.extensions(e)
+
+		buf.WriteString(header)
+		buf.WriteString(siemHeaderEscaper.Replace(eventID))
+		buf.WriteByte('|')
+		buf.WriteString(siemHeaderEscaper.Replace(e.Message))
+		buf.WriteByte('|')
+		buf.WriteString(strconv.Itoa(cefSeverity(e.Level)))
+		buf.WriteString("|rt=")
+		buf.WriteString(strconv.FormatInt(e.Time.UnixMilli(), 10))
+		buf.WriteString(" cat=")
+		buf.WriteString(siemValueEscaper.Replace(levelName(e.Level)))
+		for _, pair := range pairs {
+			buf.WriteByte(' ')
+			buf.WriteString(pair[0])
+			buf.WriteByte('=')
+			buf.WriteString(siemValueEscaper.Replace(pair[1]))
+		}
+		buf.WriteByte('\n')
+
+		return nil
+	}
+}
+
+// encodeLEEF returns an entryEncoder writing the entry in the IBM QRadar Log Event Extended Format, the attributes
+// are separated by tabs.
+func encodeLEEF(options SIEMOptions) entryEncoder {
+	header := "LEEF:" + leefVersion + "|" +
+		siemHeaderEscaper.Replace(options.Vendor) + "|" +
+		siemHeaderEscaper.Replace(options.product()) + "|" +
+		siemHeaderEscaper.Replace(options.Version) + "|"
+
+	return func(buf *bytes.Buffer, e *entry) error {
+		pairs, eventID := options.extensions(e)
+
+		buf.WriteString(header)
+		buf.WriteString(siemHeaderEscaper.Replace(eventID))
+		buf.WriteString("|devTime=")
+		buf.WriteString(e.Time.Format(leefTimeLayout))
+		buf.WriteString("\tdevTimeFormat=")
+		buf.WriteString(leefTimeFormat)
+		// LEEF severities range from 1 to 10.
+		buf.WriteString("\tsev=")
+		buf.WriteString(strconv.Itoa(max(cefSeverity(e.Level), 1)))
+		buf.WriteString("\tcat=")
+		buf.WriteString(siemValueEscaper.Replace(levelName(e.Level)))
+		buf.WriteString("\tmsg=")
+		buf.WriteString(siemValueEscaper.Replace(e.Message))
+		for _, pair := range pairs {
+			buf.WriteByte('\t')
+			buf.WriteString(pair[0])
+			buf.WriteByte('=')
+			buf.WriteString(siemValueEscaper.Replace(pair[1]))
+		}
+		buf.WriteByte('\n')
+
+		return nil
+	}
+}
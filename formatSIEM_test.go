@@ -0,0 +1,76 @@
+package nmcslog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatCEFAndLEEF(t *testing.T) {
+	options := SIEMOptions{
+		Vendor:           "Not|My\\Cloud",
+		Product:          "api",
+		Version:          "1.0",
+		EventIDAttribute: "event.id",
+		Extensions:       map[string]string{"user.name": "suser", "client": "src"},
+	}
+	stamp := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		format OutputFormat
+		want   string
+	}{
+		{
+			name:   "CEF",
+			format: FormatCEF,
+			want: `CEF:0|Not\|My\\Cloud|api|1.0|login|user a\|b logged in|6|rt=1792238400000 cat=WARN ` +
+				`src=10.0.0.1 query=a\=1 b\\c suser=alice`,
+		},
+		{
+			name:   "LEEF",
+			format: FormatLEEF,
+			want: "LEEF:1.0|Not\\|My\\\\Cloud|api|1.0|login|devTime=Oct 17 2026 12:00:00.000 UTC\t" +
+				"devTimeFormat=MMM dd yyyy HH:mm:ss.SSS z\tsev=6\tcat=WARN\tmsg=user a|b logged in\t" +
+				"src=10.0.0.1\tquery=a\\=1 b\\\\c\tsuser=alice",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := options.Validate(); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			encoder, err := tt.format.encoder(&OutputHandler{SIEM: options})
+			if err != nil {
+				t.Fatalf("encoder() error = %v", err)
+			}
+			w := &bytes.Buffer{}
+			handler := newEntryHandler(newEncodedWriter(w, encoder), nil, false)
+
+			record := slog.NewRecord(stamp, LevelWarn, "user a|b logged in", 0)
+			record.AddAttrs(
+				slog.String("event.id", "login"),
+				slog.String("client", "10.0.0.1"),
+				slog.String("query", `a=1 b\c`),
+				slog.Group("user", "name", "alice"),
+			)
+			if err := handler.Handle(context.Background(), record); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+
+			if got := strings.TrimSuffix(w.String(), "\n"); got != tt.want {
+				t.Errorf("output =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSIEMOptions_InvalidExtensionKey(t *testing.T) {
+	options := SIEMOptions{Extensions: map[string]string{"user": "source user"}}
+	if err := options.Validate(); err == nil {
+		t.Errorf("Validate() expected error for extension key with a space")
+	}
+}
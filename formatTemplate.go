@@ -0,0 +1,160 @@
+package nmcslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// DefaultTemplate is the Template used by FormatTemplate if none is configured.
+const DefaultTemplate = `{{.Time | rfc3339}} {{.Level}} {{.Message}}{{range .Attrs}} {{.Key}}={{.Value}}{{end}}`
+
+// TemplateRecord is the data a FormatTemplate Template is executed with.
+type TemplateRecord struct {
+	Time    time.Time
+	Level   TemplateLevel
+	Message string
+	// Source is only set if IncludeSource is enabled.
+	Source *slog.Source
+	// Attrs are the record and handler attributes, the keys of grouped attributes are joined by a dot.
+	Attrs []TemplateAttr
+}
+
+// Attr returns the value of the attribute with the given key, or nil if it does not exist.
+func (tr TemplateRecord) Attr(key string) any {
+	for _, a := range tr.Attrs {
+		if a.Key == key {
+			return a.Value
+		}
+	}
+
+	return nil
+}
+
+// TemplateAttr is a single attribute of a TemplateRecord.
+type TemplateAttr struct {
+	Key   string
+	Value any
+}
+
+// TemplateLevel is the level of a TemplateRecord, it prints using the CustomLevelNames.
+type TemplateLevel slog.Level
+
+func (tl TemplateLevel) String() string {
+	return levelName(slog.Level(tl))
+}
+
+// templateColors maps the names accepted by the color template function.
+var templateColors = map[string]color.Attribute{
+	"black":   color.FgBlack,
+	"red":     color.FgRed,
+	"green":   color.FgGreen,
+	"yellow":  color.FgYellow,
+	"blue":    color.FgBlue,
+	"magenta": color.FgMagenta,
+	"cyan":    color.FgCyan,
+	"white":   color.FgWhite,
+	"bold":    color.Bold,
+}
+
+// templateFuncs returns the helper functions available to a Template:
+//
+//	rfc3339, rfc3339nano: format a time.
+//	upper, lower: change the case of a value.
+//	pad, padLeft: pad a value with spaces to the given width, on the right or left respectively.
+//	quote: quote a value if it contains spaces or special characters.
+//	json: encode a value as JSON.
+//	color: colorize a value with the given color name such as red or cyan.
+//	levelColor: colorize the level name with the default level colors.
+func templateFuncs() template.FuncMap {
+	levelColors := &MWHandleColors{}
+	levelColors.SetDefaultColors()
+
+	return template.FuncMap{
+		"rfc3339": func(t time.Time) string {
+			return t.Format(time.RFC3339)
+		},
+		"rfc3339nano": func(t time.Time) string {
+			return t.Format(time.RFC3339Nano)
+		},
+		"upper": func(v any) string {
+			return strings.ToUpper(fmt.Sprint(v))
+		},
+		"lower": func(v any) string {
+			return strings.ToLower(fmt.Sprint(v))
+		},
+		"pad": func(width int, v any) string {
+			return fmt.Sprintf("%-*s", width, fmt.Sprint(v))
+		},
+		"padLeft": func(width int, v any) string {
+			return fmt.Sprintf("%*s", width, fmt.Sprint(v))
+		},
+		"quote": func(v any) string {
+			return quoteValue(fmt.Sprint(v))
+		},
+		"json": func(v any) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+		"color": func(name string, v any) (string, error) {
+			attribute, exists := templateColors[strings.ToLower(name)]
+			if !exists {
+				return "", fmt.Errorf("unknown color [%s]", name)
+			}
+			return color.New(attribute).Sprint(v), nil
+		},
+		"levelColor": func(level TemplateLevel) string {
+			attribute, exists := levelColors.ColorMap[slog.Level(level)]
+			if !exists {
+				return level.String()
+			}
+			return color.New(attribute).Sprint(level.String())
+		},
+	}
+}
+
+// parseTemplate compiles the template text, an empty text compiles the DefaultTemplate.
+func parseTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = DefaultTemplate
+	}
+
+	tmpl, err := template.New("record").Funcs(templateFuncs()).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parsing template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// encodeTemplate returns an entryEncoder executing the compiled template for each entry, a newline is appended if
+// the template does not end with one.
+func encodeTemplate(tmpl *template.Template) entryEncoder {
+	return func(buf *bytes.Buffer, e *entry) error {
+		record := TemplateRecord{
+			Time:    e.Time,
+			Level:   TemplateLevel(e.Level),
+			Message: e.Message,
+			Source:  e.Source,
+		}
+
+		flattenAttrs(e.Attrs, ".", func(key string, value slog.Value) {
+			record.Attrs = append(record.Attrs, TemplateAttr{Key: key, Value: attrValue(value)})
+		})
+
+		if err := tmpl.Execute(buf, record); err != nil {
+			return err
+		}
+		if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+			buf.WriteByte('\n')
+		}
+
+		return nil
+	}
+}
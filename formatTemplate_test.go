@@ -0,0 +1,62 @@
+package nmcslog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestFormatTemplate(t *testing.T) {
+	defer func(noColor bool) { color.NoColor = noColor }(color.NoColor)
+	color.NoColor = true
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{
+			name:     "request example",
+			template: `{{.Level}} {{.Message}} {{range .Attrs}}{{.Key}}={{.Value}} {{end}}`,
+			want:     "NOTICE deployed app=demo req.id=7 req.ok=true \n",
+		},
+		{
+			name:     "helpers",
+			template: `[{{pad 6 .Level}}] {{padLeft 4 (.Attr "req.id")}} {{quote .Message}} {{json .Attrs}}{{"\n"}}`,
+			want:     "[NOTICE]    7 deployed [{\"Key\":\"app\",\"Value\":\"demo\"},{\"Key\":\"req.id\",\"Value\":7},{\"Key\":\"req.ok\",\"Value\":true}]\n",
+		},
+		{
+			name:     "colors disabled",
+			template: `{{levelColor .Level}} {{color "red" .Message | upper}}`,
+			want:     "NOTICE DEPLOYED\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := OutputHandler{Format: FormatTemplate, Template: tt.template}
+			if err := output.Validate(); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			w := &bytes.Buffer{}
+			handler, err := output.GetHandler(w)
+			if err != nil {
+				t.Fatalf("GetHandler() error = %v", err)
+			}
+
+			slog.New(handler).With("app", "demo").WithGroup("req").Log(context.Background(), LevelNotice, "deployed", "id", 7, "ok", true)
+			if got := w.String(); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatTemplate_Invalid(t *testing.T) {
+	output := OutputHandler{Format: FormatTemplate, Template: "{{.Message"}
+	if err := output.Validate(); err == nil {
+		t.Errorf("Validate() expected error for an invalid template")
+	}
+}
@@ -4,11 +4,13 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"sync/atomic"
 )
 
 var (
 	defaultLogger *slog.Logger = slog.Default()
-	debugLogger   *slog.Logger = nil
+	// debugLogger is also used from the background goroutines of the outputs, so it is accessed atomically.
+	debugLogger atomic.Pointer[slog.Logger]
 )
 
 // Logger will return the default Slog logger which can be updated via the SetDefaultLogger function.
@@ -24,20 +26,21 @@ func SetDefaultLogger(l *slog.Logger) *slog.Logger {
 
 // DebugLogger will return a default Slog logger at level DEBUG which can be updated via the SetDebugLogger function.
 func DebugLogger() *slog.Logger {
-	if debugLogger == nil {
-		debugLogger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
-			AddSource:   true,
-			Level:       slog.LevelDebug,
-			ReplaceAttr: nil,
-		}))
+	if l := debugLogger.Load(); l != nil {
+		return l
 	}
-	return defaultLogger
+	debugLogger.CompareAndSwap(nil, slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		AddSource:   true,
+		Level:       slog.LevelDebug,
+		ReplaceAttr: nil,
+	})))
+	return debugLogger.Load()
 }
 
 // SetDebugLogger will allow the user to specify a custom logger to be used as the default DEBUG logger.
 func SetDebugLogger(l *slog.Logger) *slog.Logger {
-	defaultLogger = l
-	return Logger()
+	debugLogger.Store(l)
+	return DebugLogger()
 }
 
 // GetConfiguredLogger will return a logger that is configured according to the given configuration.
@@ -1,12 +1,14 @@
 package nmcslog
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/BurntSushi/toml"
 	"github.com/invopop/jsonschema"
@@ -19,17 +21,29 @@ type OutputHandler struct {
 	Disable bool
 	// LogLevel handles the configuration of the current Log Level.
 	LogLevel
-	// Format of the log output, currently FormatText (default) and FormatJSON are supported.
+	// Format of the log output, currently FormatText (default), FormatJSON, FormatECS, FormatGCP, FormatOTEL, FormatGELF, FormatCEF, FormatLEEF, FormatTemplate, FormatMsgPack and FormatGitHub are supported.
 	Format OutputFormat
 	// IncludeSource will include the source code position of the log statement.
 	IncludeSource bool
 	// IncludeFullSource will include the directory for the source's filename.
 	IncludeFullSource bool
+	// Resource attributes describe the service emitting the logs, such as service.name, and are added by the structured formats.
+	Resource map[string]string `json:",omitempty" jsonschema:"title=Resource Attributes"`
+	// Template is the Go text/template of FormatTemplate, executed with a TemplateRecord for each log record.
+	Template string `json:",omitempty" jsonschema:"title=Template,example={{.Time | rfc3339}} [{{.Level}}] {{.Message}}"`
+	// SIEM defines the headers and attribute mapping of FormatCEF and FormatLEEF.
+	SIEM SIEMOptions `json:",omitempty"`
+	// Keys overrides the names of the built-in time, level, message and source keys, only applies to FormatText and FormatJSON.
+	Keys OutputKeys `json:",omitempty"`
+	// TimeFormat of the record timestamp for FormatText and FormatJSON, either RFC3339, RFC3339NANO, UNIX, UNIXMILLI, UNIXMICRO, UNIXNANO or a Go time layout.
+	TimeFormat string `json:",omitempty" jsonschema:"title=Time Format,example=RFC3339NANO,example=UNIXMILLI,example=2006-01-02 15:04:05.000"`
+	// TimeZone the record timestamp is converted to for FormatText and FormatJSON, either UTC, Local or an IANA time zone name.
+	TimeZone string `json:",omitempty" jsonschema:"title=Time Zone,example=UTC,example=Local,example=America/New_York"`
 	// Middleware is an array of middleware funcs to modify the log record prior to calling the handler.
 	// https://github.com/samber/slog-multi#custom-middleware
-	Middleware []MiddlewareFunc
+	Middleware []MiddlewareFunc `json:"-"`
 	// AttributeFuncs is an array of functions to modify log record attributes.
-	AttributeFuncs []AttributeFunc
+	AttributeFuncs []AttributeFunc `json:"-"`
 }
 
 func (ob *OutputHandler) Validate() (err error) {
@@ -48,6 +62,17 @@ func (ob *OutputHandler) Validate() (err error) {
 	if err = ob.Format.Validate(); err != nil {
 		return err
 	}
+	if _, err = loadTimeZone(ob.TimeZone); err != nil {
+		return err
+	}
+	if err = ob.SIEM.Validate(); err != nil {
+		return err
+	}
+	if ob.Format == FormatTemplate {
+		if _, err = parseTemplate(ob.Template); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -80,6 +105,18 @@ func (ob *OutputHandler) GetHandler(w io.Writer) (handler slog.Handler, err erro
 		return nil, fmt.Errorf("[%s] %w", ob.Format, ErrHandlerDisabled)
 	}
 
+	if err = ob.LogLevel.Validate(); err != nil {
+		return nil, err
+	}
+
+	encoder, err := ob.Format.encoder(ob)
+	if err != nil {
+		return nil, err
+	}
+	if encoder != nil {
+		return ob.entryHandler(newEncodedWriter(w, encoder))
+	}
+
 	attrFuncs := []AttributeFunc{
 		AttrFixCustomLogLevelNames,
 	}
@@ -92,6 +129,19 @@ func (ob *OutputHandler) GetHandler(w io.Writer) (handler slog.Handler, err erro
 		attrFuncs = append(attrFuncs, ob.AttributeFuncs...)
 	}
 
+	// Formatting and renaming run last so the AttributeFuncs above always see the default keys and values.
+	if ob.TimeFormat != "" || ob.TimeZone != "" {
+		loc, err := loadTimeZone(ob.TimeZone)
+		if err != nil {
+			return nil, err
+		}
+		attrFuncs = append(attrFuncs, AttrFormatTime(ob.TimeFormat, loc))
+	}
+
+	if !ob.Keys.IsZero() {
+		attrFuncs = append(attrFuncs, AttrRenameKeys(ob.Keys))
+	}
+
 	handlerOpts := &slog.HandlerOptions{
 		AddSource:   ob.IncludeSource,
 		Level:       ob.LogLevel.level,
@@ -107,6 +157,73 @@ func (ob *OutputHandler) GetHandler(w io.Writer) (handler slog.Handler, err erro
 	return logHandler, nil
 }
 
+// entryHandler returns the handler for the record based formats and outputs, only the AttributeFuncs are applied
+// as the built-in values are mapped by the entryWriter itself.
+func (ob *OutputHandler) entryHandler(writer entryWriter) (handler slog.Handler, err error) {
+	if err = ob.LogLevel.Validate(); err != nil {
+		return nil, err
+	}
+
+	handlerOpts := &slog.HandlerOptions{
+		AddSource:   ob.IncludeSource,
+		Level:       ob.LogLevel.level,
+		ReplaceAttr: WrapAttributeFuncs(ob.AttributeFuncs...),
+	}
+
+	handler = newEntryHandler(writer, handlerOpts, ob.IncludeFullSource)
+
+	if len(ob.Middleware) > 0 {
+		return WrapMiddlewareFuncs(handler, ob.Middleware...), nil
+	}
+
+	return handler, nil
+}
+
+// formatEncoder returns an entryEncoder for any of the formats, so the outputs that map some values of the entry
+// themselves can write the rest in the configured format. The TEXT and JSON formats pass the entry on to their
+// slog handler without the AttributeFuncs and Middleware, as those were already applied by the entryHandler.
+func (ob *OutputHandler) formatEncoder() (entryEncoder, error) {
+	encoder, err := ob.Format.encoder(ob)
+	if err != nil || encoder != nil {
+		return encoder, err
+	}
+
+	formatter := *ob
+	formatter.IncludeSource = false
+	formatter.AttributeFuncs = nil
+	formatter.Middleware = nil
+
+	var mu sync.Mutex
+	target := &bufferTarget{}
+	handler, err := formatter.GetHandler(target)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(buf *bytes.Buffer, e *entry) error {
+		record := slog.NewRecord(e.Time, e.Level, e.Message, 0)
+		if e.Source != nil && ob.IncludeSource {
+			record.AddAttrs(slog.Any(slog.SourceKey, e.Source))
+		}
+		record.AddAttrs(e.Attrs...)
+
+		mu.Lock()
+		defer mu.Unlock()
+		target.buf = buf
+
+		return handler.Handle(e.Context, record)
+	}, nil
+}
+
+// bufferTarget writes to the buffer currently set, allowing a slog handler to encode into a new buffer for each record.
+type bufferTarget struct {
+	buf *bytes.Buffer
+}
+
+func (bt *bufferTarget) Write(p []byte) (int, error) {
+	return bt.buf.Write(p)
+}
+
 // LogLevel handles the decoding and parsing of a given log level to the slog log level.
 type LogLevel struct {
 	// Level to cutoff log messages, anything below this level will be dropped.
@@ -256,7 +373,7 @@ func (ll *LogLevel) UnmarshalTOML(data []byte) error {
 }
 
 // OutputFormat is the log record output formatting.
-// Currently, JSON and TEXT are supported.
+// Currently, TEXT, JSON, ECS, GCP, OTEL, GELF, CEF, LEEF, TEMPLATE, MSGPACK and GITHUB are supported.
 type OutputFormat string
 
 func (of *OutputFormat) Validate() (err error) {
@@ -278,6 +395,24 @@ func (of *OutputFormat) FromString(format string) error {
 		*of = FormatText
 	case string(FormatJSON):
 		*of = FormatJSON
+	case string(FormatECS):
+		*of = FormatECS
+	case string(FormatGCP):
+		*of = FormatGCP
+	case string(FormatOTEL):
+		*of = FormatOTEL
+	case string(FormatGELF):
+		*of = FormatGELF
+	case string(FormatCEF):
+		*of = FormatCEF
+	case string(FormatLEEF):
+		*of = FormatLEEF
+	case string(FormatTemplate):
+		*of = FormatTemplate
+	case string(FormatMsgPack):
+		*of = FormatMsgPack
+	case string(FormatGitHub):
+		*of = FormatGitHub
 	default:
 		return fmt.Errorf("invalid format: %s", format)
 	}
@@ -321,6 +456,41 @@ func (of *OutputFormat) Handler(w io.Writer, opts *slog.HandlerOptions) slog.Han
 		return slog.NewJSONHandler(w, opts)
 	}
 
+	if encoder, err := of.encoder(&OutputHandler{}); err == nil && encoder != nil {
+		return newEntryHandler(newEncodedWriter(w, encoder), opts, true)
+	}
+
 	// Default to a TextHandler
 	return slog.NewTextHandler(w, opts)
 }
+
+// encoder returns the entryEncoder of the record based formats using the settings of the given OutputHandler,
+// the formats implemented by the slog handlers return nil.
+func (of *OutputFormat) encoder(ob *OutputHandler) (entryEncoder, error) {
+	switch *of {
+	case FormatECS:
+		return encodeECS(ob.Resource), nil
+	case FormatGCP:
+		return encodeGCP(ob.Resource), nil
+	case FormatOTEL:
+		return encodeOTEL(ob.Resource), nil
+	case FormatGELF:
+		return encodeGELF(ob.Resource), nil
+	case FormatCEF:
+		return encodeCEF(ob.SIEM), nil
+	case FormatLEEF:
+		return encodeLEEF(ob.SIEM), nil
+	case FormatTemplate:
+		tmpl, err := parseTemplate(ob.Template)
+		if err != nil {
+			return nil, err
+		}
+		return encodeTemplate(tmpl), nil
+	case FormatMsgPack:
+		return encodeMsgPack(), nil
+	case FormatGitHub:
+		return encodeGitHub(), nil
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,172 @@
+package nmcslog
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// subscriber receives the records matching its filter from a subscriberHub, as values of type T.
+type subscriber[T any] struct {
+	filter  RecordFilter
+	records chan T
+	// delivered is the number of records sent, dropped the number of records missed because the buffer was full.
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+// subscriberHub sends the records to the matching subscribers without blocking, a slow subscriber misses the records
+// once its buffer is full. It backs the live output and the in-process subscriptions.
+type subscriberHub[T any] struct {
+	// encode converts a record once for all the matching subscribers, clone copies it for each of them if set.
+	encode func(e *entry, le *LogEntry) (T, error)
+	clone  func(T) T
+
+	mu          sync.RWMutex
+	subscribers []*subscriber[T]
+	closed      bool
+	// active is set while there are subscribers, minLevel is the lowest level of their filters.
+	active   atomic.Bool
+	minLevel atomic.Int64
+
+	records atomic.Uint64
+	dropped atomic.Uint64
+}
+
+func newSubscriberHub[T any](encode func(e *entry, le *LogEntry) (T, error), clone func(T) T) *subscriberHub[T] {
+	return &subscriberHub[T]{encode: encode, clone: clone}
+}
+
+// handler wraps the handler writing to the hub so the records no subscriber is interested in are skipped before they
+// are resolved.
+func (sh *subscriberHub[T]) handler(handler slog.Handler) slog.Handler {
+	return &subscriberHandler[T]{Handler: handler, hub: sh}
+}
+
+// subscribe adds a subscriber with a buffer of bufSize records, it returns nil once the hub is closed.
+func (sh *subscriberHub[T]) subscribe(filter RecordFilter, bufSize int) *subscriber[T] {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if sh.closed {
+		return nil
+	}
+	s := &subscriber[T]{filter: filter, records: make(chan T, bufSize)}
+	sh.subscribers = append(sh.subscribers, s)
+	sh.updateLevels()
+
+	return s
+}
+
+// unsubscribe removes the subscriber and closes its channel.
+func (sh *subscriberHub[T]) unsubscribe(s *subscriber[T]) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	// The channels of the subscribers are already closed once the hub is closed.
+	if i := slices.Index(sh.subscribers, s); i >= 0 {
+		sh.subscribers = slices.Delete(sh.subscribers, i, i+1)
+		sh.updateLevels()
+		close(s.records)
+	}
+}
+
+// list returns the subscribers, in the order they subscribed.
+func (sh *subscriberHub[T]) list() []*subscriber[T] {
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	return slices.Clone(sh.subscribers)
+}
+
+// updateLevels must be called with the lock held.
+func (sh *subscriberHub[T]) updateLevels() {
+	minLevel := int64(math.MaxInt64)
+	for _, s := range sh.subscribers {
+		level := int64(math.MinInt64)
+		if s.filter.Level != nil {
+			level = int64(s.filter.Level.Level())
+		}
+		minLevel = min(minLevel, level)
+	}
+	sh.minLevel.Store(minLevel)
+	sh.active.Store(len(sh.subscribers) > 0)
+}
+
+func (sh *subscriberHub[T]) enabled(level slog.Level) bool {
+	return sh.active.Load() && int64(level) >= sh.minLevel.Load()
+}
+
+func (sh *subscriberHub[T]) writeEntry(e *entry) error {
+	le := newLogEntry(e)
+
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	var record T
+	encoded := false
+	for _, s := range sh.subscribers {
+		if !s.filter.match(&le) {
+			continue
+		}
+		if !encoded {
+			var err error
+			if record, err = sh.encode(e, &le); err != nil {
+				return err
+			}
+			encoded = true
+		}
+
+		value := record
+		if sh.clone != nil {
+			value = sh.clone(record)
+		}
+		select {
+		case s.records <- value:
+			s.delivered.Add(1)
+			sh.records.Add(1)
+		default:
+			s.dropped.Add(1)
+			sh.dropped.Add(1)
+		}
+	}
+
+	return nil
+}
+
+// Close closes the channels of the subscribers, no subscriber can be added afterwards.
+func (sh *subscriberHub[T]) Close() {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if sh.closed {
+		return
+	}
+	sh.closed = true
+	for _, s := range sh.subscribers {
+		close(s.records)
+	}
+	sh.subscribers = nil
+	sh.updateLevels()
+}
+
+// subscriberHandler skips the records no subscriber is interested in before they are resolved.
+type subscriberHandler[T any] struct {
+	slog.Handler
+	hub *subscriberHub[T]
+}
+
+func (sh *subscriberHandler[T]) Enabled(ctx context.Context, level slog.Level) bool {
+	return sh.hub.enabled(level) && sh.Handler.Enabled(ctx, level)
+}
+
+func (sh *subscriberHandler[T]) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &subscriberHandler[T]{Handler: sh.Handler.WithAttrs(attrs), hub: sh.hub}
+}
+
+func (sh *subscriberHandler[T]) WithGroup(name string) slog.Handler {
+	return &subscriberHandler[T]{Handler: sh.Handler.WithGroup(name), hub: sh.hub}
+}
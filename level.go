@@ -0,0 +1,45 @@
+package nmcslog
+
+import (
+	"log/slog"
+)
+
+// Syslog severities as defined by RFC 5424.
+const (
+	syslogEmergency = iota
+	syslogAlert
+	syslogCritical
+	syslogError
+	syslogWarning
+	syslogNotice
+	syslogInformational
+	syslogDebug
+)
+
+// levelName returns the name of the level, preferring the CustomLevelNames over the slog naming.
+func levelName(level slog.Level) string {
+	if name, exists := CustomLevelNames[level]; exists {
+		return name
+	}
+
+	return level.String()
+}
+
+// syslogSeverity maps the level to the closest RFC 5424 severity, FATAL maps to critical as the
+// emergency and alert severities are reserved for system wide conditions.
+func syslogSeverity(level slog.Level) int {
+	switch {
+	case level >= LevelFatal:
+		return syslogCritical
+	case level >= LevelError:
+		return syslogError
+	case level >= LevelWarn:
+		return syslogWarning
+	case level >= LevelNotice:
+		return syslogNotice
+	case level >= LevelInfo:
+		return syslogInformational
+	default:
+		return syslogDebug
+	}
+}
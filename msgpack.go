@@ -0,0 +1,518 @@
+package nmcslog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"reflect"
+	"time"
+)
+
+// A minimal MessagePack codec covering the types needed to encode log records.
+// https://github.com/msgpack/msgpack/blob/master/spec.md
+
+const (
+	// msgpackTimestampExt is the extension type (-1) of the MessagePack timestamp.
+	msgpackTimestampExt byte = 0xff
+	// msgpackMaxLength is the max length of the values read from a stream of unknown size, such as a connection.
+	msgpackMaxLength = 1 << 20
+	// msgpackMaxDepth is the max nesting of the arrays and maps.
+	msgpackMaxDepth = 100
+)
+
+var errMsgpackInvalid = errors.New("invalid msgpack data")
+
+func appendMsgpackNil(b []byte) []byte {
+	return append(b, 0xc0)
+}
+
+func appendMsgpackBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 0xc3)
+	}
+
+	return append(b, 0xc2)
+}
+
+func appendMsgpackInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0:
+		return appendMsgpackUint(b, uint64(v))
+	case v >= -32:
+		return append(b, byte(v))
+	case v >= math.MinInt8:
+		return append(b, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(v))
+	case v >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(v))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(v))
+	}
+}
+
+func appendMsgpackUint(b []byte, v uint64) []byte {
+	switch {
+	case v <= 0x7f:
+		return append(b, byte(v))
+	case v <= math.MaxUint8:
+		return append(b, 0xcc, byte(v))
+	case v <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(v))
+	case v <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(v))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), v)
+	}
+}
+
+func appendMsgpackFloat(b []byte, v float64) []byte {
+	return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(v))
+}
+
+func appendMsgpackString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n <= 31:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+
+	return append(b, s...)
+}
+
+func appendMsgpackBinary(b []byte, data []byte) []byte {
+	switch n := len(data); {
+	case n <= math.MaxUint8:
+		b = append(b, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xc5), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xc6), uint32(n))
+	}
+
+	return append(b, data...)
+}
+
+func appendMsgpackArrayHeader(b []byte, n int) []byte {
+	switch {
+	case n <= 15:
+		return append(b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xdc), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, 0xdd), uint32(n))
+	}
+}
+
+func appendMsgpackMapHeader(b []byte, n int) []byte {
+	switch {
+	case n <= 15:
+		return append(b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xde), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, 0xdf), uint32(n))
+	}
+}
+
+// appendMsgpackTime appends the time as a timestamp 96 extension, preserving the nanoseconds.
+func appendMsgpackTime(b []byte, t time.Time) []byte {
+	b = append(b, 0xc7, 12, msgpackTimestampExt)
+	b = binary.BigEndian.AppendUint32(b, uint32(t.Nanosecond()))
+
+	return binary.BigEndian.AppendUint64(b, uint64(t.Unix()))
+}
+
+// appendMsgpackValue appends the slog value, groups become maps in attribute order.
+func appendMsgpackValue(b []byte, v slog.Value) []byte {
+	switch v.Kind() {
+	case slog.KindString:
+		return appendMsgpackString(b, v.String())
+	case slog.KindInt64:
+		return appendMsgpackInt(b, v.Int64())
+	case slog.KindUint64:
+		return appendMsgpackUint(b, v.Uint64())
+	case slog.KindFloat64:
+		return appendMsgpackFloat(b, v.Float64())
+	case slog.KindBool:
+		return appendMsgpackBool(b, v.Bool())
+	case slog.KindDuration:
+		return appendMsgpackInt(b, v.Duration().Nanoseconds())
+	case slog.KindTime:
+		return appendMsgpackTime(b, v.Time())
+	case slog.KindGroup:
+		return appendMsgpackAttrs(b, v.Group())
+	default:
+		return appendMsgpackAny(b, v.Any())
+	}
+}
+
+// appendMsgpackAttrs appends the attributes as a map.
+func appendMsgpackAttrs(b []byte, attrs []slog.Attr) []byte {
+	b = appendMsgpackMapHeader(b, len(attrs))
+	for _, a := range attrs {
+		b = appendMsgpackString(b, a.Key)
+		b = appendMsgpackValue(b, a.Value)
+	}
+
+	return b
+}
+
+// appendMsgpackAny appends an arbitrary value, types without a MessagePack equivalent are converted via their
+// JSON representation.
+func appendMsgpackAny(b []byte, v any) []byte {
+	switch value := v.(type) {
+	case nil:
+		return appendMsgpackNil(b)
+	case slog.Value:
+		return appendMsgpackValue(b, value)
+	case string:
+		return appendMsgpackString(b, value)
+	case []byte:
+		return appendMsgpackBinary(b, value)
+	case bool:
+		return appendMsgpackBool(b, value)
+	case time.Time:
+		return appendMsgpackTime(b, value)
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return appendMsgpackInt(b, i)
+		}
+		f, _ := value.Float64()
+		return appendMsgpackFloat(b, f)
+	case error:
+		if _, marshaler := value.(json.Marshaler); !marshaler {
+			return appendMsgpackString(b, value.Error())
+		}
+	case json.Marshaler:
+		// Converted via its JSON representation below.
+	case map[string]any:
+		b = appendMsgpackMapHeader(b, len(value))
+		for key, item := range value {
+			b = appendMsgpackString(b, key)
+			b = appendMsgpackAny(b, item)
+		}
+		return b
+	case []any:
+		b = appendMsgpackArrayHeader(b, len(value))
+		for _, item := range value {
+			b = appendMsgpackAny(b, item)
+		}
+		return b
+	default:
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return appendMsgpackInt(b, rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return appendMsgpackUint(b, rv.Uint())
+		case reflect.Float32, reflect.Float64:
+			return appendMsgpackFloat(b, rv.Float())
+		case reflect.String:
+			return appendMsgpackString(b, rv.String())
+		case reflect.Bool:
+			return appendMsgpackBool(b, rv.Bool())
+		}
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return appendMsgpackString(b, fmt.Sprintf("%+v", v))
+	}
+	var generic any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&generic); err != nil {
+		return appendMsgpackString(b, string(data))
+	}
+
+	return appendMsgpackAny(b, generic)
+}
+
+// msgpackReader decodes MessagePack values into slog values, maps become groups preserving their order.
+type msgpackReader struct {
+	r *bufio.Reader
+	// maxLength caps the lengths of the strings, binaries, extensions, arrays and maps, so that a corrupt length
+	// cannot exhaust the memory.
+	maxLength int
+	depth     int
+}
+
+// newMsgpackReader returns a msgpackReader reading from r, maxLength is usually the size of the input if known.
+func newMsgpackReader(r io.Reader, maxLength int) *msgpackReader {
+	return &msgpackReader{r: bufio.NewReader(r), maxLength: maxLength}
+}
+
+func (mr *msgpackReader) readByte() (byte, error) {
+	return mr.r.ReadByte()
+}
+
+func (mr *msgpackReader) readN(n int) ([]byte, error) {
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(mr.r, buf); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	return buf, nil
+}
+
+func (mr *msgpackReader) readUint(size int) (uint64, error) {
+	buf, err := mr.readN(size)
+	if err != nil {
+		return 0, err
+	}
+
+	switch size {
+	case 1:
+		return uint64(buf[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(buf)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(buf)), nil
+	default:
+		return binary.BigEndian.Uint64(buf), nil
+	}
+}
+
+// readLength reads a length of the given size in bytes, and checks it against the max length.
+func (mr *msgpackReader) readLength(size int) (int, error) {
+	n, err := mr.readUint(size)
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(mr.maxLength) {
+		return 0, fmt.Errorf("%w: length %d exceeds %d", errMsgpackInvalid, n, mr.maxLength)
+	}
+
+	return int(n), nil
+}
+
+// readMapHeader reads the header of a map and returns its number of entries.
+func (mr *msgpackReader) readMapHeader() (int, error) {
+	c, err := mr.readByte()
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case c&0xf0 == 0x80:
+		return int(c & 0x0f), nil
+	case c == 0xde:
+		return mr.readLength(2)
+	case c == 0xdf:
+		return mr.readLength(4)
+	default:
+		return 0, fmt.Errorf("%w: expected map, got 0x%02x", errMsgpackInvalid, c)
+	}
+}
+
+// readString reads a string value.
+func (mr *msgpackReader) readString() (string, error) {
+	v, err := mr.readValue()
+	if err != nil {
+		return "", err
+	}
+	if v.Kind() != slog.KindString {
+		return "", fmt.Errorf("%w: expected string, got %s", errMsgpackInvalid, v.Kind())
+	}
+
+	return v.String(), nil
+}
+
+// readValue reads the next value.
+func (mr *msgpackReader) readValue() (slog.Value, error) {
+	c, err := mr.readByte()
+	if err != nil {
+		return slog.Value{}, err
+	}
+
+	switch {
+	case c <= 0x7f:
+		return slog.Int64Value(int64(c)), nil
+	case c >= 0xe0:
+		return slog.Int64Value(int64(int8(c))), nil
+	case c&0xf0 == 0x80:
+		return mr.readMap(int(c & 0x0f))
+	case c&0xf0 == 0x90:
+		return mr.readArray(int(c & 0x0f))
+	case c&0xe0 == 0xa0:
+		return mr.readStr(int(c & 0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		return slog.AnyValue(nil), nil
+	case 0xc2:
+		return slog.BoolValue(false), nil
+	case 0xc3:
+		return slog.BoolValue(true), nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := mr.readLength(1 << (c - 0xc4))
+		if err != nil {
+			return slog.Value{}, err
+		}
+		data, err := mr.readN(n)
+		return slog.AnyValue(data), err
+	case 0xc7, 0xc8, 0xc9:
+		n, err := mr.readLength(1 << (c - 0xc7))
+		if err != nil {
+			return slog.Value{}, err
+		}
+		return mr.readExt(n)
+	case 0xca:
+		bits, err := mr.readUint(4)
+		return slog.Float64Value(float64(math.Float32frombits(uint32(bits)))), err
+	case 0xcb:
+		bits, err := mr.readUint(8)
+		return slog.Float64Value(math.Float64frombits(bits)), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := mr.readUint(1 << (c - 0xcc))
+		return slog.Uint64Value(n), err
+	case 0xd0:
+		n, err := mr.readUint(1)
+		return slog.Int64Value(int64(int8(n))), err
+	case 0xd1:
+		n, err := mr.readUint(2)
+		return slog.Int64Value(int64(int16(n))), err
+	case 0xd2:
+		n, err := mr.readUint(4)
+		return slog.Int64Value(int64(int32(n))), err
+	case 0xd3:
+		n, err := mr.readUint(8)
+		return slog.Int64Value(int64(n)), err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return mr.readExt(1 << (c - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := mr.readLength(1 << (c - 0xd9))
+		if err != nil {
+			return slog.Value{}, err
+		}
+		return mr.readStr(n)
+	case 0xdc, 0xdd:
+		n, err := mr.readLength(2 << (c - 0xdc))
+		if err != nil {
+			return slog.Value{}, err
+		}
+		return mr.readArray(n)
+	case 0xde, 0xdf:
+		n, err := mr.readLength(2 << (c - 0xde))
+		if err != nil {
+			return slog.Value{}, err
+		}
+		return mr.readMap(n)
+	default:
+		return slog.Value{}, fmt.Errorf("%w: unknown type 0x%02x", errMsgpackInvalid, c)
+	}
+}
+
+func (mr *msgpackReader) readStr(n int) (slog.Value, error) {
+	data, err := mr.readN(n)
+	if err != nil {
+		return slog.Value{}, err
+	}
+
+	return slog.StringValue(string(data)), nil
+}
+
+func (mr *msgpackReader) readArray(n int) (slog.Value, error) {
+	if err := mr.enter(); err != nil {
+		return slog.Value{}, err
+	}
+	defer mr.leave()
+
+	values := make([]any, n)
+	for i := range values {
+		v, err := mr.readValue()
+		if err != nil {
+			return slog.Value{}, unexpectedEOF(err)
+		}
+		values[i] = v.Any()
+	}
+
+	return slog.AnyValue(values), nil
+}
+
+func (mr *msgpackReader) readMap(n int) (slog.Value, error) {
+	if err := mr.enter(); err != nil {
+		return slog.Value{}, err
+	}
+	defer mr.leave()
+
+	attrs := make([]slog.Attr, 0, n)
+	for i := 0; i < n; i++ {
+		key, err := mr.readValue()
+		if err != nil {
+			return slog.Value{}, unexpectedEOF(err)
+		}
+		value, err := mr.readValue()
+		if err != nil {
+			return slog.Value{}, unexpectedEOF(err)
+		}
+		attrs = append(attrs, slog.Attr{Key: key.String(), Value: value})
+	}
+
+	return slog.GroupValue(attrs...), nil
+}
+
+// enter increments the nesting depth of the arrays and maps being read, leave decrements it.
+func (mr *msgpackReader) enter() error {
+	if mr.depth == msgpackMaxDepth {
+		return fmt.Errorf("%w: nesting exceeds %d", errMsgpackInvalid, msgpackMaxDepth)
+	}
+	mr.depth++
+
+	return nil
+}
+
+func (mr *msgpackReader) leave() {
+	mr.depth--
+}
+
+// readExt reads the extension data, only timestamps are supported and other types are returned as bytes.
+func (mr *msgpackReader) readExt(n int) (slog.Value, error) {
+	extType, err := mr.readByte()
+	if err != nil {
+		return slog.Value{}, unexpectedEOF(err)
+	}
+	data, err := mr.readN(n)
+	if err != nil {
+		return slog.Value{}, err
+	}
+	if extType != msgpackTimestampExt {
+		return slog.AnyValue(data), nil
+	}
+
+	switch n {
+	case 4:
+		return slog.TimeValue(time.Unix(int64(binary.BigEndian.Uint32(data)), 0)), nil
+	case 8:
+		value := binary.BigEndian.Uint64(data)
+		return slog.TimeValue(time.Unix(int64(value&0x3ffffffff), int64(value>>34))), nil
+	case 12:
+		nsec := binary.BigEndian.Uint32(data[:4])
+		sec := int64(binary.BigEndian.Uint64(data[4:]))
+		return slog.TimeValue(time.Unix(sec, int64(nsec))), nil
+	default:
+		return slog.Value{}, fmt.Errorf("%w: timestamp of %d bytes", errMsgpackInvalid, n)
+	}
+}
+
+// unexpectedEOF converts io.EOF into io.ErrUnexpectedEOF for reads within a value.
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
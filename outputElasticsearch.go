@@ -0,0 +1,267 @@
+package nmcslog
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultIndexDateLayout is the Go time layout of the date added to the index names, such as logs-api-2024.05.06.
+const DefaultIndexDateLayout = "2006.01.02"
+
+// ElasticsearchOutput defines the settings specific to the Elasticsearch and OpenSearch bulk output, its Format
+// defaults to ECS.
+type ElasticsearchOutput struct {
+	OutputHandler
+	// URL of the cluster, the records are sent to its _bulk API. If not provided, Elasticsearch logging will be disabled.
+	URL string `json:",omitempty" jsonschema:"title=Elasticsearch URL,example=https://elasticsearch:9200"`
+	// Index is the prefix of the daily index names, defaults to logs- followed by the executable name.
+	Index string `json:",omitempty" jsonschema:"title=Index Prefix,example=logs-api"`
+	// IndexDateLayout is the Go time layout of the UTC record date added to the Index.
+	IndexDateLayout string `json:",omitempty" jsonschema:"title=Index Date Layout,example=2006.01,default=2006.01.02"`
+	// Username to authenticate with HTTP basic authentication.
+	Username string `json:",omitempty" jsonschema:"title=Username,example=elastic"`
+	// Password of the Username.
+	Password string `json:",omitempty" jsonschema:"title=Password"`
+	// APIKey authenticates with the encoded API key, rather than the Username and Password.
+	APIKey string `json:",omitempty" jsonschema:"title=API Key"`
+	// Headers added to each request.
+	Headers map[string]string `json:",omitempty" jsonschema:"title=Headers"`
+	// Gzip compresses the request bodies.
+	Gzip bool `json:",omitempty" jsonschema:"title=Gzip,example=true,default=false"`
+	// Timeout of each request as a Go duration.
+	Timeout string `json:",omitempty" jsonschema:"title=Timeout,example=30s,default=10s"`
+	// Batch defines how the records are grouped and retried.
+	Batch   BatchOptions `json:",omitempty"`
+	batcher *batcher[esDocument]
+}
+
+func (eo *ElasticsearchOutput) enabled() bool {
+	return !eo.Disable && eo.URL != ""
+}
+
+func (eo *ElasticsearchOutput) Validate() (err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: validate config [elasticsearch output]: %w", err)
+		}
+	}()
+
+	if !eo.enabled() {
+		return nil
+	}
+	// The data streams, such as logs-*-*, require the @timestamp field of ECS.
+	if eo.Format == "" {
+		eo.Format = FormatECS
+	}
+	if err = eo.OutputHandler.Validate(); err != nil {
+		return err
+	}
+	if !eo.Format.jsonLines() {
+		return fmt.Errorf("invalid format [%s], only JSON based formats are supported", eo.Format)
+	}
+	if err = validateHTTPURL(eo.URL); err != nil {
+		return err
+	}
+	if strings.ToLower(eo.index()) != eo.index() || strings.ContainsAny(eo.index(), `\/*?"<>| ,#:`) {
+		return fmt.Errorf("invalid Index [%s]", eo.Index)
+	}
+	if _, err = parseDuration(eo.Timeout, 0); err != nil {
+		return fmt.Errorf("invalid Timeout: %w", err)
+	}
+
+	return eo.Batch.Validate()
+}
+
+func (eo *ElasticsearchOutput) index() string {
+	if eo.Index != "" {
+		return eo.Index
+	}
+
+	return "logs-" + strings.ToLower(filepath.Base(os.Args[0]))
+}
+
+func (eo *ElasticsearchOutput) GetHandler() (handler slog.Handler, err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: get handler [elasticsearch output]: %w", err)
+		}
+	}()
+
+	if !eo.enabled() {
+		return nil, fmt.Errorf("[%s] %w", eo.URL, ErrHandlerDisabled)
+	}
+
+	encoder, err := eo.formatEncoder()
+	if err != nil {
+		return nil, err
+	}
+
+	if eo.batcher == nil {
+		settings, err := eo.Batch.settings()
+		if err != nil {
+			return nil, err
+		}
+		timeout, err := parseDuration(eo.Timeout, DefaultHTTPTimeout)
+		if err != nil {
+			return nil, err
+		}
+		bulkURL, err := url.JoinPath(eo.URL, "_bulk")
+		if err != nil {
+			return nil, fmt.Errorf("invalid URL [%s]: %w", eo.URL, err)
+		}
+
+		headers := maps.Clone(eo.Headers)
+		if headers == nil {
+			headers = map[string]string{}
+		}
+		switch {
+		case eo.APIKey != "":
+			headers["Authorization"] = "ApiKey " + eo.APIKey
+		case eo.Username != "":
+			headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(eo.Username+":"+eo.Password))
+		}
+
+		client := &http.Client{Timeout: timeout}
+		eo.batcher = newBatcher(settings, func(batch []esDocument) error {
+			return sendBulk(client, bulkURL, headers, batch, eo.Gzip)
+		})
+	}
+
+	layout := eo.IndexDateLayout
+	if layout == "" {
+		layout = DefaultIndexDateLayout
+	}
+	writer := &esWriter{
+		index:   eo.index(),
+		layout:  layout,
+		encode:  encoder,
+		batcher: eo.batcher,
+	}
+	handler, err = eo.entryHandler(writer)
+	if err != nil {
+		return nil, fmt.Errorf("getting handler [elasticsearch]: %w", err)
+	}
+
+	return handler, nil
+}
+
+// Stats returns the delivery statistics of the output.
+func (eo *ElasticsearchOutput) Stats() BatchStats {
+	if eo.batcher == nil {
+		return BatchStats{}
+	}
+
+	return eo.batcher.stats()
+}
+
+// Close sends the pending records and stops the output.
+func (eo *ElasticsearchOutput) Close() error {
+	if eo.batcher == nil {
+		return nil
+	}
+
+	return eo.batcher.Close()
+}
+
+// esDocument is a record to be created in the index.
+type esDocument struct {
+	index  string
+	source []byte
+}
+
+// esWriter encodes the entries into documents of the daily index of the record.
+type esWriter struct {
+	index   string
+	layout  string
+	encode  entryEncoder
+	batcher *batcher[esDocument]
+}
+
+func (ew *esWriter) writeEntry(e *entry) error {
+	buf := &bytes.Buffer{}
+	if err := ew.encode(buf, e); err != nil {
+		return fmt.Errorf("encoding document: %w", err)
+	}
+
+	return ew.batcher.add(esDocument{
+		index:  ew.index + "-" + e.Time.UTC().Format(ew.layout),
+		source: bytes.TrimSuffix(buf.Bytes(), []byte("\n")),
+	})
+}
+
+// esBulkResponse is the part of the _bulk API response needed to find the failed items.
+type esBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// sendBulk creates the documents with the _bulk API. The items rejected with a 429 or server error are retried,
+// the others are dropped such as documents failing the mapping.
+func sendBulk(client *http.Client, bulkURL string, headers map[string]string, batch []esDocument, compress bool) error {
+	body := &bytes.Buffer{}
+	for _, doc := range batch {
+		action, err := json.Marshal(map[string]any{"create": map[string]string{"_index": doc.index}})
+		if err != nil {
+			return &batchError{err: err, permanent: true}
+		}
+		body.Write(action)
+		body.WriteByte('\n')
+		body.Write(doc.source)
+		body.WriteByte('\n')
+	}
+
+	response := &esBulkResponse{}
+	if err := sendHTTPResponse(client, http.MethodPost, bulkURL, "application/x-ndjson", headers, body.Bytes(), compress, response); err != nil {
+		return err
+	}
+	if !response.Errors {
+		return nil
+	}
+	if len(response.Items) != len(batch) {
+		return &batchError{err: fmt.Errorf("bulk response holds %d items for %d documents", len(response.Items), len(batch)), permanent: true}
+	}
+
+	partial := &batchPartialError[esDocument]{}
+	var reasons []error
+	for i, item := range response.Items {
+		for _, result := range item {
+			switch {
+			case result.Status < 300:
+			case result.Status == http.StatusTooManyRequests || result.Status >= 500:
+				partial.retry = append(partial.retry, batch[i])
+			default:
+				partial.rejected++
+				if len(reasons) < 3 {
+					reasons = append(reasons, fmt.Errorf("[%d] %s: %s", result.Status, result.Error.Type, result.Error.Reason))
+				}
+			}
+		}
+	}
+	if partial.retry == nil && partial.rejected == 0 {
+		return nil
+	}
+
+	err := fmt.Errorf("bulk request failed for %d documents", len(partial.retry)+partial.rejected)
+	if len(reasons) > 0 {
+		err = fmt.Errorf("%w: %w", err, errors.Join(reasons...))
+	}
+	partial.batchError = &batchError{err: err}
+
+	return partial
+}
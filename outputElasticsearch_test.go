@@ -0,0 +1,187 @@
+package nmcslog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBulk is a _bulk API stand-in rejecting the documents with a reject attribute using its status once.
+type fakeBulk struct {
+	mu       sync.Mutex
+	indexed  map[string][]map[string]any
+	rejected map[string]bool
+	auth     string
+	// garbled is the number of responses with an invalid body still to send.
+	garbled int
+}
+
+func (fb *fakeBulk) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/_bulk" {
+		http.NotFound(w, r)
+		return
+	}
+
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	fb.auth = r.Header.Get("Authorization")
+
+	response := esBulkResponse{}
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		action := map[string]map[string]string{}
+		if err := json.Unmarshal(scanner.Bytes(), &action); err != nil || !scanner.Scan() {
+			http.Error(w, "invalid action", http.StatusBadRequest)
+			return
+		}
+		doc := map[string]any{}
+		if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil {
+			http.Error(w, "invalid document", http.StatusBadRequest)
+			return
+		}
+
+		status := http.StatusCreated
+		if reject, ok := doc["reject"].(float64); ok && !fb.rejected[doc["message"].(string)] {
+			fb.rejected[doc["message"].(string)] = true
+			status = int(reject)
+			response.Errors = true
+		} else {
+			index := action["create"]["_index"]
+			fb.indexed[index] = append(fb.indexed[index], doc)
+		}
+
+		item := map[string]struct {
+			Status int `json:"status"`
+			Error  struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		}{"create": {Status: status}}
+		if status >= 300 {
+			result := item["create"]
+			result.Error.Type = "rejected"
+			result.Error.Reason = fmt.Sprintf("status %d", status)
+			item["create"] = result
+		}
+		response.Items = append(response.Items, item)
+	}
+
+	if fb.garbled > 0 {
+		fb.garbled--
+		_, _ = w.Write([]byte("<html>"))
+		return
+	}
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func TestElasticsearchOutput(t *testing.T) {
+	bulk := &fakeBulk{indexed: map[string][]map[string]any{}, rejected: map[string]bool{}}
+	server := httptest.NewServer(bulk)
+	defer server.Close()
+
+	config := Config{
+		Console: ConsoleOutput{OutputHandler: OutputHandler{Disable: true}},
+		File:    FileOutput{OutputHandler: OutputHandler{Disable: true}},
+		Elasticsearch: ElasticsearchOutput{
+			OutputHandler: OutputHandler{Format: FormatECS},
+			URL:           server.URL,
+			Index:         "logs-app",
+			Username:      "elastic",
+			Password:      "changeme",
+			Batch:         BatchOptions{Age: "1h", InitialBackoff: "1ms"},
+		},
+	}
+	logger, err := GetConfiguredLogger(&config)
+	if err != nil {
+		t.Fatalf("GetConfiguredLogger() error = %v", err)
+	}
+
+	logger.Info("indexed")
+	logger.Info("overloaded", "reject", http.StatusTooManyRequests)
+	logger.Info("mapping", "reject", http.StatusBadRequest)
+	err = config.Close()
+	if err == nil || !strings.Contains(err.Error(), "dropped 1 rejected records") {
+		t.Errorf("Close() error = %v, want the rejected record", err)
+	}
+
+	bulk.mu.Lock()
+	defer bulk.mu.Unlock()
+
+	if bulk.auth != "Basic ZWxhc3RpYzpjaGFuZ2VtZQ==" {
+		t.Errorf("Authorization = %q", bulk.auth)
+	}
+	index := "logs-app-" + time.Now().UTC().Format(DefaultIndexDateLayout)
+	docs := bulk.indexed[index]
+	if len(bulk.indexed) != 1 || len(docs) != 2 {
+		t.Fatalf("indexed = %v, want 2 documents in %s", bulk.indexed, index)
+	}
+	if docs[0]["message"] != "indexed" || docs[1]["message"] != "overloaded" || docs[0]["@timestamp"] == nil {
+		t.Errorf("documents = %v", docs)
+	}
+
+	want := BatchStats{Records: 2, Batches: 1, Retries: 1, DroppedRecords: 1}
+	if stats := config.Elasticsearch.Stats(); stats != want {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+}
+
+func TestElasticsearchOutput_InvalidResponse(t *testing.T) {
+	bulk := &fakeBulk{indexed: map[string][]map[string]any{}, rejected: map[string]bool{}, garbled: 1}
+	server := httptest.NewServer(bulk)
+	defer server.Close()
+
+	output := ElasticsearchOutput{URL: server.URL, Index: "logs-app", Batch: BatchOptions{Age: "1h", InitialBackoff: "1ms"}}
+	if err := output.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	handler, err := output.GetHandler()
+	if err != nil {
+		t.Fatalf("GetHandler() error = %v", err)
+	}
+	slog.New(handler).Info("indexed")
+	if err = output.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	bulk.mu.Lock()
+	defer bulk.mu.Unlock()
+
+	// The document of the invalid response was indexed as well, the retry duplicates it.
+	want := BatchStats{Records: 1, Batches: 1, Retries: 1}
+	if stats := output.Stats(); stats != want {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+	docs := bulk.indexed["logs-app-"+time.Now().UTC().Format(DefaultIndexDateLayout)]
+	if len(docs) != 2 || docs[0]["@timestamp"] == nil {
+		t.Errorf("documents = %v, want ECS documents with a @timestamp by default", docs)
+	}
+}
+
+func TestElasticsearchOutput_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  ElasticsearchOutput
+		wantErr bool
+	}{
+		{name: "disabled without URL", output: ElasticsearchOutput{Index: "Invalid"}},
+		{name: "defaults", output: ElasticsearchOutput{URL: "http://localhost:9200"}},
+		{name: "uppercase index", output: ElasticsearchOutput{URL: "http://localhost:9200", Index: "Logs"}, wantErr: true},
+		{name: "index with wildcard", output: ElasticsearchOutput{URL: "http://localhost:9200", Index: "logs-*"}, wantErr: true},
+		{name: "text format", output: ElasticsearchOutput{URL: "http://localhost:9200", OutputHandler: OutputHandler{Format: FormatText}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.output.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package nmcslog
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"time"
+)
+
+const (
+	// DefaultExecTimeout is the default timeout of each write to the stdin of the command.
+	DefaultExecTimeout = 10 * time.Second
+	// DefaultExecStopTimeout is the default time the command is given to exit once its stdin is closed.
+	DefaultExecStopTimeout = 5 * time.Second
+)
+
+// ExecOutput defines the settings specific to the exec output, writing the newline delimited records in the
+// configured format to the stdin of a command, such as logger or a vendor agent. The command is restarted with an
+// exponential backoff whenever it exits, its stderr is logged to the DebugLogger.
+type ExecOutput struct {
+	OutputHandler
+	// Command to run, either a path or a name looked up in the PATH. If not provided, exec logging will be disabled.
+	Command string `json:",omitempty" jsonschema:"title=Command,example=logger,example=/opt/agent/bin/forward"`
+	// Args of the command.
+	Args []string `json:",omitempty" jsonschema:"title=Arguments,example=--tag,example=api"`
+	// Env variables added to the environment of the command.
+	Env map[string]string `json:",omitempty" jsonschema:"title=Environment"`
+	// Dir is the working directory of the command, defaults to the current directory.
+	Dir string `json:",omitempty" jsonschema:"title=Working Directory,example=/opt/agent"`
+	// BufferSize is the max number of records buffered while the command is restarting.
+	BufferSize int `json:",omitempty" jsonschema:"title=Buffer Size,example=50000,default=10000"`
+	// Overflow is the policy once the buffer is full, either DROP_NEWEST (default), DROP_OLDEST or BLOCK.
+	Overflow string `json:",omitempty" jsonschema:"title=Overflow Policy,enum=DROP_NEWEST,enum=DROP_OLDEST,enum=BLOCK,default=DROP_NEWEST"`
+	// InitialBackoff is the delay before the first restart, doubling for each following attempt with a random jitter.
+	InitialBackoff string `json:",omitempty" jsonschema:"title=Initial Backoff,example=1s,default=500ms"`
+	// MaxBackoff is the max delay between restarts.
+	MaxBackoff string `json:",omitempty" jsonschema:"title=Max Backoff,example=1m,default=30s"`
+	// Timeout of each write as a Go duration.
+	Timeout string `json:",omitempty" jsonschema:"title=Timeout,example=30s,default=10s"`
+	// StopTimeout is the time the command is given to exit once its stdin is closed, before it is killed.
+	StopTimeout string `json:",omitempty" jsonschema:"title=Stop Timeout,example=30s,default=5s"`
+	writer      *streamWriter
+}
+
+func (eo *ExecOutput) enabled() bool {
+	return !eo.Disable && eo.Command != ""
+}
+
+func (eo *ExecOutput) Validate() (err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: validate config [exec output]: %w", err)
+		}
+	}()
+
+	if !eo.enabled() {
+		return nil
+	}
+	if eo.Format == "" {
+		eo.Format = FormatText
+	}
+	if err = eo.OutputHandler.Validate(); err != nil {
+		return err
+	}
+	if _, err = exec.LookPath(eo.Command); err != nil {
+		return err
+	}
+	if eo.BufferSize < 0 {
+		return fmt.Errorf("invalid BufferSize [%d]", eo.BufferSize)
+	}
+	if err = validateOverflow(eo.Overflow); err != nil {
+		return err
+	}
+	for name, value := range map[string]string{
+		"InitialBackoff": eo.InitialBackoff,
+		"MaxBackoff":     eo.MaxBackoff,
+		"Timeout":        eo.Timeout,
+		"StopTimeout":    eo.StopTimeout,
+	} {
+		if _, err = parseDuration(value, 0); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func (eo *ExecOutput) GetHandler() (handler slog.Handler, err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: get handler [exec output]: %w", err)
+		}
+	}()
+
+	if !eo.enabled() {
+		return nil, fmt.Errorf("[%s] %w", eo.Command, ErrHandlerDisabled)
+	}
+
+	if eo.writer == nil {
+		settings, err := streamSettings(eo.BufferSize, eo.InitialBackoff, eo.MaxBackoff)
+		if err != nil {
+			return nil, err
+		}
+		timeout, err := parseDuration(eo.Timeout, DefaultExecTimeout)
+		if err != nil {
+			return nil, err
+		}
+		stopTimeout, err := parseDuration(eo.StopTimeout, DefaultExecStopTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		eo.writer = newStreamWriter(eo.Command, func() (io.WriteCloser, error) {
+			process, err := eo.start(stopTimeout)
+			if err != nil {
+				return nil, err
+			}
+			return process, nil
+		}, settings, eo.Overflow, timeout)
+	}
+
+	handler, err = eo.OutputHandler.GetHandler(eo.writer)
+	if err != nil {
+		return nil, fmt.Errorf("getting handler [exec]: %w", err)
+	}
+
+	return handler, nil
+}
+
+// start runs the command, returning its stdin.
+func (eo *ExecOutput) start(stopTimeout time.Duration) (*execProcess, error) {
+	cmd := exec.Command(eo.Command, eo.Args...)
+	cmd.Dir = eo.Dir
+	if len(eo.Env) > 0 {
+		cmd.Env = os.Environ()
+		for key, value := range eo.Env {
+			cmd.Env = append(cmd.Env, key+"="+value)
+		}
+	}
+
+	// The stdin is an os.Pipe rather than cmd.StdinPipe so the writes support deadlines.
+	stdin, stdinWriter, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("creating stdin pipe: %w", err)
+	}
+	cmd.Stdin = stdin
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		_ = stdin.Close()
+		_ = stdinWriter.Close()
+		return nil, fmt.Errorf("creating stderr pipe: %w", err)
+	}
+	err = cmd.Start()
+	// The command holds its own copy of the read end, so the writes fail once it exits.
+	_ = stdin.Close()
+	if err != nil {
+		_ = stdinWriter.Close()
+		return nil, fmt.Errorf("starting command [%s]: %w", eo.Command, err)
+	}
+
+	ep := &execProcess{
+		cmd:         cmd,
+		stdin:       stdinWriter,
+		stopTimeout: stopTimeout,
+		exited:      make(chan struct{}),
+	}
+	go ep.wait(stderr, DebugLogger().With("command", eo.Command, "pid", cmd.Process.Pid))
+
+	return ep, nil
+}
+
+// ExecStats are the delivery statistics of the exec output.
+type ExecStats struct {
+	// Records is the number of records written to the command.
+	Records uint64
+	// Restarts is the number of failed starts and writes, each followed by a restart of the command.
+	Restarts uint64
+	// DroppedRecords is the number of records dropped by the overflow policy or still buffered when closed.
+	DroppedRecords uint64
+}
+
+// Stats returns the delivery statistics of the output.
+func (eo *ExecOutput) Stats() ExecStats {
+	if eo.writer == nil {
+		return ExecStats{}
+	}
+
+	return ExecStats{
+		Records:        eo.writer.records.Load(),
+		Restarts:       eo.writer.reopened.Load(),
+		DroppedRecords: eo.writer.dropped.Load(),
+	}
+}
+
+// Close writes the buffered records if the command is running, then closes its stdin and waits for it to exit.
+func (eo *ExecOutput) Close() error {
+	if eo.writer == nil {
+		return nil
+	}
+
+	return eo.writer.Close()
+}
+
+// execProcess is a running command of the exec output.
+type execProcess struct {
+	cmd         *exec.Cmd
+	stdin       *os.File
+	stopTimeout time.Duration
+	exited      chan struct{}
+}
+
+// wait logs the stderr lines of the command until it exits.
+func (ep *execProcess) wait(stderr io.Reader, diagnostics *slog.Logger) {
+	defer close(ep.exited)
+
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		diagnostics.Warn("exec output stderr", "line", scanner.Text())
+	}
+	// The scanner stops on a line too long, the rest is discarded so the command does not block writing to stderr.
+	if err := scanner.Err(); err != nil {
+		diagnostics.Warn("exec output stderr unreadable", "error", err)
+		_, _ = io.Copy(io.Discard, stderr)
+	}
+
+	if err := ep.cmd.Wait(); err != nil {
+		diagnostics.Warn("exec output command exited", "error", err)
+	} else {
+		diagnostics.Debug("exec output command exited")
+	}
+}
+
+func (ep *execProcess) Write(p []byte) (int, error) {
+	return ep.stdin.Write(p)
+}
+
+func (ep *execProcess) SetWriteDeadline(t time.Time) error {
+	return ep.stdin.SetWriteDeadline(t)
+}
+
+// Close closes the stdin of the command, killing it if it does not exit within the stop timeout.
+func (ep *execProcess) Close() error {
+	err := ep.stdin.Close()
+
+	timer := time.NewTimer(ep.stopTimeout)
+	defer timer.Stop()
+	select {
+	case <-ep.exited:
+		return err
+	case <-timer.C:
+	}
+
+	err = errors.Join(err, ep.cmd.Process.Kill())
+	<-ep.exited
+
+	return errors.Join(err, fmt.Errorf("killed [%s] after the stop timeout of %s", ep.cmd.Path, ep.stopTimeout))
+}
@@ -0,0 +1,204 @@
+package nmcslog
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use, collecting the DebugLogger output.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (sb *syncBuffer) Write(p []byte) (int, error) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	return sb.buf.Write(p)
+}
+
+func (sb *syncBuffer) String() string {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	return sb.buf.String()
+}
+
+// captureDebugLogger replaces the DebugLogger for the duration of the test.
+func captureDebugLogger(t *testing.T) *syncBuffer {
+	diagnostics := &syncBuffer{}
+	previous := debugLogger.Load()
+	SetDebugLogger(slog.New(slog.NewTextHandler(diagnostics, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { SetDebugLogger(previous) })
+
+	return diagnostics
+}
+
+func TestExecOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.log")
+	config := Config{
+		Console: ConsoleOutput{OutputHandler: OutputHandler{Disable: true}},
+		File:    FileOutput{OutputHandler: OutputHandler{Disable: true}},
+		Exec: ExecOutput{
+			Command: "sh",
+			Args:    []string{"-c", `cat > "$RECORDS"`},
+			Env:     map[string]string{"RECORDS": path},
+		},
+	}
+	logger, err := GetConfiguredLogger(&config)
+	if err != nil {
+		t.Fatalf("GetConfiguredLogger() error = %v", err)
+	}
+
+	logger.Info("first", "n", 1)
+	logger.Info("second", "n", 2)
+	// Close waits for the command to exit, so the records are written once it returns.
+	if err = config.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "msg=first n=1") || !strings.Contains(lines[1], "msg=second n=2") {
+		t.Errorf("records = %q, want 2 text records", lines)
+	}
+	if stats := config.Exec.Stats(); stats.Records != 2 || stats.Restarts != 0 {
+		t.Errorf("Stats() = %+v, want 2 records without restart", stats)
+	}
+}
+
+func TestExecOutput_Restart(t *testing.T) {
+	diagnostics := captureDebugLogger(t)
+	path := filepath.Join(t.TempDir(), "records.log")
+	config := Config{
+		Console: ConsoleOutput{OutputHandler: OutputHandler{Disable: true}},
+		File:    FileOutput{OutputHandler: OutputHandler{Disable: true}},
+		Exec: ExecOutput{
+			OutputHandler: OutputHandler{Format: FormatJSON},
+			Command:       "sh",
+			// Each run handles a single record before exiting.
+			Args:           []string{"-c", `read -r line && echo "$line" >> "$0" && echo "handled one" >&2`, path},
+			InitialBackoff: "10ms",
+			MaxBackoff:     "50ms",
+		},
+	}
+	logger, err := GetConfiguredLogger(&config)
+	if err != nil {
+		t.Fatalf("GetConfiguredLogger() error = %v", err)
+	}
+
+	for i := range 3 {
+		logger.Info("restarted", "n", i)
+		waitFor(t, func() bool { return strings.Count(diagnostics.String(), "exec output command exited") == i+1 })
+	}
+	if err = config.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 3 {
+		t.Errorf("records = %q, want 3", lines)
+	}
+	if n := strings.Count(diagnostics.String(), `msg="exec output stderr" command=sh`); n != 3 {
+		t.Errorf("diagnostics = %q, want 3 stderr lines", diagnostics.String())
+	}
+	if stats := config.Exec.Stats(); stats.Records != 3 || stats.Restarts != 2 || stats.DroppedRecords != 0 {
+		t.Errorf("Stats() = %+v, want 3 records after 2 restarts", stats)
+	}
+}
+
+func TestExecOutput_LongStderrLine(t *testing.T) {
+	diagnostics := captureDebugLogger(t)
+	path := filepath.Join(t.TempDir(), "records.log")
+	config := Config{
+		Console: ConsoleOutput{OutputHandler: OutputHandler{Disable: true}},
+		File:    FileOutput{OutputHandler: OutputHandler{Disable: true}},
+		Exec: ExecOutput{
+			Command: "sh",
+			// The stderr line is longer than the scanner buffer and the pipe buffer.
+			Args:        []string{"-c", `head -c 1000000 /dev/zero | tr '\0' x >&2; echo >&2; cat > "$0"`, path},
+			StopTimeout: "5s",
+		},
+	}
+	logger, err := GetConfiguredLogger(&config)
+	if err != nil {
+		t.Fatalf("GetConfiguredLogger() error = %v", err)
+	}
+
+	logger.Info("after long line")
+	if err = config.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(data), "msg=\"after long line\"") {
+		t.Errorf("records = %q, error = %v, want the record", data, err)
+	}
+	if !strings.Contains(diagnostics.String(), "exec output stderr unreadable") {
+		t.Errorf("diagnostics = %q, want the unreadable stderr", diagnostics.String())
+	}
+}
+
+func TestExecOutput_StopTimeout(t *testing.T) {
+	captureDebugLogger(t)
+	config := Config{
+		Console: ConsoleOutput{OutputHandler: OutputHandler{Disable: true}},
+		File:    FileOutput{OutputHandler: OutputHandler{Disable: true}},
+		Exec: ExecOutput{
+			Command:     "sh",
+			Args:        []string{"-c", "cat > /dev/null; exec sleep 10"},
+			StopTimeout: "50ms",
+		},
+	}
+	logger, err := GetConfiguredLogger(&config)
+	if err != nil {
+		t.Fatalf("GetConfiguredLogger() error = %v", err)
+	}
+
+	logger.Info("ignored")
+	waitFor(t, func() bool { return config.Exec.Stats().Records == 1 })
+	start := time.Now()
+	if err = config.Close(); err == nil || !strings.Contains(err.Error(), "stop timeout") {
+		t.Errorf("Close() error = %v, want the command killed", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Close() took %s", elapsed)
+	}
+}
+
+func TestExecOutput_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  ExecOutput
+		wantErr bool
+	}{
+		{name: "disabled without command", output: ExecOutput{Overflow: "NEVER"}},
+		{name: "command in path", output: ExecOutput{Command: "sh"}},
+		{name: "msgpack", output: ExecOutput{Command: "sh", OutputHandler: OutputHandler{Format: FormatMsgPack}}},
+		{name: "missing command", output: ExecOutput{Command: "nmcslog-missing-command"}, wantErr: true},
+		{name: "invalid overflow", output: ExecOutput{Command: "sh", Overflow: "NEVER"}, wantErr: true},
+		{name: "negative buffer", output: ExecOutput{Command: "sh", BufferSize: -1}, wantErr: true},
+		{name: "invalid stop timeout", output: ExecOutput{Command: "sh", StopTimeout: "never"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.output.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
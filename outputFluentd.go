@@ -0,0 +1,342 @@
+package nmcslog
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultFluentdTimeout is the default max time to send a message and receive its ack.
+	DefaultFluentdTimeout = 10 * time.Second
+
+	// fluentdEventTimeExt is the extension type of the Forward protocol EventTime.
+	fluentdEventTimeExt byte = 0x00
+)
+
+// FluentdOutput defines the settings specific to the Fluentd and Fluent Bit output, sending the records with the
+// Forward protocol in PackedForward mode. The records are MessagePack maps holding the level, msg, the optional
+// source and the attributes, the attributes named like those keys being prefixed with an underscore.
+type FluentdOutput struct {
+	OutputHandler
+	// Address of the forward input as host:port, or the path of a unix socket.
+	// If not provided, Fluentd logging will be disabled.
+	Address string `json:",omitempty" jsonschema:"title=Fluentd Address,example=fluentd:24224,example=/var/run/fluent.sock"`
+	// Network to send the messages over, defaults to tcp or for a socket path to unix.
+	Network string `json:",omitempty" jsonschema:"title=Network,enum=tcp,enum=unix"`
+	// Tag of the records, defaults to the executable name.
+	Tag string `json:",omitempty" jsonschema:"title=Tag,example=app.api"`
+	// TagKey is the attribute, by its dotted group path, overriding the Tag of a record. It is removed from the record.
+	TagKey string `json:",omitempty" jsonschema:"title=Tag Attribute,example=tag"`
+	// Ack requests an ack of each message, resending the message if none is received in time.
+	Ack bool `json:",omitempty" jsonschema:"title=Ack,example=true,default=false"`
+	// Timeout to send a message and receive its ack, as a Go duration.
+	Timeout string `json:",omitempty" jsonschema:"title=Timeout,example=30s,default=10s"`
+	// Batch defines how the records are grouped and retried.
+	Batch   BatchOptions `json:",omitempty"`
+	batcher *batcher[fluentdEntry]
+	sender  *fluentdSender
+}
+
+func (fo *FluentdOutput) enabled() bool {
+	return !fo.Disable && fo.Address != ""
+}
+
+func (fo *FluentdOutput) Validate() (err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: validate config [fluentd output]: %w", err)
+		}
+	}()
+
+	if !fo.enabled() {
+		return nil
+	}
+	if fo.Format == "" {
+		fo.Format = FormatMsgPack
+	}
+	if err = fo.OutputHandler.Validate(); err != nil {
+		return err
+	}
+	if fo.Format != FormatMsgPack {
+		return fmt.Errorf("invalid format [%s], only %s is supported", fo.Format, FormatMsgPack)
+	}
+	switch strings.ToLower(fo.Network) {
+	case "", "tcp", "unix":
+	default:
+		return fmt.Errorf("invalid network [%s]", fo.Network)
+	}
+	if _, err = parseDuration(fo.Timeout, 0); err != nil {
+		return fmt.Errorf("invalid Timeout: %w", err)
+	}
+
+	return fo.Batch.Validate()
+}
+
+func (fo *FluentdOutput) GetHandler() (handler slog.Handler, err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: get handler [fluentd output]: %w", err)
+		}
+	}()
+
+	if !fo.enabled() {
+		return nil, fmt.Errorf("[%s] %w", fo.Address, ErrHandlerDisabled)
+	}
+
+	if fo.batcher == nil {
+		settings, err := fo.Batch.settings()
+		if err != nil {
+			return nil, err
+		}
+		timeout, err := parseDuration(fo.Timeout, DefaultFluentdTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		fo.sender = &fluentdSender{
+			network: strings.ToLower(fo.Network),
+			address: fo.Address,
+			ack:     fo.Ack,
+			timeout: timeout,
+		}
+		if fo.sender.network == "" {
+			fo.sender.network = "tcp"
+			if filepath.IsAbs(fo.Address) {
+				fo.sender.network = "unix"
+			}
+		}
+		fo.batcher = newBatcher(settings, fo.sender.send)
+	}
+
+	writer := &fluentdWriter{
+		tag:     fo.Tag,
+		tagKey:  fo.TagKey,
+		batcher: fo.batcher,
+	}
+	if writer.tag == "" {
+		writer.tag = filepath.Base(os.Args[0])
+	}
+	handler, err = fo.entryHandler(writer)
+	if err != nil {
+		return nil, fmt.Errorf("getting handler [fluentd]: %w", err)
+	}
+
+	return handler, nil
+}
+
+// Stats returns the delivery statistics of the output.
+func (fo *FluentdOutput) Stats() BatchStats {
+	if fo.batcher == nil {
+		return BatchStats{}
+	}
+
+	return fo.batcher.stats()
+}
+
+// Close sends the pending records, stops the output and closes the connection.
+func (fo *FluentdOutput) Close() error {
+	if fo.batcher == nil {
+		return nil
+	}
+
+	return errors.Join(fo.batcher.Close(), fo.sender.Close())
+}
+
+// fluentdEntry is an encoded [time, record] entry along with its tag.
+type fluentdEntry struct {
+	tag  string
+	data []byte
+}
+
+// fluentdWriter encodes the entries into Forward protocol entries.
+type fluentdWriter struct {
+	tag     string
+	tagKey  string
+	batcher *batcher[fluentdEntry]
+}
+
+func (fw *fluentdWriter) writeEntry(e *entry) error {
+	tag, attrs := fw.tag, e.Attrs
+	if fw.tagKey != "" {
+		remaining, value, found := takeAttr(attrs, fw.tagKey)
+		if found {
+			tag, attrs = fmt.Sprint(attrValue(value)), remaining
+		}
+	}
+
+	fields := 2 + len(attrs)
+	if e.Source != nil {
+		fields++
+	}
+
+	data := appendMsgpackArrayHeader(nil, 2)
+	data = appendFluentdEventTime(data, e.Time)
+	data = appendMsgpackMapHeader(data, fields)
+	data = appendMsgpackString(data, slog.LevelKey)
+	data = appendMsgpackString(data, levelName(e.Level))
+	data = appendMsgpackString(data, slog.MessageKey)
+	data = appendMsgpackString(data, e.Message)
+	if e.Source != nil {
+		data = appendMsgpackString(data, slog.SourceKey)
+		data = appendMsgpackAttrs(data, []slog.Attr{
+			slog.String("function", e.Source.Function),
+			slog.String("file", e.Source.File),
+			slog.Int("line", e.Source.Line),
+		})
+	}
+	for _, a := range attrs {
+		data = appendMsgpackString(data, fluentdFieldName(a.Key, e.Source != nil))
+		data = appendMsgpackValue(data, a.Value)
+	}
+
+	return fw.batcher.add(fluentdEntry{tag: tag, data: data})
+}
+
+// fluentdFieldName returns the key of the attribute in the record map, the keys of the level, message and source
+// written from the entry are prefixed with an underscore as Fluentd keeps only the last value of a repeated key.
+func fluentdFieldName(key string, source bool) string {
+	if key == slog.LevelKey || key == slog.MessageKey || (source && key == slog.SourceKey) {
+		return "_" + key
+	}
+
+	return key
+}
+
+// appendFluentdEventTime appends the time as the EventTime extension, preserving the nanoseconds.
+func appendFluentdEventTime(b []byte, t time.Time) []byte {
+	b = append(b, 0xd7, fluentdEventTimeExt)
+	b = binary.BigEndian.AppendUint32(b, uint32(t.Unix()))
+
+	return binary.BigEndian.AppendUint32(b, uint32(t.Nanosecond()))
+}
+
+// fluentdSender sends the batches as a PackedForward message per tag, connecting on first use and reconnecting
+// after a failure.
+type fluentdSender struct {
+	mu      sync.Mutex
+	network string
+	address string
+	ack     bool
+	timeout time.Duration
+	conn    net.Conn
+	reader  *msgpackReader
+}
+
+func (fs *fluentdSender) send(batch []fluentdEntry) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	var tags []string
+	entries, sizes := map[string][]byte{}, map[string]int{}
+	for _, entry := range batch {
+		if _, exists := entries[entry.tag]; !exists {
+			tags = append(tags, entry.tag)
+		}
+		entries[entry.tag] = append(entries[entry.tag], entry.data...)
+		sizes[entry.tag]++
+	}
+
+	for i, tag := range tags {
+		if err := fs.forward(tag, entries[tag], sizes[tag]); err != nil {
+			_ = fs.closeConn()
+			err = fmt.Errorf("forwarding to [%s://%s]: %w", fs.network, fs.address, err)
+			if i == 0 {
+				return err
+			}
+			// Only resend the tags that were not delivered.
+			partial := &batchPartialError[fluentdEntry]{batchError: &batchError{err: err}}
+			for _, delivered := range tags[:i] {
+				delete(sizes, delivered)
+			}
+			for _, entry := range batch {
+				if sizes[entry.tag] > 0 {
+					partial.retry = append(partial.retry, entry)
+				}
+			}
+			return partial
+		}
+	}
+
+	return nil
+}
+
+// forward sends the entries of the tag as a PackedForward message and waits for its ack if enabled.
+func (fs *fluentdSender) forward(tag string, entries []byte, size int) error {
+	if fs.conn == nil {
+		conn, err := net.DialTimeout(fs.network, fs.address, DefaultDialTimeout)
+		if err != nil {
+			return err
+		}
+		fs.conn, fs.reader = conn, newMsgpackReader(conn, msgpackMaxLength)
+	}
+	if err := fs.conn.SetDeadline(time.Now().Add(fs.timeout)); err != nil {
+		return err
+	}
+
+	options := 1
+	var chunk string
+	if fs.ack {
+		options++
+		id := make([]byte, 16)
+		_, _ = rand.Read(id)
+		chunk = base64.StdEncoding.EncodeToString(id)
+	}
+
+	message := appendMsgpackArrayHeader(nil, 3)
+	message = appendMsgpackString(message, tag)
+	message = appendMsgpackBinary(message, entries)
+	message = appendMsgpackMapHeader(message, options)
+	message = appendMsgpackString(message, "size")
+	message = appendMsgpackUint(message, uint64(size))
+	if fs.ack {
+		message = appendMsgpackString(message, "chunk")
+		message = appendMsgpackString(message, chunk)
+	}
+	if _, err := fs.conn.Write(message); err != nil {
+		return err
+	}
+	if !fs.ack {
+		return nil
+	}
+
+	response, err := fs.reader.readValue()
+	if err != nil {
+		return fmt.Errorf("reading ack: %w", err)
+	}
+	if response.Kind() == slog.KindGroup {
+		for _, a := range response.Group() {
+			if a.Key == "ack" && a.Value.String() == chunk {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("unexpected ack [%s], want [%s]", response, chunk)
+}
+
+func (fs *fluentdSender) closeConn() error {
+	if fs.conn == nil {
+		return nil
+	}
+	err := fs.conn.Close()
+	fs.conn, fs.reader = nil, nil
+
+	return err
+}
+
+func (fs *fluentdSender) Close() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	return fs.closeConn()
+}
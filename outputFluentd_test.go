@@ -0,0 +1,297 @@
+package nmcslog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// forwardEvent is an entry of a PackedForward message received by the fakeForward server.
+type forwardEvent struct {
+	tag    string
+	time   time.Time
+	record map[string]slog.Value
+}
+
+// fakeForward is a forward input stand-in acking the chunks, it drops the connection without an ack on the first
+// message of the dropTag.
+type fakeForward struct {
+	listener net.Listener
+
+	mu          sync.Mutex
+	dropTag     string
+	events      []forwardEvent
+	connections int
+}
+
+func newFakeForward(t *testing.T, dropTag string) *fakeForward {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	ff := &fakeForward{listener: listener, dropTag: dropTag}
+	go ff.serve(t)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	return ff
+}
+
+func (ff *fakeForward) serve(t *testing.T) {
+	for {
+		conn, err := ff.listener.Accept()
+		if err != nil {
+			return
+		}
+		ff.mu.Lock()
+		ff.connections++
+		ff.mu.Unlock()
+		go ff.handle(t, conn)
+	}
+}
+
+func (ff *fakeForward) handle(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+
+	reader := newMsgpackReader(conn, msgpackMaxLength)
+	for {
+		message, err := reader.readValue()
+		if err != nil {
+			return
+		}
+
+		fields, _ := message.Any().([]any)
+		if len(fields) != 3 {
+			t.Errorf("message = %v, want [tag, entries, option]", message)
+			return
+		}
+		tag, _ := fields[0].(string)
+		ff.mu.Lock()
+		drop := tag == ff.dropTag
+		if drop {
+			ff.dropTag = ""
+		}
+		ff.mu.Unlock()
+		if drop {
+			return
+		}
+		entries, _ := fields[1].([]byte)
+		options, _ := fields[2].([]slog.Attr)
+
+		var chunk string
+		size := int64(-1)
+		for _, option := range options {
+			switch option.Key {
+			case "chunk":
+				chunk = option.Value.String()
+			case "size":
+				size = msgpackTestInt(option.Value)
+			}
+		}
+
+		var received int64
+		entryReader := newMsgpackReader(bytes.NewReader(entries), len(entries))
+		for {
+			entry, err := entryReader.readValue()
+			if err != nil {
+				break
+			}
+			values, _ := entry.Any().([]any)
+			eventTime, _ := values[0].([]byte)
+			record := map[string]slog.Value{}
+			for _, a := range values[1].([]slog.Attr) {
+				record[a.Key] = a.Value
+			}
+			ff.mu.Lock()
+			ff.events = append(ff.events, forwardEvent{
+				tag:    tag,
+				time:   time.Unix(int64(binary.BigEndian.Uint32(eventTime[:4])), int64(binary.BigEndian.Uint32(eventTime[4:]))),
+				record: record,
+			})
+			ff.mu.Unlock()
+			received++
+		}
+		if size != received {
+			t.Errorf("size option = %d, want %d", size, received)
+		}
+
+		if chunk != "" {
+			ack := appendMsgpackMapHeader(nil, 1)
+			ack = appendMsgpackString(ack, "ack")
+			ack = appendMsgpackString(ack, chunk)
+			if _, err = conn.Write(ack); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// msgpackTestInt returns the integer, decoded as an Int64 or Uint64 depending on its MessagePack encoding.
+func msgpackTestInt(v slog.Value) int64 {
+	if v.Kind() == slog.KindUint64 {
+		return int64(v.Uint64())
+	}
+
+	return v.Int64()
+}
+
+func TestFluentdOutput(t *testing.T) {
+	forward := newFakeForward(t, "app.api")
+
+	config := Config{
+		Console: ConsoleOutput{OutputHandler: OutputHandler{Disable: true}},
+		File:    FileOutput{OutputHandler: OutputHandler{Disable: true}},
+		Fluentd: FluentdOutput{
+			Address: forward.listener.Addr().String(),
+			Tag:     "app.api",
+			TagKey:  "audit.tag",
+			Ack:     true,
+			Timeout: "500ms",
+			Batch:   BatchOptions{Age: "1h", InitialBackoff: "1ms"},
+		},
+	}
+	logger, err := GetConfiguredLogger(&config)
+	if err != nil {
+		t.Fatalf("GetConfiguredLogger() error = %v", err)
+	}
+
+	before := time.Now()
+	logger.Info("request", "status", 200, "user", slog.GroupValue(slog.String("name", "ada")))
+	logger.Warn("login", slog.Group("audit", "tag", "app.audit", "action", "login"))
+	if err = config.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	forward.mu.Lock()
+	defer forward.mu.Unlock()
+
+	if forward.connections != 2 {
+		t.Errorf("connections = %d, want a reconnect after the dropped connection", forward.connections)
+	}
+	if len(forward.events) != 2 {
+		t.Fatalf("events = %v, want 2", forward.events)
+	}
+
+	request := forward.events[0]
+	if request.tag != "app.api" || request.record["msg"].String() != "request" || request.record["level"].String() != "INFO" {
+		t.Errorf("event = %+v", request)
+	}
+	if msgpackTestInt(request.record["status"]) != 200 || request.record["user"].Kind() != slog.KindGroup {
+		t.Errorf("record = %v", request.record)
+	}
+	if request.time.Before(before.Truncate(time.Second)) || request.time.After(time.Now()) {
+		t.Errorf("time = %s, want the record time", request.time)
+	}
+
+	login := forward.events[1]
+	audit := login.record["audit"].Group()
+	if login.tag != "app.audit" || len(audit) != 1 || audit[0].Key != "action" {
+		t.Errorf("event = %+v, want the tag attribute moved to the tag", login)
+	}
+
+	want := BatchStats{Records: 2, Batches: 1, Retries: 1}
+	if stats := config.Fluentd.Stats(); stats != want {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+}
+
+func TestFluentdWriter_EntryKeyAttrs(t *testing.T) {
+	var entries []fluentdEntry
+	b := newBatcher(batchSettings{size: 10, age: time.Hour, bufferSize: 10}, func(batch []fluentdEntry) error {
+		entries = append(entries, batch...)
+		return nil
+	})
+	fw := &fluentdWriter{tag: "app", batcher: b}
+
+	attrs := []slog.Attr{slog.String("level", "x"), slog.String("msg", "other"), slog.String("source", "db")}
+	if err := fw.writeEntry(&entry{Time: time.Now(), Level: slog.LevelInfo, Message: "request", Attrs: attrs}); err != nil {
+		t.Fatalf("writeEntry() error = %v", err)
+	}
+	if err := b.Close(); err != nil || len(entries) != 1 {
+		t.Fatalf("Close() error = %v, entries = %d", err, len(entries))
+	}
+
+	// The map header must count the written keys for the whole event to decode.
+	reader := newMsgpackReader(bytes.NewReader(entries[0].data), len(entries[0].data))
+	event, err := reader.readValue()
+	if err != nil {
+		t.Fatalf("readValue() error = %v", err)
+	}
+	record := map[string]string{}
+	for _, a := range event.Any().([]any)[1].([]slog.Attr) {
+		if _, ok := record[a.Key]; ok {
+			t.Errorf("key %s repeated", a.Key)
+		}
+		record[a.Key] = a.Value.String()
+	}
+	want := map[string]string{"level": "INFO", "msg": "request", "_level": "x", "_msg": "other", "source": "db"}
+	if len(record) != len(want) {
+		t.Errorf("record = %v, want %v", record, want)
+	}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("record[%s] = %q, want %q", key, record[key], value)
+		}
+	}
+}
+
+func TestFluentdSender_PartialRetry(t *testing.T) {
+	forward := newFakeForward(t, "b")
+	sender := &fluentdSender{network: "tcp", address: forward.listener.Addr().String(), ack: true, timeout: time.Second}
+	defer sender.Close()
+
+	data := appendMsgpackArrayHeader(nil, 2)
+	data = appendFluentdEventTime(data, time.Now())
+	data = appendMsgpackMapHeader(data, 0)
+	batch := []fluentdEntry{{tag: "a", data: data}, {tag: "b", data: data}, {tag: "a", data: data}}
+
+	err := sender.send(batch)
+	var partial *batchPartialError[fluentdEntry]
+	if !errors.As(err, &partial) {
+		t.Fatalf("send() error = %v, want a partial error", err)
+	}
+	if len(partial.retry) != 1 || partial.retry[0].tag != "b" || partial.rejected != 0 {
+		t.Fatalf("retry = %v, rejected = %d, want the b entry only", partial.retry, partial.rejected)
+	}
+	if err = sender.send(partial.retry); err != nil {
+		t.Fatalf("send() error = %v", err)
+	}
+
+	forward.mu.Lock()
+	defer forward.mu.Unlock()
+
+	tags := map[string]int{}
+	for _, event := range forward.events {
+		tags[event.tag]++
+	}
+	if tags["a"] != 2 || tags["b"] != 1 {
+		t.Errorf("tags = %v, want a twice and b once", tags)
+	}
+}
+
+func TestFluentdOutput_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  FluentdOutput
+		wantErr bool
+	}{
+		{name: "disabled without address", output: FluentdOutput{Network: "udp"}},
+		{name: "defaults", output: FluentdOutput{Address: "localhost:24224"}},
+		{name: "unix socket", output: FluentdOutput{Address: "/var/run/fluent.sock", Network: "unix"}},
+		{name: "udp network", output: FluentdOutput{Address: "localhost:24224", Network: "udp"}, wantErr: true},
+		{name: "json format", output: FluentdOutput{Address: "localhost:24224", OutputHandler: OutputHandler{Format: FormatJSON}}, wantErr: true},
+		{name: "invalid timeout", output: FluentdOutput{Address: "localhost:24224", Timeout: "soon"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.output.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package nmcslog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// GELFCompressionNone sends the GELF messages uncompressed.
+	GELFCompressionNone = "NONE"
+	// GELFCompressionZlib compresses the GELF UDP messages with zlib.
+	GELFCompressionZlib = "ZLIB"
+	// GELFCompressionGzip compresses the GELF UDP messages with gzip.
+	GELFCompressionGzip = "GZIP"
+
+	// DefaultGELFChunkSize is the default max size of a GELF UDP datagram, safe for most WAN links.
+	DefaultGELFChunkSize = 1420
+	// DefaultDialTimeout is the default timeout to connect to a network based output.
+	DefaultDialTimeout = 5 * time.Second
+
+	gelfChunkHeaderSize = 12
+	gelfMaxChunks       = 128
+)
+
+var gelfChunkMagic = []byte{0x1e, 0x0f}
+
+// GELFOutput defines the settings specific to the Graylog Extended Log Format output.
+type GELFOutput struct {
+	OutputHandler
+	// Address of the Graylog GELF input as host:port. If not provided, GELF logging will be disabled.
+	Address string `json:",omitempty" jsonschema:"title=GELF Address,example=graylog.example.com:12201"`
+	// Network to send the messages over, either udp (default) or tcp.
+	Network string `json:",omitempty" jsonschema:"title=Network,enum=udp,enum=tcp,default=udp"`
+	// Compression of the UDP messages, either NONE (default), ZLIB or GZIP. TCP messages are never compressed.
+	Compression string `json:",omitempty" jsonschema:"title=Compression,example=ZLIB,default=NONE"`
+	// ChunkSize is the max size of a UDP datagram, larger messages are split into chunks.
+	ChunkSize int `json:",omitempty" jsonschema:"title=Chunk Size,example=8154,default=1420"`
+	writer    *gelfWriter
+}
+
+func (gl *GELFOutput) enabled() bool {
+	return !gl.Disable && gl.Address != ""
+}
+
+func (gl *GELFOutput) Validate() (err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: validate config [gelf output]: %w", err)
+		}
+	}()
+
+	if !gl.enabled() {
+		return nil
+	}
+	if gl.Format == "" {
+		gl.Format = FormatGELF
+	}
+	if err = gl.OutputHandler.Validate(); err != nil {
+		return err
+	}
+	if gl.Format != FormatGELF {
+		return fmt.Errorf("invalid format [%s], only %s is supported", gl.Format, FormatGELF)
+	}
+	switch strings.ToLower(gl.Network) {
+	case "", "udp", "tcp":
+	default:
+		return fmt.Errorf("invalid network [%s]", gl.Network)
+	}
+	switch strings.ToUpper(gl.Compression) {
+	case "", GELFCompressionNone, GELFCompressionZlib, GELFCompressionGzip:
+	default:
+		return fmt.Errorf("invalid compression [%s]", gl.Compression)
+	}
+	if gl.ChunkSize != 0 && gl.ChunkSize <= gelfChunkHeaderSize {
+		return fmt.Errorf("invalid ChunkSize [%d]", gl.ChunkSize)
+	}
+
+	return nil
+}
+
+func (gl *GELFOutput) GetHandler() (handler slog.Handler, err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: get handler [gelf output]: %w", err)
+		}
+	}()
+
+	if !gl.enabled() {
+		return nil, fmt.Errorf("[%s] %w", gl.Address, ErrHandlerDisabled)
+	}
+
+	if gl.writer == nil {
+		gl.writer = &gelfWriter{
+			network:     strings.ToLower(gl.Network),
+			address:     gl.Address,
+			compression: strings.ToUpper(gl.Compression),
+			chunkSize:   gl.ChunkSize,
+		}
+		if gl.writer.network == "" {
+			gl.writer.network = "udp"
+		}
+		if gl.writer.chunkSize == 0 {
+			gl.writer.chunkSize = DefaultGELFChunkSize
+		}
+	}
+
+	handler, err = gl.entryHandler(newEncodedWriter(gl.writer, encodeGELF(gl.Resource)))
+	if err != nil {
+		return nil, fmt.Errorf("getting handler [gelf]: %w", err)
+	}
+
+	return handler, nil
+}
+
+// Close closes the connection to the GELF input.
+func (gl *GELFOutput) Close() error {
+	if gl.writer == nil {
+		return nil
+	}
+
+	return gl.writer.Close()
+}
+
+// gelfWriter sends each written GELF message to a GELF input, connecting on first use and reconnecting after a failure.
+type gelfWriter struct {
+	mu          sync.Mutex
+	network     string
+	address     string
+	compression string
+	chunkSize   int
+	conn        net.Conn
+}
+
+func (gw *gelfWriter) Write(p []byte) (n int, err error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	message := bytes.TrimSuffix(p, []byte("\n"))
+
+	// Retry once on a new connection in case the previous one went stale.
+	for attempt := 0; attempt < 2; attempt++ {
+		if gw.conn == nil {
+			if gw.conn, err = net.DialTimeout(gw.network, gw.address, DefaultDialTimeout); err != nil {
+				return 0, fmt.Errorf("connecting to GELF input [%s://%s]: %w", gw.network, gw.address, err)
+			}
+		}
+
+		if gw.network == "tcp" {
+			err = gw.writeTCP(message)
+		} else {
+			err = gw.writeUDP(message)
+		}
+		if err == nil {
+			return len(p), nil
+		}
+
+		_ = gw.conn.Close()
+		gw.conn = nil
+	}
+
+	return 0, fmt.Errorf("sending GELF message [%s://%s]: %w", gw.network, gw.address, err)
+}
+
+// writeTCP sends the message terminated by a null byte.
+func (gw *gelfWriter) writeTCP(message []byte) error {
+	_, err := gw.conn.Write(append(bytes.Clone(message), 0))
+	return err
+}
+
+// writeUDP sends the optionally compressed message as a single datagram or as chunks if it exceeds the chunk size.
+func (gw *gelfWriter) writeUDP(message []byte) (err error) {
+	if message, err = gw.compress(message); err != nil {
+		return err
+	}
+
+	if len(message) <= gw.chunkSize {
+		_, err = gw.conn.Write(message)
+		return err
+	}
+
+	payloadSize := gw.chunkSize - gelfChunkHeaderSize
+	count := (len(message) + payloadSize - 1) / payloadSize
+	if count > gelfMaxChunks {
+		return fmt.Errorf("message of %d bytes exceeds %d chunks", len(message), gelfMaxChunks)
+	}
+
+	id := make([]byte, 8)
+	binary.BigEndian.PutUint64(id, rand.Uint64())
+
+	chunk := make([]byte, 0, gw.chunkSize)
+	for i := 0; i < count; i++ {
+		payload := message[i*payloadSize : min((i+1)*payloadSize, len(message))]
+
+		chunk = append(chunk[:0], gelfChunkMagic...)
+		chunk = append(chunk, id...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunk = append(chunk, payload...)
+		if _, err = gw.conn.Write(chunk); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (gw *gelfWriter) compress(message []byte) ([]byte, error) {
+	var compressor io.WriteCloser
+	buf := &bytes.Buffer{}
+
+	switch gw.compression {
+	case GELFCompressionZlib:
+		compressor = zlib.NewWriter(buf)
+	case GELFCompressionGzip:
+		compressor = gzip.NewWriter(buf)
+	default:
+		return message, nil
+	}
+
+	if _, err := compressor.Write(message); err != nil {
+		return nil, fmt.Errorf("compressing message: %w", err)
+	}
+	if err := compressor.Close(); err != nil {
+		return nil, fmt.Errorf("compressing message: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (gw *gelfWriter) Close() error {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	if gw.conn == nil {
+		return nil
+	}
+	err := gw.conn.Close()
+	gw.conn = nil
+
+	return err
+}
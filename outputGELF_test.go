@@ -0,0 +1,140 @@
+package nmcslog
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGELFOutput_UDP(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	defer listener.Close()
+
+	config := Config{
+		Console: ConsoleOutput{OutputHandler: OutputHandler{Disable: true}},
+		File:    FileOutput{OutputHandler: OutputHandler{Disable: true}},
+		GELF: GELFOutput{
+			Address:     listener.LocalAddr().String(),
+			Compression: GELFCompressionZlib,
+			ChunkSize:   64,
+		},
+	}
+	logger, err := GetConfiguredLogger(&config)
+	if err != nil {
+		t.Fatalf("GetConfiguredLogger() error = %v", err)
+	}
+	defer config.Close()
+
+	padding := strings.Repeat("0123456789abcdef", 32)
+	logger.Warn("disk almost full", slog.Group("disk", "free", 12), "id", 42, "padding", padding)
+
+	var chunks [][]byte
+	var received int
+	buf := make([]byte, 65535)
+	_ = listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for chunks == nil || received < len(chunks) {
+		n, _, err := listener.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("reading datagram: %v", err)
+		}
+		datagram := buf[:n]
+		if !bytes.HasPrefix(datagram, gelfChunkMagic) {
+			t.Fatalf("expected a chunked message, got %q", datagram)
+		}
+		if n > 64 {
+			t.Errorf("chunk of %d bytes exceeds the chunk size", n)
+		}
+		if chunks == nil {
+			chunks = make([][]byte, datagram[11])
+		}
+		chunks[datagram[10]] = bytes.Clone(datagram[gelfChunkHeaderSize:])
+		received++
+	}
+
+	reader, err := zlib.NewReader(bytes.NewReader(bytes.Join(chunks, nil)))
+	if err != nil {
+		t.Fatalf("decompressing message: %v", err)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("decompressing message: %v", err)
+	}
+
+	msg := map[string]any{}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatalf("invalid GELF message %q: %v", data, err)
+	}
+	want := map[string]any{
+		"version":       "1.1",
+		"short_message": "disk almost full",
+		"level":         float64(syslogWarning),
+		"_disk_free":    float64(12),
+		"_id_":          float64(42),
+		"_padding":      padding,
+	}
+	for key, value := range want {
+		if msg[key] != value {
+			t.Errorf("%s = %v, want %v", key, msg[key], value)
+		}
+	}
+}
+
+func TestGELFOutput_TCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	defer listener.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		message, _ := bufio.NewReader(conn).ReadString(0)
+		received <- message
+	}()
+
+	output := GELFOutput{
+		OutputHandler: OutputHandler{Resource: map[string]string{"host.name": "web-1"}},
+		Address:       listener.Addr().String(),
+		Network:       "tcp",
+	}
+	if err := output.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	handler, err := output.GetHandler()
+	if err != nil {
+		t.Fatalf("GetHandler() error = %v", err)
+	}
+	defer output.Close()
+	slog.New(handler).Info("started")
+
+	select {
+	case message := <-received:
+		if !strings.HasSuffix(message, "\x00") {
+			t.Errorf("message is not null terminated: %q", message)
+		}
+		msg := map[string]any{}
+		if err := json.Unmarshal([]byte(strings.TrimSuffix(message, "\x00")), &msg); err != nil {
+			t.Fatalf("invalid GELF message %q: %v", message, err)
+		}
+		if msg["host"] != "web-1" || msg["short_message"] != "started" || msg["level"] != float64(syslogInformational) {
+			t.Errorf("message = %v", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the GELF message")
+	}
+}
@@ -0,0 +1,245 @@
+package nmcslog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultHTTPTimeout is the default timeout of a request of the HTTP based outputs.
+const DefaultHTTPTimeout = 10 * time.Second
+
+// HTTPOutput defines the settings specific to the HTTP output, sending batches of newline delimited JSON records.
+type HTTPOutput struct {
+	OutputHandler
+	// URL the batches are sent to. If not provided, HTTP logging will be disabled.
+	URL string `json:",omitempty" jsonschema:"title=URL,example=https://logs.example.com/ingest"`
+	// Method of the requests, defaults to POST.
+	Method string `json:",omitempty" jsonschema:"title=Method,enum=POST,enum=PUT,default=POST"`
+	// Headers added to each request, such as Authorization.
+	Headers map[string]string `json:",omitempty" jsonschema:"title=Headers"`
+	// Gzip compresses the request bodies.
+	Gzip bool `json:",omitempty" jsonschema:"title=Gzip,example=true,default=false"`
+	// Timeout of each request as a Go duration.
+	Timeout string `json:",omitempty" jsonschema:"title=Timeout,example=30s,default=10s"`
+	// Batch defines how the records are grouped and retried.
+	Batch   BatchOptions `json:",omitempty"`
+	batcher *batcher[[]byte]
+}
+
+func (ho *HTTPOutput) enabled() bool {
+	return !ho.Disable && ho.URL != ""
+}
+
+func (ho *HTTPOutput) Validate() (err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: validate config [http output]: %w", err)
+		}
+	}()
+
+	if !ho.enabled() {
+		return nil
+	}
+	if ho.Format == "" {
+		ho.Format = FormatJSON
+	}
+	if err = ho.OutputHandler.Validate(); err != nil {
+		return err
+	}
+	if !ho.Format.jsonLines() {
+		return fmt.Errorf("invalid format [%s], only JSON based formats are supported", ho.Format)
+	}
+	if err = validateHTTPURL(ho.URL); err != nil {
+		return err
+	}
+	switch strings.ToUpper(ho.Method) {
+	case "", http.MethodPost, http.MethodPut:
+	default:
+		return fmt.Errorf("invalid method [%s]", ho.Method)
+	}
+	if _, err = parseDuration(ho.Timeout, 0); err != nil {
+		return fmt.Errorf("invalid Timeout: %w", err)
+	}
+
+	return ho.Batch.Validate()
+}
+
+func (ho *HTTPOutput) GetHandler() (handler slog.Handler, err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: get handler [http output]: %w", err)
+		}
+	}()
+
+	if !ho.enabled() {
+		return nil, fmt.Errorf("[%s] %w", ho.URL, ErrHandlerDisabled)
+	}
+
+	if ho.batcher == nil {
+		settings, err := ho.Batch.settings()
+		if err != nil {
+			return nil, err
+		}
+		timeout, err := parseDuration(ho.Timeout, DefaultHTTPTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		client := &http.Client{Timeout: timeout}
+		method := strings.ToUpper(ho.Method)
+		if method == "" {
+			method = http.MethodPost
+		}
+		ho.batcher = newBatcher(settings, func(batch [][]byte) error {
+			body := append(bytes.Join(batch, []byte("\n")), '\n')
+			return sendHTTP(client, method, ho.URL, "application/x-ndjson", ho.Headers, body, ho.Gzip)
+		})
+	}
+
+	handler, err = ho.OutputHandler.GetHandler(batchWriter{ho.batcher})
+	if err != nil {
+		return nil, fmt.Errorf("getting handler [http]: %w", err)
+	}
+
+	return handler, nil
+}
+
+// Stats returns the delivery statistics of the output.
+func (ho *HTTPOutput) Stats() BatchStats {
+	if ho.batcher == nil {
+		return BatchStats{}
+	}
+
+	return ho.batcher.stats()
+}
+
+// Close sends the pending records and stops the output.
+func (ho *HTTPOutput) Close() error {
+	if ho.batcher == nil {
+		return nil
+	}
+
+	return ho.batcher.Close()
+}
+
+// jsonLines reports whether the format writes each record as a single line of JSON.
+func (of OutputFormat) jsonLines() bool {
+	switch of {
+	case FormatJSON, FormatECS, FormatGCP, FormatOTEL, FormatGELF:
+		return true
+	default:
+		return false
+	}
+}
+
+// batchWriter queues each written record, without the trailing newline, into the batcher.
+type batchWriter struct {
+	batcher *batcher[[]byte]
+}
+
+func (bw batchWriter) Write(p []byte) (int, error) {
+	if err := bw.batcher.add(bytes.Clone(bytes.TrimSuffix(p, []byte("\n")))); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// validateHTTPURL checks the URL is an absolute http or https URL.
+func validateHTTPURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL [%s]: %w", rawURL, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid URL [%s]: must be an absolute http or https URL", rawURL)
+	}
+
+	return nil
+}
+
+// sendHTTP sends the body, gzip compressed if enabled, and maps the response onto a batchError: client errors other
+// than 408 and 429 are permanent failures, the Retry-After header of 429 and 503 responses overrides the backoff.
+func sendHTTP(client *http.Client, method, url, contentType string, headers map[string]string, body []byte, compress bool) error {
+	return sendHTTPResponse(client, method, url, contentType, headers, body, compress, nil)
+}
+
+// sendHTTPResponse is sendHTTP decoding the JSON body of a successful response into the response, if not nil.
+func sendHTTPResponse(client *http.Client, method, url, contentType string, headers map[string]string, body []byte, compress bool, response any) error {
+	if compress {
+		buf := &bytes.Buffer{}
+		gz := gzip.NewWriter(buf)
+		if _, err := gz.Write(body); err != nil {
+			return &batchError{err: fmt.Errorf("compressing body: %w", err), permanent: true}
+		}
+		if err := gz.Close(); err != nil {
+			return &batchError{err: fmt.Errorf("compressing body: %w", err), permanent: true}
+		}
+		body = buf.Bytes()
+	}
+
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return &batchError{err: fmt.Errorf("creating request: %w", err), permanent: true}
+	}
+	req.Header.Set("Content-Type", contentType)
+	if compress {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if response == nil {
+			return nil
+		}
+		if err = json.NewDecoder(resp.Body).Decode(response); err != nil {
+			// Whether the records were accepted is unknown, so they are retried as after a timeout.
+			return fmt.Errorf("decoding response: %w", err)
+		}
+		return nil
+	}
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	be := &batchError{
+		err: fmt.Errorf("unexpected response [%s]: %s", resp.Status, bytes.TrimSpace(message)),
+		permanent: resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+			resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests,
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		be.retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+	}
+
+	return be
+}
+
+// parseRetryAfter returns the delay of a Retry-After header given in seconds or as an HTTP date, or 0 if not set.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0)
+	}
+
+	return 0
+}
@@ -0,0 +1,235 @@
+package nmcslog
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// ndjsonServer records the decoded records of each request.
+type ndjsonServer struct {
+	mu       sync.Mutex
+	requests [][]map[string]any
+	headers  []http.Header
+}
+
+func (ns *ndjsonServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body := io.Reader(r.Body)
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body = gz
+	}
+
+	var records []map[string]any
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		record := map[string]any{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		records = append(records, record)
+	}
+
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.requests = append(ns.requests, records)
+	ns.headers = append(ns.headers, r.Header.Clone())
+}
+
+func TestHTTPOutput_Batches(t *testing.T) {
+	received := &ndjsonServer{}
+	server := httptest.NewServer(received)
+	defer server.Close()
+
+	config := Config{
+		Console: ConsoleOutput{OutputHandler: OutputHandler{Disable: true}},
+		File:    FileOutput{OutputHandler: OutputHandler{Disable: true}},
+		HTTP: HTTPOutput{
+			URL:     server.URL,
+			Headers: map[string]string{"Authorization": "Bearer secret"},
+			Gzip:    true,
+			Batch:   BatchOptions{Size: 2, Age: "1h"},
+		},
+	}
+	logger, err := GetConfiguredLogger(&config)
+	if err != nil {
+		t.Fatalf("GetConfiguredLogger() error = %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		logger.Info("record", "i", i)
+	}
+	if err := config.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	received.mu.Lock()
+	defer received.mu.Unlock()
+
+	var i float64
+	for _, records := range received.requests {
+		if len(records) > 2 {
+			t.Errorf("batch of %d records exceeds the batch size", len(records))
+		}
+		for _, record := range records {
+			if record["msg"] != "record" || record["i"] != i {
+				t.Errorf("record = %v, want i=%v", record, i)
+			}
+			i++
+		}
+	}
+	if i != 5 {
+		t.Errorf("received %v records, want 5", i)
+	}
+	for _, header := range received.headers {
+		if header.Get("Authorization") != "Bearer secret" || header.Get("Content-Type") != "application/x-ndjson" {
+			t.Errorf("headers = %v", header)
+		}
+	}
+
+	stats := config.HTTP.Stats()
+	if stats.Records != 5 || stats.Batches != uint64(len(received.requests)) || stats.DroppedRecords != 0 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+}
+
+func TestHTTPOutput_Retries(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []int
+		wantStats BatchStats
+		wantErr   bool
+	}{
+		{
+			name:      "recovers",
+			responses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK},
+			wantStats: BatchStats{Records: 1, Batches: 1, Retries: 2},
+		},
+		{
+			name:      "permanent failure",
+			responses: []int{http.StatusBadRequest},
+			wantStats: BatchStats{FailedBatches: 1, DroppedRecords: 1},
+			wantErr:   true,
+		},
+		{
+			name:      "retries exhausted",
+			responses: []int{500, 500, 500, 500},
+			wantStats: BatchStats{Retries: 2, FailedBatches: 1, DroppedRecords: 1},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tt.responses[min(int(attempts.Add(1))-1, len(tt.responses)-1)]
+				if status == http.StatusTooManyRequests {
+					w.Header().Set("Retry-After", "0")
+				}
+				w.WriteHeader(status)
+			}))
+			defer server.Close()
+
+			output := HTTPOutput{
+				URL:   server.URL,
+				Batch: BatchOptions{MaxRetries: 2, InitialBackoff: "1ms", MaxBackoff: "2ms"},
+			}
+			if err := output.Validate(); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			handler, err := output.GetHandler()
+			if err != nil {
+				t.Fatalf("GetHandler() error = %v", err)
+			}
+			slog.New(handler).Info("record")
+
+			if err := output.Close(); (err != nil) != tt.wantErr {
+				t.Errorf("Close() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if stats := output.Stats(); stats != tt.wantStats {
+				t.Errorf("Stats() = %+v, want %+v", stats, tt.wantStats)
+			}
+		})
+	}
+}
+
+func TestHTTPOutput_CloseUnreachable(t *testing.T) {
+	output := HTTPOutput{
+		URL:   "http://127.0.0.1:1",
+		Batch: BatchOptions{Size: 2, Age: "1h", InitialBackoff: "100ms", MaxBackoff: "200ms", RetryBudget: "1s"},
+	}
+	if err := output.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	handler, err := output.GetHandler()
+	if err != nil {
+		t.Fatalf("GetHandler() error = %v", err)
+	}
+	logger := slog.New(handler)
+	for i := range 10 {
+		logger.Info("record", "i", i)
+	}
+
+	// Without the bound, each of the 5 batches is retried for the retry budget.
+	start := time.Now()
+	if err = output.Close(); err == nil {
+		t.Error("Close() error = nil, want the dropped batches")
+	}
+	if elapsed := time.Since(start); elapsed > 2500*time.Millisecond {
+		t.Errorf("Close() took %s", elapsed)
+	}
+	if stats := output.Stats(); stats.DroppedRecords != 10 || stats.FailedBatches != 5 {
+		t.Errorf("Stats() = %+v, want the 10 records dropped", stats)
+	}
+}
+
+func TestHTTPOutput_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  HTTPOutput
+		wantErr bool
+	}{
+		{name: "disabled without URL", output: HTTPOutput{Method: "GET"}},
+		{name: "defaults", output: HTTPOutput{URL: "https://logs.example.com"}},
+		{name: "ECS format", output: HTTPOutput{URL: "https://logs.example.com", OutputHandler: OutputHandler{Format: FormatECS}}},
+		{name: "text format", output: HTTPOutput{URL: "https://logs.example.com", OutputHandler: OutputHandler{Format: FormatText}}, wantErr: true},
+		{name: "relative URL", output: HTTPOutput{URL: "/ingest"}, wantErr: true},
+		{name: "invalid method", output: HTTPOutput{URL: "https://logs.example.com", Method: "GET"}, wantErr: true},
+		{name: "invalid timeout", output: HTTPOutput{URL: "https://logs.example.com", Timeout: "soon"}, wantErr: true},
+		{name: "invalid batch age", output: HTTPOutput{URL: "https://logs.example.com", Batch: BatchOptions{Age: "-1s"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.output.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter("3"); got != 3*time.Second {
+		t.Errorf("parseRetryAfter(3) = %v", got)
+	}
+	if got := parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)); got <= 58*time.Second || got > time.Minute {
+		t.Errorf("parseRetryAfter(date) = %v", got)
+	}
+	if got := parseRetryAfter("invalid"); got != 0 {
+		t.Errorf("parseRetryAfter(invalid) = %v", got)
+	}
+}
@@ -0,0 +1,208 @@
+package nmcslog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultJournaldSocket is the socket of the native journal protocol of systemd-journald.
+const DefaultJournaldSocket = "/run/systemd/journal/socket"
+
+// journaldInvalidFieldChars matches the characters not allowed in the name of a journal field.
+var journaldInvalidFieldChars = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// journaldEntryFields are the fields written from the entry itself, which the attributes must not repeat.
+var journaldEntryFields = map[string]bool{
+	"MESSAGE":           true,
+	"PRIORITY":          true,
+	"SYSLOG_IDENTIFIER": true,
+	"CODE_FILE":         true,
+	"CODE_LINE":         true,
+	"CODE_FUNC":         true,
+}
+
+// JournaldOutput defines the settings specific to the systemd-journald output.
+type JournaldOutput struct {
+	OutputHandler
+	// Socket of the journal, usually /run/systemd/journal/socket. If not provided, journald logging will be disabled.
+	Socket string `json:",omitempty" jsonschema:"title=Journal Socket,example=/run/systemd/journal/socket"`
+	// SyslogIdentifier identifies the application in the journal, defaults to the executable name.
+	SyslogIdentifier string `json:",omitempty" jsonschema:"title=Syslog Identifier,example=api"`
+	writer           *journaldWriter
+}
+
+func (jo *JournaldOutput) enabled() bool {
+	return !jo.Disable && jo.Socket != ""
+}
+
+func (jo *JournaldOutput) Validate() (err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: validate config [journald output]: %w", err)
+		}
+	}()
+
+	if !jo.enabled() {
+		return nil
+	}
+	// The records are always written as journal fields, with the attributes as text.
+	if jo.Format == "" {
+		jo.Format = FormatText
+	}
+	if err = jo.OutputHandler.Validate(); err != nil {
+		return err
+	}
+	if jo.Format != FormatText {
+		return fmt.Errorf("invalid format [%s], only %s is supported", jo.Format, FormatText)
+	}
+
+	return nil
+}
+
+func (jo *JournaldOutput) GetHandler() (handler slog.Handler, err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: get handler [journald output]: %w", err)
+		}
+	}()
+
+	if !jo.enabled() {
+		return nil, fmt.Errorf("[%s] %w", jo.Socket, ErrHandlerDisabled)
+	}
+
+	identifier := jo.SyslogIdentifier
+	if identifier == "" {
+		identifier = filepath.Base(os.Args[0])
+	}
+
+	if jo.writer == nil {
+		jo.writer = &journaldWriter{socket: jo.Socket}
+	}
+
+	handler, err = jo.entryHandler(newEncodedWriter(jo.writer, encodeJournald(identifier)))
+	if err != nil {
+		return nil, fmt.Errorf("getting handler [journald]: %w", err)
+	}
+
+	return handler, nil
+}
+
+// Close closes the connection to the journal.
+func (jo *JournaldOutput) Close() error {
+	if jo.writer == nil {
+		return nil
+	}
+
+	return jo.writer.Close()
+}
+
+// journaldFieldName returns the attribute key as a journal field name, which consists of uppercase letters, digits
+// and underscores. Names starting with an underscore are reserved for trusted fields and the names of the fields
+// written from the entry would give them a second value, so those are prefixed.
+func journaldFieldName(key string) string {
+	name := journaldInvalidFieldChars.ReplaceAllString(strings.ToUpper(key), "_")
+	if name == "" || name[0] == '_' || (name[0] >= '0' && name[0] <= '9') {
+		name = "X" + name
+	} else if journaldEntryFields[name] {
+		name = "X_" + name
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+
+	return name
+}
+
+// appendJournaldField appends the field in the native journal protocol, values containing a newline are written
+// with their length as the protocol requires.
+func appendJournaldField(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	if strings.Contains(value, "\n") {
+		buf.WriteByte('\n')
+		_ = binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	} else {
+		buf.WriteByte('=')
+	}
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// encodeJournald returns an entryEncoder writing the entry as the fields of a journal entry, attributes become
+// uppercased fields with the keys of nested groups joined by an underscore.
+func encodeJournald(identifier string) entryEncoder {
+	return func(buf *bytes.Buffer, e *entry) error {
+		appendJournaldField(buf, "MESSAGE", e.Message)
+		appendJournaldField(buf, "PRIORITY", strconv.Itoa(syslogSeverity(e.Level)))
+		appendJournaldField(buf, "SYSLOG_IDENTIFIER", identifier)
+		if e.Source != nil {
+			appendJournaldField(buf, "CODE_FILE", e.Source.File)
+			appendJournaldField(buf, "CODE_LINE", strconv.Itoa(e.Source.Line))
+			appendJournaldField(buf, "CODE_FUNC", e.Source.Function)
+		}
+
+		flattenAttrs(e.Attrs, "_", func(key string, value slog.Value) {
+			appendJournaldField(buf, journaldFieldName(key), fmt.Sprint(attrValue(value)))
+		})
+
+		return nil
+	}
+}
+
+// journaldWriter sends each written entry as a datagram to the journal, entries too large for a datagram are passed
+// as a sealed memory file instead.
+type journaldWriter struct {
+	mu     sync.Mutex
+	socket string
+	conn   *net.UnixConn
+}
+
+func (jw *journaldWriter) Write(p []byte) (n int, err error) {
+	jw.mu.Lock()
+	defer jw.mu.Unlock()
+
+	// Retry once on a new connection in case the journal was restarted.
+	for attempt := 0; attempt < 2; attempt++ {
+		if jw.conn == nil {
+			if jw.conn, err = net.DialUnix("unixgram", nil, &net.UnixAddr{Name: jw.socket, Net: "unixgram"}); err != nil {
+				return 0, fmt.Errorf("connecting to journal [%s]: %w", jw.socket, err)
+			}
+		}
+
+		if _, err = jw.conn.Write(p); err == nil {
+			return len(p), nil
+		}
+		if journaldTooLarge(err) {
+			if err = journaldSendFile(jw.conn, p); err != nil {
+				return 0, fmt.Errorf("sending large journal entry [%s]: %w", jw.socket, err)
+			}
+			return len(p), nil
+		}
+
+		_ = jw.conn.Close()
+		jw.conn = nil
+	}
+
+	return 0, fmt.Errorf("sending journal entry [%s]: %w", jw.socket, err)
+}
+
+func (jw *journaldWriter) Close() error {
+	jw.mu.Lock()
+	defer jw.mu.Unlock()
+
+	if jw.conn == nil {
+		return nil
+	}
+	err := jw.conn.Close()
+	jw.conn = nil
+
+	return err
+}
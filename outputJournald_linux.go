@@ -0,0 +1,55 @@
+//go:build linux
+
+package nmcslog
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// journaldTooLarge reports whether the entry was rejected for exceeding the max datagram size.
+func journaldTooLarge(err error) bool {
+	return errors.Is(err, unix.EMSGSIZE) || errors.Is(err, unix.ENOBUFS)
+}
+
+// journaldSendFile writes the entry to a sealed memfd and passes its descriptor to the journal.
+func journaldSendFile(conn *net.UnixConn, entry []byte) error {
+	fd, err := unix.MemfdCreate("journal-entry", unix.MFD_ALLOW_SEALING|unix.MFD_CLOEXEC)
+	if err != nil {
+		return fmt.Errorf("creating memfd: %w", err)
+	}
+	file := os.NewFile(uintptr(fd), "journal-entry")
+	defer file.Close()
+
+	if _, err = file.Write(entry); err != nil {
+		return fmt.Errorf("writing memfd: %w", err)
+	}
+	// The journal only accepts sealed memfds, so the entry cannot be modified after it was passed.
+	seals := unix.F_SEAL_SHRINK | unix.F_SEAL_GROW | unix.F_SEAL_WRITE | unix.F_SEAL_SEAL
+	if _, err = unix.FcntlInt(file.Fd(), unix.F_ADD_SEALS, seals); err != nil {
+		return fmt.Errorf("sealing memfd: %w", err)
+	}
+
+	// WriteMsgUnix refuses connected datagram sockets, so the descriptor is sent on the raw socket.
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return fmt.Errorf("passing memfd: %w", err)
+	}
+	rights := unix.UnixRights(int(file.Fd()))
+	var sendErr error
+	if err = rawConn.Write(func(socket uintptr) bool {
+		sendErr = unix.Sendmsg(int(socket), nil, rights, nil, 0)
+		return !errors.Is(sendErr, unix.EAGAIN)
+	}); err != nil {
+		return fmt.Errorf("passing memfd: %w", err)
+	}
+	if sendErr != nil {
+		return fmt.Errorf("passing memfd: %w", sendErr)
+	}
+
+	return nil
+}
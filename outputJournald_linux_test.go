@@ -0,0 +1,68 @@
+//go:build linux
+
+package nmcslog
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestJournaldOutput_LargeEntry(t *testing.T) {
+	listener := listenJournald(t)
+
+	output := JournaldOutput{Socket: listener.LocalAddr().String()}
+	if err := output.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	handler, err := output.GetHandler()
+	if err != nil {
+		t.Fatalf("GetHandler() error = %v", err)
+	}
+	defer output.Close()
+
+	// Larger than the max datagram size of a unix socket.
+	payload := strings.Repeat("x", 4<<20)
+	record := slog.NewRecord(time.Now(), LevelInfo, "large", 0)
+	record.AddAttrs(slog.String("payload", payload))
+	if err := handler.Handle(context.Background(), record); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	oob := make([]byte, unix.CmsgSpace(4))
+	_, oobn, _, _, err := listener.ReadMsgUnix(make([]byte, 1), oob)
+	if err != nil {
+		t.Fatalf("reading message: %v", err)
+	}
+	messages, err := unix.ParseSocketControlMessage(oob[:oobn])
+	if err != nil || len(messages) != 1 {
+		t.Fatalf("parsing control message: %v", err)
+	}
+	fds, err := unix.ParseUnixRights(&messages[0])
+	if err != nil || len(fds) != 1 {
+		t.Fatalf("parsing file descriptor: %v", err)
+	}
+	file := os.NewFile(uintptr(fds[0]), "journal-entry")
+	defer file.Close()
+
+	seals, err := unix.FcntlInt(file.Fd(), unix.F_GET_SEALS, 0)
+	if err != nil || seals&unix.F_SEAL_WRITE == 0 {
+		t.Errorf("memfd not sealed: %d, %v", seals, err)
+	}
+
+	// The descriptor shares the offset left at the end of the entry by the writer.
+	data, err := io.ReadAll(io.NewSectionReader(file, 0, 1<<30))
+	if err != nil {
+		t.Fatalf("reading memfd: %v", err)
+	}
+	fields := parseJournaldFields(t, data)
+	if fields["MESSAGE"] != "large" || fields["PAYLOAD"] != payload {
+		t.Errorf("entry of %d bytes does not hold the payload", len(data))
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package nmcslog
+
+import (
+	"errors"
+	"net"
+)
+
+// journaldTooLarge reports whether the entry was rejected for exceeding the max datagram size, journald only runs
+// on Linux so other platforms never pass the entry as a file.
+func journaldTooLarge(error) bool {
+	return false
+}
+
+func journaldSendFile(*net.UnixConn, []byte) error {
+	return errors.New("passing journal entries as a file is only supported on linux")
+}
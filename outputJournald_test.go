@@ -0,0 +1,148 @@
+package nmcslog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// parseJournaldFields decodes an entry of the native journal protocol.
+func parseJournaldFields(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+
+	fields := map[string]string{}
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			t.Fatalf("unterminated field %q", data)
+		}
+		line := data[:end]
+		if name, value, ok := bytes.Cut(line, []byte("=")); ok {
+			fields[string(name)] = string(value)
+			data = data[end+1:]
+			continue
+		}
+
+		size := binary.LittleEndian.Uint64(data[end+1 : end+9])
+		fields[string(line)] = string(data[end+9 : end+9+int(size)])
+		data = data[end+9+int(size)+1:]
+	}
+
+	return fields
+}
+
+// listenJournald returns a unixgram socket standing in for the journal.
+func listenJournald(t *testing.T) *net.UnixConn {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "socket")
+	listener, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	_ = listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	return listener
+}
+
+func TestJournaldOutput(t *testing.T) {
+	listener := listenJournald(t)
+
+	config := Config{
+		Console: ConsoleOutput{OutputHandler: OutputHandler{Disable: true}},
+		File:    FileOutput{OutputHandler: OutputHandler{Disable: true}},
+		Journald: JournaldOutput{
+			OutputHandler:    OutputHandler{IncludeSource: true},
+			Socket:           listener.LocalAddr().String(),
+			SyslogIdentifier: "api",
+		},
+	}
+	logger, err := GetConfiguredLogger(&config)
+	if err != nil {
+		t.Fatalf("GetConfiguredLogger() error = %v", err)
+	}
+	defer config.Close()
+
+	logger.WithGroup("http").Warn("slow request", "status", 200, "_internal", true, "body", "line 1\nline 2")
+
+	buf := make([]byte, 65535)
+	n, err := listener.Read(buf)
+	if err != nil {
+		t.Fatalf("reading datagram: %v", err)
+	}
+	fields := parseJournaldFields(t, buf[:n])
+
+	if fields["CODE_FILE"] != "outputJournald_test.go" || fields["CODE_LINE"] == "" || fields["CODE_FUNC"] == "" {
+		t.Errorf("source fields missing: %v", fields)
+	}
+	delete(fields, "CODE_FILE")
+	delete(fields, "CODE_LINE")
+	delete(fields, "CODE_FUNC")
+
+	want := map[string]string{
+		"MESSAGE":           "slow request",
+		"PRIORITY":          "4",
+		"SYSLOG_IDENTIFIER": "api",
+		"HTTP_STATUS":       "200",
+		"HTTP__INTERNAL":    "true",
+		"HTTP_BODY":         "line 1\nline 2",
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("fields = %v, want %v", fields, want)
+	}
+}
+
+func TestJournaldFieldName(t *testing.T) {
+	tests := map[string]string{
+		"user.id":     "USER_ID",
+		"_trusted":    "X_TRUSTED",
+		"1st":         "X1ST",
+		"café":        "CAF_",
+		"":            "X",
+		"requestPath": "REQUESTPATH",
+		"priority":    "X_PRIORITY",
+		"code.line":   "X_CODE_LINE",
+		"http.status": "HTTP_STATUS",
+	}
+	for key, want := range tests {
+		if got := journaldFieldName(key); got != want {
+			t.Errorf("journaldFieldName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestEncodeJournald_EntryFieldAttrs(t *testing.T) {
+	e := &entry{
+		Time:    time.Now(),
+		Level:   slog.LevelError,
+		Message: "failed",
+		Attrs:   []slog.Attr{slog.String("priority", "x"), slog.String("message", "other")},
+	}
+	buf := &bytes.Buffer{}
+	if err := encodeJournald("api")(buf, e); err != nil {
+		t.Fatalf("encode error = %v", err)
+	}
+
+	// The fields are decoded into a map, so the repeated ones are counted on the lines.
+	for _, field := range []string{"PRIORITY=", "MESSAGE="} {
+		var n int
+		for _, line := range bytes.Split(buf.Bytes(), []byte("\n")) {
+			if bytes.HasPrefix(line, []byte(field)) {
+				n++
+			}
+		}
+		if n != 1 {
+			t.Errorf("%s written %d times in %q, want once", field, n, buf.String())
+		}
+	}
+	fields := parseJournaldFields(t, buf.Bytes())
+	if fields["PRIORITY"] != "3" || fields["X_PRIORITY"] != "x" || fields["MESSAGE"] != "failed" || fields["X_MESSAGE"] != "other" {
+		t.Errorf("fields = %v, want the attributes prefixed", fields)
+	}
+}
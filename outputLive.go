@@ -0,0 +1,413 @@
+package nmcslog
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultLiveBufferSize is the default number of records buffered per subscriber, further records are dropped
+	// until the subscriber catches up.
+	DefaultLiveBufferSize = 256
+	// DefaultLiveHeartbeat is the default time between the keep-alive messages of an idle stream.
+	DefaultLiveHeartbeat = 15 * time.Second
+
+	// webSocketGUID is appended to the Sec-WebSocket-Key of the handshake, see RFC 6455 section 1.3.
+	webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	// webSocketMaxFrame is the max payload size of the frames read from the clients, which only send control frames.
+	webSocketMaxFrame = 1 << 16
+)
+
+// LiveOutput defines the settings specific to the live output, streaming the records to the subscribers of its
+// http.Handler as Server-Sent Events, or WebSocket text messages if the client requests an upgrade. Each subscriber
+// filters the records with the level, message and attr query parameters, such as /debug/logs/stream?level=debug.
+// A slow subscriber misses the records once its buffer is full rather than blocking the logging calls. Its Level
+// defaults to DEBUG, independently of the other outputs, and the records are only resolved while subscribed.
+type LiveOutput struct {
+	OutputHandler
+	// Enable the live output. If not enabled, its handler responds with 503 Service Unavailable.
+	Enable bool `json:",omitempty" jsonschema:"title=Enable,example=true,default=false"`
+	// BufferSize is the number of records buffered per subscriber.
+	BufferSize int `json:",omitempty" jsonschema:"title=Buffer Size,example=1000,default=256"`
+	// Heartbeat is the time between the keep-alive messages of an idle stream, as a Go duration.
+	Heartbeat string `json:",omitempty" jsonschema:"title=Heartbeat,example=30s,default=15s"`
+	hub       *subscriberHub[[]byte]
+	heartbeat time.Duration
+}
+
+func (lo *LiveOutput) enabled() bool {
+	return !lo.Disable && lo.Enable
+}
+
+func (lo *LiveOutput) Validate() (err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: validate config [live output]: %w", err)
+		}
+	}()
+
+	if !lo.enabled() {
+		return nil
+	}
+	if lo.Level == "" {
+		lo.Level = "DEBUG"
+	}
+	if lo.Format == "" {
+		lo.Format = FormatJSON
+	}
+	if err = lo.OutputHandler.Validate(); err != nil {
+		return err
+	}
+	if lo.Format != FormatJSON {
+		return fmt.Errorf("invalid format [%s], only %s is supported", lo.Format, FormatJSON)
+	}
+	if lo.BufferSize < 0 {
+		return fmt.Errorf("invalid BufferSize [%d]", lo.BufferSize)
+	}
+	if _, err = parseDuration(lo.Heartbeat, 0); err != nil {
+		return fmt.Errorf("invalid Heartbeat: %w", err)
+	}
+
+	return nil
+}
+
+func (lo *LiveOutput) GetHandler() (handler slog.Handler, err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: get handler [live output]: %w", err)
+		}
+	}()
+
+	if !lo.enabled() {
+		return nil, fmt.Errorf("[live] %w", ErrHandlerDisabled)
+	}
+
+	if lo.hub == nil {
+		if lo.heartbeat, err = parseDuration(lo.Heartbeat, DefaultLiveHeartbeat); err != nil {
+			return nil, err
+		}
+		lo.hub = newSubscriberHub(encodeLive, nil)
+	}
+
+	handler, err = lo.entryHandler(lo.hub)
+	if err != nil {
+		return nil, fmt.Errorf("getting handler [live]: %w", err)
+	}
+
+	return lo.hub.handler(handler), nil
+}
+
+// encodeLive encodes the record as JSON, once for all the subscribers.
+func encodeLive(_ *entry, le *LogEntry) ([]byte, error) {
+	data, err := json.Marshal(le)
+	if err != nil {
+		return nil, fmt.Errorf("encoding record: %w", err)
+	}
+
+	return data, nil
+}
+
+// bufferSize returns the number of records buffered per subscriber.
+func (lo *LiveOutput) bufferSize() int {
+	if lo.BufferSize == 0 {
+		return DefaultLiveBufferSize
+	}
+
+	return lo.BufferSize
+}
+
+// LiveStats are the delivery statistics of the live output.
+type LiveStats struct {
+	// Subscribers is the number of connected subscribers.
+	Subscribers int
+	// Records is the number of records sent to the subscribers, a record counts once per subscriber.
+	Records uint64
+	// DroppedRecords is the number of records missed by the subscribers because their buffer was full.
+	DroppedRecords uint64
+}
+
+// Stats returns the delivery statistics of the output.
+func (lo *LiveOutput) Stats() LiveStats {
+	if lo.hub == nil {
+		return LiveStats{}
+	}
+
+	return LiveStats{
+		Subscribers:    len(lo.hub.list()),
+		Records:        lo.hub.records.Load(),
+		DroppedRecords: lo.hub.dropped.Load(),
+	}
+}
+
+// Close ends the streams of the subscribers.
+func (lo *LiveOutput) Close() error {
+	if lo.hub == nil {
+		return nil
+	}
+	lo.hub.Close()
+
+	return nil
+}
+
+// ServeHTTP streams the records matching the level, message and attr query parameters, as Server-Sent Events or
+// WebSocket text messages if the client requests an upgrade. Each record is sent as JSON, a {"dropped":n} event
+// reports the records missed while the subscriber was too slow.
+func (lo *LiveOutput) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if lo.hub == nil {
+		http.Error(w, "live output not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	filter, err := recordFilterFromQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		lo.serveWebSocket(w, r, filter)
+		return
+	}
+	lo.serveEvents(w, r, filter)
+}
+
+// serveEvents streams the records as Server-Sent Events.
+func (lo *LiveOutput) serveEvents(w http.ResponseWriter, r *http.Request, filter RecordFilter) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	subscriber := lo.hub.subscribe(filter, lo.bufferSize())
+	if subscriber == nil {
+		http.Error(w, "live output closed", http.StatusServiceUnavailable)
+		return
+	}
+	defer lo.hub.unsubscribe(subscriber)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Disable the response buffering of nginx.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(lo.heartbeat)
+	defer heartbeat.Stop()
+
+	// reported is the number of dropped records already reported.
+	var reported uint64
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			_, err = io.WriteString(w, ": keep-alive\n\n")
+		case data, ok := <-subscriber.records:
+			if !ok {
+				// The output was closed.
+				return
+			}
+			if dropped := subscriber.dropped.Load(); dropped > reported {
+				_, err = fmt.Fprintf(w, "event: dropped\ndata: {\"dropped\":%d}\n\n", dropped-reported)
+				reported = dropped
+			}
+			if err == nil {
+				_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+			}
+		}
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// serveWebSocket upgrades the connection and streams the records as WebSocket text messages.
+func (lo *LiveOutput) serveWebSocket(w http.ResponseWriter, r *http.Request, filter RecordFilter) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" || r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported WebSocket handshake", http.StatusBadRequest)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "WebSocket not supported", http.StatusInternalServerError)
+		return
+	}
+	subscriber := lo.hub.subscribe(filter, lo.bufferSize())
+	if subscriber == nil {
+		http.Error(w, "live output closed", http.StatusServiceUnavailable)
+		return
+	}
+	defer lo.hub.unsubscribe(subscriber)
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	accept := sha1.Sum([]byte(key + webSocketGUID))
+	ws := &webSocketConn{conn: conn, w: rw.Writer, timeout: lo.heartbeat}
+	_ = conn.SetWriteDeadline(time.Now().Add(ws.timeout))
+	_, err = fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Accept: %s\r\n\r\n", base64.StdEncoding.EncodeToString(accept[:]))
+	if err != nil || rw.Flush() != nil {
+		return
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		ws.readControl(rw.Reader)
+	}()
+
+	heartbeat := time.NewTicker(lo.heartbeat)
+	defer heartbeat.Stop()
+
+	// reported is the number of dropped records already reported.
+	var reported uint64
+	for {
+		select {
+		case <-closed:
+			return
+		case <-heartbeat.C:
+			err = ws.writeFrame(webSocketPing, nil)
+		case data, ok := <-subscriber.records:
+			if !ok {
+				// The output was closed, 1001 is the going away status code.
+				_ = ws.writeFrame(webSocketClose, binary.BigEndian.AppendUint16(nil, 1001))
+				return
+			}
+			if dropped := subscriber.dropped.Load(); dropped > reported {
+				err = ws.writeFrame(webSocketText, fmt.Appendf(nil, "{\"dropped\":%d}", dropped-reported))
+				reported = dropped
+			}
+			if err == nil {
+				err = ws.writeFrame(webSocketText, data)
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// WebSocket opcodes, see RFC 6455 section 5.2.
+const (
+	webSocketText  = 0x1
+	webSocketClose = 0x8
+	webSocketPing  = 0x9
+	webSocketPong  = 0xA
+)
+
+// webSocketConn writes the server frames of a WebSocket connection, the writes are serialized as the pings of the
+// client are answered from the reading goroutine.
+type webSocketConn struct {
+	conn    net.Conn
+	timeout time.Duration
+
+	mu sync.Mutex
+	w  *bufio.Writer
+}
+
+func (ws *webSocketConn) writeFrame(opcode byte, payload []byte) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	// The server frames are final and unmasked.
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= math.MaxUint16:
+		header = binary.BigEndian.AppendUint16(append(header, 126), uint16(n))
+	default:
+		header = binary.BigEndian.AppendUint64(append(header, 127), uint64(n))
+	}
+
+	_ = ws.conn.SetWriteDeadline(time.Now().Add(ws.timeout))
+	if _, err := ws.w.Write(header); err != nil {
+		return err
+	}
+	if _, err := ws.w.Write(payload); err != nil {
+		return err
+	}
+
+	return ws.w.Flush()
+}
+
+// readControl reads the client frames until the connection is closed, answering the pings and the close frame.
+// The data frames of the client are ignored.
+func (ws *webSocketConn) readControl(r *bufio.Reader) {
+	for {
+		var header [2]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return
+		}
+		opcode := header[0] & 0x0F
+		masked := header[1]&0x80 != 0
+		n := uint64(header[1] & 0x7F)
+		switch n {
+		case 126:
+			var size [2]byte
+			if _, err := io.ReadFull(r, size[:]); err != nil {
+				return
+			}
+			n = uint64(binary.BigEndian.Uint16(size[:]))
+		case 127:
+			var size [8]byte
+			if _, err := io.ReadFull(r, size[:]); err != nil {
+				return
+			}
+			n = binary.BigEndian.Uint64(size[:])
+		}
+		// The client frames must be masked.
+		if !masked || n > webSocketMaxFrame {
+			return
+		}
+
+		var mask [4]byte
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return
+		}
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return
+		}
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+
+		switch opcode {
+		case webSocketClose:
+			// Echo the status code of the client.
+			_ = ws.writeFrame(webSocketClose, payload[:min(len(payload), 2)])
+			return
+		case webSocketPing:
+			if ws.writeFrame(webSocketPong, payload) != nil {
+				return
+			}
+		}
+	}
+}
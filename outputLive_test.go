@@ -0,0 +1,255 @@
+package nmcslog
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newLiveTestConfig(t *testing.T) (*Config, *slog.Logger, *httptest.Server) {
+	t.Helper()
+
+	config := &Config{
+		Console: ConsoleOutput{OutputHandler: OutputHandler{Disable: true}},
+		File:    FileOutput{OutputHandler: OutputHandler{Disable: true}},
+		Live:    LiveOutput{Enable: true},
+	}
+	logger, err := GetConfiguredLogger(config)
+	if err != nil {
+		t.Fatalf("GetConfiguredLogger() error = %v", err)
+	}
+	server := httptest.NewServer(&config.Live)
+	t.Cleanup(server.Close)
+
+	return config, logger, server
+}
+
+func TestLiveOutput_Events(t *testing.T) {
+	config, logger, server := newLiveTestConfig(t)
+
+	query := url.Values{"level": {"warn"}, "attr": {"path=/orders"}}
+	response, err := http.Get(server.URL + "/debug/logs/stream?" + query.Encode())
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK || response.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("response = %d %v", response.StatusCode, response.Header)
+	}
+	waitFor(t, func() bool { return config.Live.Stats().Subscribers == 1 })
+
+	logger.Info("request served", "path", "/orders")
+	logger.Warn("slow request", "path", "/cart")
+	logger.Warn("slow request", "path", "/orders", "ms", 250)
+	if err = config.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// The stream ends once the output is closed.
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	var events []map[string]any
+	for _, line := range strings.Split(string(body), "\n") {
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			event := map[string]any{}
+			if err = json.Unmarshal([]byte(data), &event); err != nil {
+				t.Fatalf("data = %q: %v", data, err)
+			}
+			events = append(events, event)
+		}
+	}
+	if len(events) != 1 || events[0]["level"] != "WARN" || events[0]["attrs"].(map[string]any)["ms"] != float64(250) {
+		t.Errorf("events = %v, want the matching record", events)
+	}
+	if stats := config.Live.Stats(); stats.Records != 1 || stats.DroppedRecords != 0 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+}
+
+func TestLiveOutput_SlowSubscriber(t *testing.T) {
+	output := LiveOutput{Enable: true, BufferSize: 1}
+	if err := output.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	handler, err := output.GetHandler()
+	if err != nil {
+		t.Fatalf("GetHandler() error = %v", err)
+	}
+	logger := slog.New(handler)
+
+	ctx := context.Background()
+	if handler.Enabled(ctx, slog.LevelError) {
+		t.Error("Enabled() = true without subscribers")
+	}
+	subscriber := output.hub.subscribe(RecordFilter{Level: slog.LevelInfo}, output.bufferSize())
+	if handler.Enabled(ctx, slog.LevelDebug) || !handler.Enabled(ctx, slog.LevelInfo) {
+		t.Error("Enabled() does not follow the level of the subscriber")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 3 {
+			logger.Info("not read")
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("logging blocked by the slow subscriber")
+	}
+
+	if dropped := subscriber.dropped.Load(); dropped != 2 || len(subscriber.records) != 1 {
+		t.Errorf("dropped = %d, buffered %d, want 2 dropped and 1 buffered", dropped, len(subscriber.records))
+	}
+	if stats := output.Stats(); stats.Records != 1 || stats.DroppedRecords != 2 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+
+	output.hub.unsubscribe(subscriber)
+	if handler.Enabled(ctx, slog.LevelError) {
+		t.Error("Enabled() = true after the subscriber left")
+	}
+}
+
+// readWebSocketFrame reads an unmasked server frame.
+func readWebSocketFrame(t *testing.T, r *bufio.Reader) (byte, []byte) {
+	t.Helper()
+
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		t.Fatalf("reading frame: %v", err)
+	}
+	n := int(header[1] & 0x7F)
+	if n == 126 {
+		var size [2]byte
+		_, _ = io.ReadFull(r, size[:])
+		n = int(binary.BigEndian.Uint16(size[:]))
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		t.Fatalf("reading frame: %v", err)
+	}
+
+	return header[0] & 0x0F, payload
+}
+
+func TestLiveOutput_WebSocket(t *testing.T) {
+	config, logger, server := newLiveTestConfig(t)
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// The handshake example of RFC 6455 section 1.3.
+	_, err = io.WriteString(conn, "GET /debug/logs/stream?message=request HTTP/1.1\r\nHost: localhost\r\n"+
+		"Upgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"+
+		"Sec-WebSocket-Version: 13\r\n\r\n")
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("ReadResponse() error = %v", err)
+	}
+	if response.StatusCode != http.StatusSwitchingProtocols ||
+		response.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("response = %d %v", response.StatusCode, response.Header)
+	}
+	waitFor(t, func() bool { return config.Live.Stats().Subscribers == 1 })
+
+	logger.Debug("cache miss")
+	logger.Debug("request served", "path", "/orders")
+	opcode, payload := readWebSocketFrame(t, reader)
+	event := map[string]any{}
+	if err = json.Unmarshal(payload, &event); opcode != webSocketText || err != nil || event["msg"] != "request served" {
+		t.Errorf("frame = %d %q, want the matching record as text", opcode, payload)
+	}
+
+	// A masked close frame with the normal closure status code.
+	mask := []byte{1, 2, 3, 4}
+	closeFrame := []byte{0x80 | webSocketClose, 0x80 | 2}
+	closeFrame = append(closeFrame, mask...)
+	closeFrame = append(closeFrame, 0x03^mask[0], 0xE8^mask[1])
+	if _, err = conn.Write(closeFrame); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if opcode, payload = readWebSocketFrame(t, reader); opcode != webSocketClose || binary.BigEndian.Uint16(payload) != 1000 {
+		t.Errorf("frame = %d %v, want the close frame echoed", opcode, payload)
+	}
+	waitFor(t, func() bool { return config.Live.Stats().Subscribers == 0 })
+}
+
+func TestLiveOutput_ServeHTTP_Errors(t *testing.T) {
+	disabled := LiveOutput{}
+	response := httptest.NewRecorder()
+	disabled.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/stream", nil))
+	if response.Code != http.StatusServiceUnavailable {
+		t.Errorf("disabled = %d, want %d", response.Code, http.StatusServiceUnavailable)
+	}
+
+	_, _, server := newLiveTestConfig(t)
+	for target, want := range map[string]int{
+		"/stream?level=loud": http.StatusBadRequest,
+		"/stream?attr=path":  http.StatusBadRequest,
+	} {
+		response, err := http.Get(server.URL + target)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		_ = response.Body.Close()
+		if response.StatusCode != want {
+			t.Errorf("GET %s = %d, want %d", target, response.StatusCode, want)
+		}
+	}
+
+	request, _ := http.NewRequest(http.MethodGet, server.URL+"/stream", nil)
+	request.Header.Set("Upgrade", "websocket")
+	upgrade, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	_ = upgrade.Body.Close()
+	if upgrade.StatusCode != http.StatusBadRequest {
+		t.Errorf("upgrade without key = %d, want %d", upgrade.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestLiveOutput_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  LiveOutput
+		wantErr bool
+	}{
+		{name: "disabled", output: LiveOutput{BufferSize: -1}},
+		{name: "enabled", output: LiveOutput{Enable: true}},
+		{name: "negative buffer", output: LiveOutput{Enable: true, BufferSize: -1}, wantErr: true},
+		{name: "invalid heartbeat", output: LiveOutput{Enable: true, Heartbeat: "often"}, wantErr: true},
+		{name: "zero heartbeat", output: LiveOutput{Enable: true, Heartbeat: "0s"}, wantErr: true},
+		{name: "text format", output: LiveOutput{Enable: true, OutputHandler: OutputHandler{Format: FormatText}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.output.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
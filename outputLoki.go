@@ -0,0 +1,345 @@
+package nmcslog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// lokiPushPath is the path of the push API, added to a Loki URL without a path.
+	lokiPushPath = "/loki/api/v1/push"
+	// lokiLevelLabel is the label holding the lowercased level name rather than an attribute.
+	lokiLevelLabel = "level"
+	// lokiTenantHeader selects the tenant of a multi-tenant Loki.
+	lokiTenantHeader = "X-Scope-OrgID"
+)
+
+// lokiInvalidLabelChars matches the characters not allowed in a Loki label name.
+var lokiInvalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// LokiOutput defines the settings specific to the Grafana Loki output.
+type LokiOutput struct {
+	OutputHandler
+	// URL of Loki, the push API path /loki/api/v1/push is added if the URL has no path.
+	// If not provided, Loki logging will be disabled.
+	URL string `json:",omitempty" jsonschema:"title=Loki URL,example=http://loki:3100"`
+	// Labels are the attributes, by their dotted group path, the records are grouped into streams by.
+	// The label attributes are removed from the line, the level label holds the level name.
+	Labels []string `json:",omitempty" jsonschema:"title=Label Attributes,example=service,example=level"`
+	// StaticLabels are added to every stream, such as the environment.
+	StaticLabels map[string]string `json:",omitempty" jsonschema:"title=Static Labels"`
+	// TenantID is sent as the X-Scope-OrgID header of a multi-tenant Loki.
+	TenantID string `json:",omitempty" jsonschema:"title=Tenant ID,example=team-a"`
+	// Headers added to each request, such as Authorization.
+	Headers map[string]string `json:",omitempty" jsonschema:"title=Headers"`
+	// Protobuf pushes snappy compressed protobuf requests rather than JSON.
+	Protobuf bool `json:",omitempty" jsonschema:"title=Protobuf,example=true,default=false"`
+	// Gzip compresses the JSON requests.
+	Gzip bool `json:",omitempty" jsonschema:"title=Gzip,example=true,default=false"`
+	// Timeout of each request as a Go duration.
+	Timeout string `json:",omitempty" jsonschema:"title=Timeout,example=30s,default=10s"`
+	// Batch defines how the records are grouped and retried.
+	Batch   BatchOptions `json:",omitempty"`
+	batcher *batcher[lokiEntry]
+}
+
+func (lo *LokiOutput) enabled() bool {
+	return !lo.Disable && lo.URL != ""
+}
+
+func (lo *LokiOutput) Validate() (err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: validate config [loki output]: %w", err)
+		}
+	}()
+
+	if !lo.enabled() {
+		return nil
+	}
+	if lo.Format == "" {
+		lo.Format = FormatText
+	}
+	if err = lo.OutputHandler.Validate(); err != nil {
+		return err
+	}
+	if lo.Format == FormatMsgPack {
+		return fmt.Errorf("invalid format [%s], only text based formats are supported", lo.Format)
+	}
+	if err = validateHTTPURL(lo.URL); err != nil {
+		return err
+	}
+	for name := range lo.StaticLabels {
+		if lokiLabelName(name) != name {
+			return fmt.Errorf("invalid static label [%s]", name)
+		}
+	}
+	if _, err = parseDuration(lo.Timeout, 0); err != nil {
+		return fmt.Errorf("invalid Timeout: %w", err)
+	}
+
+	return lo.Batch.Validate()
+}
+
+func (lo *LokiOutput) GetHandler() (handler slog.Handler, err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: get handler [loki output]: %w", err)
+		}
+	}()
+
+	if !lo.enabled() {
+		return nil, fmt.Errorf("[%s] %w", lo.URL, ErrHandlerDisabled)
+	}
+
+	encoder, err := lo.formatEncoder()
+	if err != nil {
+		return nil, err
+	}
+
+	if lo.batcher == nil {
+		settings, err := lo.Batch.settings()
+		if err != nil {
+			return nil, err
+		}
+		timeout, err := parseDuration(lo.Timeout, DefaultHTTPTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		pushURL, err := lokiPushURL(lo.URL)
+		if err != nil {
+			return nil, err
+		}
+		headers := maps.Clone(lo.Headers)
+		if lo.TenantID != "" {
+			if headers == nil {
+				headers = map[string]string{}
+			}
+			headers[lokiTenantHeader] = lo.TenantID
+		}
+
+		client := &http.Client{Timeout: timeout}
+		lo.batcher = newBatcher(settings, func(batch []lokiEntry) error {
+			if lo.Protobuf {
+				return sendHTTP(client, http.MethodPost, pushURL, "application/x-protobuf", headers, lokiProtobuf(batch), false)
+			}
+			body, err := lokiJSON(batch)
+			if err != nil {
+				return &batchError{err: err, permanent: true}
+			}
+			return sendHTTP(client, http.MethodPost, pushURL, "application/json", headers, body, lo.Gzip)
+		})
+	}
+
+	writer := &lokiWriter{
+		labels:  lo.Labels,
+		static:  lo.StaticLabels,
+		encode:  encoder,
+		batcher: lo.batcher,
+	}
+	handler, err = lo.entryHandler(writer)
+	if err != nil {
+		return nil, fmt.Errorf("getting handler [loki]: %w", err)
+	}
+
+	return handler, nil
+}
+
+// Stats returns the delivery statistics of the output.
+func (lo *LokiOutput) Stats() BatchStats {
+	if lo.batcher == nil {
+		return BatchStats{}
+	}
+
+	return lo.batcher.stats()
+}
+
+// Close sends the pending records and stops the output.
+func (lo *LokiOutput) Close() error {
+	if lo.batcher == nil {
+		return nil
+	}
+
+	return lo.batcher.Close()
+}
+
+// lokiPushURL returns the URL with the push API path added if it has no path.
+func lokiPushURL(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL [%s]: %w", rawURL, err)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = lokiPushPath
+	}
+
+	return u.String(), nil
+}
+
+// lokiLabelName returns the attribute key as a valid label name.
+func lokiLabelName(key string) string {
+	name := lokiInvalidLabelChars.ReplaceAllString(key, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+
+	return name
+}
+
+// lokiEntry is a line of a stream, identified by its canonical label string.
+type lokiEntry struct {
+	stream string
+	labels map[string]string
+	time   time.Time
+	line   string
+}
+
+// lokiWriter splits the label attributes off the entries and queues the remaining line.
+type lokiWriter struct {
+	labels  []string
+	static  map[string]string
+	encode  entryEncoder
+	batcher *batcher[lokiEntry]
+}
+
+func (lw *lokiWriter) writeEntry(e *entry) error {
+	labels := maps.Clone(lw.static)
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	line := *e
+	for _, name := range lw.labels {
+		attrs, value, found := takeAttr(line.Attrs, name)
+		if found {
+			line.Attrs = attrs
+			labels[lokiLabelName(name)] = fmt.Sprint(attrValue(value))
+		} else if name == lokiLevelLabel {
+			labels[lokiLevelLabel] = strings.ToLower(levelName(e.Level))
+		}
+	}
+	// Loki rejects streams without labels.
+	if len(labels) == 0 {
+		labels["service_name"] = filepath.Base(os.Args[0])
+	}
+
+	buf := &bytes.Buffer{}
+	if err := lw.encode(buf, &line); err != nil {
+		return fmt.Errorf("encoding log line: %w", err)
+	}
+
+	return lw.batcher.add(lokiEntry{
+		stream: lokiStream(labels),
+		labels: labels,
+		time:   e.Time,
+		line:   strings.TrimSuffix(buf.String(), "\n"),
+	})
+}
+
+// lokiStream returns the labels in the canonical {name="value", ...} form, sorted by name.
+func lokiStream(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + "=" + strconv.Quote(labels[name])
+	}
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// lokiStreams groups the entries by stream, in the order the streams first appear in the batch.
+func lokiStreams(batch []lokiEntry) [][]lokiEntry {
+	var streams [][]lokiEntry
+	index := map[string]int{}
+	for _, entry := range batch {
+		i, exists := index[entry.stream]
+		if !exists {
+			i = len(streams)
+			index[entry.stream] = i
+			streams = append(streams, nil)
+		}
+		streams[i] = append(streams[i], entry)
+	}
+
+	return streams
+}
+
+// lokiJSON returns the JSON push request of the batch.
+func lokiJSON(batch []lokiEntry) ([]byte, error) {
+	type stream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+	request := struct {
+		Streams []stream `json:"streams"`
+	}{}
+
+	for _, entries := range lokiStreams(batch) {
+		s := stream{Stream: entries[0].labels}
+		for _, entry := range entries {
+			s.Values = append(s.Values, [2]string{strconv.FormatInt(entry.time.UnixNano(), 10), entry.line})
+		}
+		request.Streams = append(request.Streams, s)
+	}
+
+	return json.Marshal(request)
+}
+
+// lokiProtobuf returns the snappy compressed protobuf push request of the batch.
+func lokiProtobuf(batch []lokiEntry) []byte {
+	var request []byte
+	for _, entries := range lokiStreams(batch) {
+		stream := appendProtoString(nil, 1, entries[0].stream)
+		for _, entry := range entries {
+			timestamp := appendProtoInt(nil, 1, entry.time.Unix())
+			timestamp = appendProtoInt(timestamp, 2, int64(entry.time.Nanosecond()))
+			message := appendProtoMessage(nil, 1, timestamp)
+			message = appendProtoString(message, 2, entry.line)
+			stream = appendProtoMessage(stream, 2, message)
+		}
+		request = appendProtoMessage(request, 1, stream)
+	}
+
+	return snappyLiteral(request)
+}
+
+// snappyLiteral returns the data as a snappy block made of uncompressed literals, which every snappy decoder
+// accepts while keeping the output free of a compression dependency.
+func snappyLiteral(data []byte) []byte {
+	const maxLiteral = 1 << 16
+
+	block := binary.AppendUvarint(nil, uint64(len(data)))
+	for len(data) > 0 {
+		n := min(len(data), maxLiteral)
+		switch {
+		case n <= 60:
+			block = append(block, byte(n-1)<<2)
+		case n <= 1<<8:
+			block = append(block, 60<<2, byte(n-1))
+		default:
+			block = append(block, 61<<2, byte(n-1), byte((n-1)>>8))
+		}
+		block = append(block, data[:n]...)
+		data = data[n:]
+	}
+
+	return block
+}
@@ -0,0 +1,333 @@
+package nmcslog
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// protoFields decodes the fields of a protobuf message, the values of varint, fixed64 and fixed32 fields are returned in
+// their wire encoding.
+func protoFields(message []byte) (map[int][][]byte, error) {
+	fields := map[int][][]byte{}
+	for len(message) > 0 {
+		tag, n := binary.Uvarint(message)
+		if n <= 0 {
+			return nil, errors.New("invalid tag")
+		}
+		message = message[n:]
+		field, wireType := int(tag>>3), int(tag&7)
+
+		var value []byte
+		switch wireType {
+		case protoVarint:
+			if _, n = binary.Uvarint(message); n <= 0 {
+				return nil, errors.New("invalid varint")
+			}
+			value, message = message[:n], message[n:]
+		case protoI64:
+			if len(message) < 8 {
+				return nil, errors.New("truncated fixed64")
+			}
+			value, message = message[:8], message[8:]
+		case protoI32:
+			if len(message) < 4 {
+				return nil, errors.New("truncated fixed32")
+			}
+			value, message = message[:4], message[4:]
+		case protoLen:
+			size, n := binary.Uvarint(message)
+			if n <= 0 || len(message) < n+int(size) {
+				return nil, errors.New("truncated length delimited field")
+			}
+			value, message = message[n:n+int(size)], message[n+int(size):]
+		default:
+			return nil, fmt.Errorf("unexpected wire type %d", wireType)
+		}
+		fields[field] = append(fields[field], value)
+	}
+
+	return fields, nil
+}
+
+// protoVarintValue returns the value of a varint field returned by protoFields.
+func protoVarintValue(fields map[int][][]byte, field int) uint64 {
+	if len(fields[field]) == 0 {
+		return 0
+	}
+	v, _ := binary.Uvarint(fields[field][0])
+
+	return v
+}
+
+// snappyDecodeLiterals decodes a snappy block made of literals only, as written by snappyLiteral.
+func snappyDecodeLiterals(block []byte) ([]byte, error) {
+	size, n := binary.Uvarint(block)
+	block = block[n:]
+	var data []byte
+	for len(block) > 0 {
+		tag := block[0]
+		if tag&3 != 0 {
+			return nil, fmt.Errorf("unexpected copy element %x", tag)
+		}
+		length := int(tag>>2) + 1
+		block = block[1:]
+		switch tag >> 2 {
+		case 60:
+			length, block = int(block[0])+1, block[1:]
+		case 61:
+			length, block = int(binary.LittleEndian.Uint16(block))+1, block[2:]
+		}
+		data, block = append(data, block[:length]...), block[length:]
+	}
+	if len(data) != int(size) {
+		return nil, fmt.Errorf("decoded %d bytes, want %d", len(data), size)
+	}
+
+	return data, nil
+}
+
+// lokiPush maps the streams of a push request onto their timestamp and line pairs.
+type lokiPush map[string][][2]string
+
+// fakeLoki decodes the JSON and protobuf push requests.
+type fakeLoki struct {
+	mu      sync.Mutex
+	pushes  []lokiPush
+	tenants []string
+}
+
+func (fl *fakeLoki) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != lokiPushPath {
+		http.NotFound(w, r)
+		return
+	}
+	reader := io.Reader(r.Body)
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		reader = gz
+	}
+	body, _ := io.ReadAll(reader)
+
+	var push lokiPush
+	var err error
+	switch r.Header.Get("Content-Type") {
+	case "application/json":
+		push, err = decodeLokiJSON(body)
+	case "application/x-protobuf":
+		push, err = decodeLokiProtobuf(body)
+	default:
+		err = errors.New("unsupported content type")
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	fl.pushes = append(fl.pushes, push)
+	fl.tenants = append(fl.tenants, r.Header.Get(lokiTenantHeader))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func decodeLokiJSON(body []byte) (lokiPush, error) {
+	request := struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}{}
+	if err := json.Unmarshal(body, &request); err != nil {
+		return nil, err
+	}
+
+	push := lokiPush{}
+	for _, stream := range request.Streams {
+		push[lokiStream(stream.Stream)] = stream.Values
+	}
+
+	return push, nil
+}
+
+func decodeLokiProtobuf(body []byte) (lokiPush, error) {
+	data, err := snappyDecodeLiterals(body)
+	if err != nil {
+		return nil, err
+	}
+	request, err := protoFields(data)
+	if err != nil {
+		return nil, err
+	}
+
+	push := lokiPush{}
+	for _, stream := range request[1] {
+		fields, err := protoFields(stream)
+		if err != nil {
+			return nil, err
+		}
+		labels := string(fields[1][0])
+		for _, entry := range fields[2] {
+			entryFields, err := protoFields(entry)
+			if err != nil {
+				return nil, err
+			}
+			timestamp, err := protoFields(entryFields[1][0])
+			if err != nil {
+				return nil, err
+			}
+			unixNano := protoVarintValue(timestamp, 1)*uint64(time.Second) + protoVarintValue(timestamp, 2)
+			push[labels] = append(push[labels], [2]string{strconv.FormatUint(unixNano, 10), string(entryFields[2][0])})
+		}
+	}
+
+	return push, nil
+}
+
+func TestLokiOutput(t *testing.T) {
+	for _, protobuf := range []bool{false, true} {
+		t.Run(fmt.Sprintf("protobuf=%v", protobuf), func(t *testing.T) {
+			loki := &fakeLoki{}
+			server := httptest.NewServer(loki)
+			defer server.Close()
+
+			config := Config{
+				Console: ConsoleOutput{OutputHandler: OutputHandler{Disable: true}},
+				File:    FileOutput{OutputHandler: OutputHandler{Disable: true}},
+				Loki: LokiOutput{
+					OutputHandler: OutputHandler{
+						Format: FormatJSON,
+						AttributeFuncs: []AttributeFunc{func(_ []string, a slog.Attr) slog.Attr {
+							if a.Key == "secret" {
+								return slog.String(a.Key, "redacted")
+							}
+							return a
+						}},
+					},
+					URL:          server.URL,
+					Labels:       []string{"service", "level", "http.route"},
+					StaticLabels: map[string]string{"env": "test"},
+					TenantID:     "team-a",
+					Protobuf:     protobuf,
+					Gzip:         true,
+					Batch:        BatchOptions{Age: "1h"},
+				},
+			}
+			logger, err := GetConfiguredLogger(&config)
+			if err != nil {
+				t.Fatalf("GetConfiguredLogger() error = %v", err)
+			}
+
+			api := logger.With("service", "api")
+			api.Info("request", slog.Group("http", "route", "/users", "status", 200))
+			api.Error("request", slog.Group("http", "route", "/users", "status", 500), "secret", "hunter2")
+			api.Info("started")
+			if err := config.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			loki.mu.Lock()
+			defer loki.mu.Unlock()
+			if len(loki.pushes) != 1 || loki.tenants[0] != "team-a" {
+				t.Fatalf("pushes = %v, tenants = %v", loki.pushes, loki.tenants)
+			}
+
+			lines := map[string][]map[string]any{}
+			for stream, values := range loki.pushes[0] {
+				for _, value := range values {
+					if _, err := strconv.ParseInt(value[0], 10, 64); err != nil {
+						t.Errorf("invalid timestamp %q", value[0])
+					}
+					line := map[string]any{}
+					if err := json.Unmarshal([]byte(value[1]), &line); err != nil {
+						t.Fatalf("invalid line %q: %v", value[1], err)
+					}
+					delete(line, "time")
+					lines[stream] = append(lines[stream], line)
+				}
+			}
+
+			want := map[string][]map[string]any{
+				`{env="test", http_route="/users", level="info", service="api"}`: {
+					{"level": "INFO", "msg": "request", "http": map[string]any{"status": float64(200)}},
+				},
+				`{env="test", http_route="/users", level="error", service="api"}`: {
+					{"level": "ERROR", "msg": "request", "http": map[string]any{"status": float64(500)}, "secret": "redacted"},
+				},
+				`{env="test", level="info", service="api"}`: {
+					{"level": "INFO", "msg": "started"},
+				},
+			}
+			if !reflect.DeepEqual(lines, want) {
+				t.Errorf("streams = %v, want %v", lines, want)
+			}
+			if stats := config.Loki.Stats(); stats.Records != 3 || stats.Batches != 1 {
+				t.Errorf("Stats() = %+v", stats)
+			}
+		})
+	}
+}
+
+func TestLokiOutput_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  LokiOutput
+		wantErr bool
+	}{
+		{name: "disabled without URL", output: LokiOutput{StaticLabels: map[string]string{"in-valid": ""}}},
+		{name: "defaults", output: LokiOutput{URL: "http://loki:3100"}},
+		{name: "invalid static label", output: LokiOutput{URL: "http://loki:3100", StaticLabels: map[string]string{"in-valid": ""}}, wantErr: true},
+		{name: "binary format", output: LokiOutput{URL: "http://loki:3100", OutputHandler: OutputHandler{Format: FormatMsgPack}}, wantErr: true},
+		{name: "invalid URL", output: LokiOutput{URL: "loki:3100"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.output.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTakeAttr(t *testing.T) {
+	attrs := []slog.Attr{
+		slog.String("service.name", "api"),
+		slog.Group("http", slog.String("route", "/"), slog.Int("status", 200)),
+		slog.Group("db", slog.String("name", "users")),
+	}
+
+	rest, value, found := takeAttr(attrs, "service.name")
+	if !found || value.String() != "api" || len(rest) != 2 {
+		t.Errorf("takeAttr(service.name) = %v, %v, %v", rest, value, found)
+	}
+	rest, value, found = takeAttr(attrs, "http.status")
+	if !found || value.Int64() != 200 || rest[1].String() != "http=[route=/]" {
+		t.Errorf("takeAttr(http.status) = %v, %v, %v", rest, value, found)
+	}
+	rest, _, found = takeAttr(attrs, "db.name")
+	if !found || len(rest) != 2 {
+		t.Errorf("takeAttr(db.name) should remove the empty group: %v", rest)
+	}
+	if _, _, found = takeAttr(attrs, "http"); found {
+		t.Error("takeAttr(http) should not take a group")
+	}
+	if attrs[1].Value.Group()[1].Key != "status" {
+		t.Error("takeAttr() modified the attributes")
+	}
+}
@@ -0,0 +1,205 @@
+package nmcslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// MemoryOutput defines the settings specific to the memory output, keeping the most recent records in a ring
+// buffer so they can be queried with Query or served as JSON, such as on an admin page. Its Level defaults to DEBUG,
+// independently of the other outputs.
+type MemoryOutput struct {
+	OutputHandler
+	// MaxRecords is the max number of records kept, the oldest records are evicted first.
+	MaxRecords int `json:",omitempty" jsonschema:"title=Max Records,example=1000"`
+	// MaxBytes is the max total size of the records kept, as their JSON encoding. If neither MaxRecords nor
+	// MaxBytes are provided, memory logging will be disabled.
+	MaxBytes int `json:",omitempty" jsonschema:"title=Max Bytes,example=1048576"`
+	buffer   *memoryBuffer
+}
+
+func (mo *MemoryOutput) enabled() bool {
+	return !mo.Disable && (mo.MaxRecords > 0 || mo.MaxBytes > 0)
+}
+
+func (mo *MemoryOutput) Validate() (err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: validate config [memory output]: %w", err)
+		}
+	}()
+
+	if !mo.enabled() {
+		return nil
+	}
+	if mo.MaxRecords < 0 {
+		return fmt.Errorf("invalid MaxRecords [%d]", mo.MaxRecords)
+	}
+	if mo.MaxBytes < 0 {
+		return fmt.Errorf("invalid MaxBytes [%d]", mo.MaxBytes)
+	}
+	if mo.Level == "" {
+		mo.Level = "DEBUG"
+	}
+	if mo.Format == "" {
+		mo.Format = FormatJSON
+	}
+	if err = mo.OutputHandler.Validate(); err != nil {
+		return err
+	}
+	if mo.Format != FormatJSON {
+		return fmt.Errorf("invalid format [%s], only %s is supported", mo.Format, FormatJSON)
+	}
+
+	return nil
+}
+
+func (mo *MemoryOutput) GetHandler() (handler slog.Handler, err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: get handler [memory output]: %w", err)
+		}
+	}()
+
+	if !mo.enabled() {
+		return nil, fmt.Errorf("[memory] %w", ErrHandlerDisabled)
+	}
+
+	if mo.buffer == nil {
+		mo.buffer = &memoryBuffer{maxRecords: mo.MaxRecords, maxBytes: mo.MaxBytes}
+	}
+
+	handler, err = mo.entryHandler(mo.buffer)
+	if err != nil {
+		return nil, fmt.Errorf("getting handler [memory]: %w", err)
+	}
+
+	return handler, nil
+}
+
+// MemoryRecord is a record kept by the memory output.
+type MemoryRecord = LogEntry
+
+// MemoryFilter selects the records returned by Query, the zero value matches all the records.
+type MemoryFilter = RecordFilter
+
+// Query returns the kept records matching the filter, oldest first.
+func (mo *MemoryOutput) Query(filter MemoryFilter) []MemoryRecord {
+	if mo.buffer == nil {
+		return nil
+	}
+
+	return mo.buffer.query(&filter)
+}
+
+// MemoryStats are the statistics of the memory output.
+type MemoryStats struct {
+	// Records is the number of records kept.
+	Records int
+	// Bytes is the total size of the records kept.
+	Bytes int
+	// EvictedRecords is the number of records evicted to make room for newer ones.
+	EvictedRecords uint64
+}
+
+// Stats returns the statistics of the output.
+func (mo *MemoryOutput) Stats() MemoryStats {
+	if mo.buffer == nil {
+		return MemoryStats{}
+	}
+
+	mo.buffer.mu.RLock()
+	defer mo.buffer.mu.RUnlock()
+
+	return MemoryStats{
+		Records:        len(mo.buffer.items),
+		Bytes:          mo.buffer.bytes,
+		EvictedRecords: mo.buffer.evicted,
+	}
+}
+
+// ServeHTTP returns the records matching the query parameters as a JSON array, oldest first. The parameters are
+// level, since and until as RFC 3339 times, message, attr as key=value which can be repeated, and limit.
+func (mo *MemoryOutput) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, err := recordFilterFromQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	records := mo.Query(filter)
+	if records == nil {
+		records = []LogEntry{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(records)
+}
+
+// memoryItem is a kept record along with its size.
+type memoryItem struct {
+	record LogEntry
+	size   int
+}
+
+// memoryBuffer keeps the most recent records within the max number of records and bytes.
+type memoryBuffer struct {
+	maxRecords int
+	maxBytes   int
+
+	mu      sync.RWMutex
+	items   []memoryItem
+	bytes   int
+	evicted uint64
+}
+
+func (mb *memoryBuffer) writeEntry(e *entry) error {
+	record := newLogEntry(e)
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("encoding record: %w", err)
+	}
+
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	mb.items = append(mb.items, memoryItem{record: record, size: len(encoded)})
+	mb.bytes += len(encoded)
+	for len(mb.items) > 0 &&
+		(mb.maxRecords > 0 && len(mb.items) > mb.maxRecords || mb.maxBytes > 0 && mb.bytes > mb.maxBytes) {
+		mb.bytes -= mb.items[0].size
+		// Clear the evicted item so it is not retained by the backing array.
+		mb.items[0] = memoryItem{}
+		mb.items = mb.items[1:]
+		mb.evicted++
+	}
+
+	return nil
+}
+
+func (mb *memoryBuffer) query(filter *RecordFilter) []LogEntry {
+	mb.mu.RLock()
+	defer mb.mu.RUnlock()
+
+	var records []LogEntry
+	for i := len(mb.items) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(records) == filter.Limit {
+			break
+		}
+		if filter.match(&mb.items[i].record) {
+			records = append(records, mb.items[i].record)
+		}
+	}
+	slices.Reverse(records)
+
+	return records
+}
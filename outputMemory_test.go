@@ -0,0 +1,188 @@
+package nmcslog
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryOutput(t *testing.T) {
+	config := Config{
+		Console: ConsoleOutput{OutputHandler: OutputHandler{LogLevel: LogLevel{Level: "ERROR"}, Format: FormatText}},
+		File:    FileOutput{OutputHandler: OutputHandler{Disable: true}},
+		Memory:  MemoryOutput{MaxRecords: 3},
+	}
+	logger, err := GetConfiguredLogger(&config)
+	if err != nil {
+		t.Fatalf("GetConfiguredLogger() error = %v", err)
+	}
+
+	logger.Debug("evicted")
+	logger.Debug("cache miss", "key", "user:7")
+	logger.Info("request", "path", "/orders", slog.Group("user", "id", 7))
+	logger.Warn("slow query", "ms", 250)
+
+	records := config.Memory.Query(MemoryFilter{})
+	if len(records) != 3 || records[0].Message != "cache miss" || records[2].Message != "slow query" {
+		t.Fatalf("Query() = %+v, want the 3 most recent records including DEBUG", records)
+	}
+	if stats := config.Memory.Stats(); stats.Records != 3 || stats.EvictedRecords != 1 || stats.Bytes == 0 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+	if err = config.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+}
+
+func TestMemoryOutput_MaxBytes(t *testing.T) {
+	output := MemoryOutput{MaxBytes: 300}
+	if err := output.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	handler, err := output.GetHandler()
+	if err != nil {
+		t.Fatalf("GetHandler() error = %v", err)
+	}
+	logger := slog.New(handler)
+
+	for range 10 {
+		logger.Info("a record of about sixty bytes once encoded")
+	}
+	stats := output.Stats()
+	if stats.Bytes > 300 || stats.Records == 0 || stats.Records+int(stats.EvictedRecords) != 10 {
+		t.Errorf("Stats() = %+v, want at most 300 bytes", stats)
+	}
+
+	logger.Info(strings.Repeat("x", 400))
+	if stats = output.Stats(); stats.Records != 0 || stats.Bytes != 0 {
+		t.Errorf("Stats() = %+v, want the oversized record evicted", stats)
+	}
+}
+
+func TestMemoryOutput_Query(t *testing.T) {
+	output := MemoryOutput{MaxRecords: 100}
+	if err := output.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	handler, err := output.GetHandler()
+	if err != nil {
+		t.Fatalf("GetHandler() error = %v", err)
+	}
+	logger := slog.New(handler)
+
+	logger.Debug("cache miss", "key", "user:7")
+	logger.Info("request served", "path", "/orders", slog.Group("user", "id", 7))
+	middle := time.Now()
+	time.Sleep(time.Millisecond)
+	logger.Warn("slow request", "path", "/orders", "ms", 250)
+	logger.Error("request failed", "path", "/cart", slog.Group("user", "id", 8))
+
+	tests := []struct {
+		name   string
+		filter MemoryFilter
+		want   []string
+	}{
+		{name: "all", want: []string{"cache miss", "request served", "slow request", "request failed"}},
+		{name: "level", filter: MemoryFilter{Level: slog.LevelWarn}, want: []string{"slow request", "request failed"}},
+		{name: "since", filter: MemoryFilter{Since: middle}, want: []string{"slow request", "request failed"}},
+		{name: "until", filter: MemoryFilter{Until: middle}, want: []string{"cache miss", "request served"}},
+		{name: "message", filter: MemoryFilter{Message: "request"}, want: []string{"request served", "slow request", "request failed"}},
+		{name: "attr", filter: MemoryFilter{Attrs: map[string]string{"path": "/orders"}}, want: []string{"request served", "slow request"}},
+		{name: "grouped attr", filter: MemoryFilter{Attrs: map[string]string{"user.id": "8"}}, want: []string{"request failed"}},
+		{name: "limit", filter: MemoryFilter{Message: "request", Limit: 2}, want: []string{"slow request", "request failed"}},
+		{name: "no match", filter: MemoryFilter{Attrs: map[string]string{"missing": ""}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, record := range output.Query(tt.filter) {
+				got = append(got, record.Message)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Query() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryOutput_ServeHTTP(t *testing.T) {
+	output := MemoryOutput{MaxRecords: 100}
+	if err := output.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	handler, err := output.GetHandler()
+	if err != nil {
+		t.Fatalf("GetHandler() error = %v", err)
+	}
+	logger := slog.New(handler)
+	logger.Info("request served", "path", "/orders")
+	logger.Warn("slow request", "path", "/orders", slog.Group("user", "id", 7))
+	logger.Warn("slow request", "path", "/cart")
+
+	query := url.Values{"level": {"warn"}, "attr": {"path=/orders"}, "limit": {"5"}}
+	response := httptest.NewRecorder()
+	output.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/logs?"+query.Encode(), nil))
+	if response.Code != http.StatusOK || response.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("response = %d %v", response.Code, response.Header())
+	}
+	var records []map[string]any
+	if err = json.Unmarshal(response.Body.Bytes(), &records); err != nil {
+		t.Fatalf("body = %q: %v", response.Body, err)
+	}
+	if len(records) != 1 || records[0]["level"] != "WARN" || records[0]["msg"] != "slow request" ||
+		records[0]["attrs"].(map[string]any)["user"].(map[string]any)["id"] != float64(7) {
+		t.Errorf("records = %v", records)
+	}
+
+	for _, target := range []string{"/logs?level=loud", "/logs?since=yesterday", "/logs?attr=path", "/logs?limit=-1"} {
+		response = httptest.NewRecorder()
+		output.ServeHTTP(response, httptest.NewRequest(http.MethodGet, target, nil))
+		if response.Code != http.StatusBadRequest {
+			t.Errorf("GET %s = %d, want %d", target, response.Code, http.StatusBadRequest)
+		}
+	}
+
+	response = httptest.NewRecorder()
+	output.ServeHTTP(response, httptest.NewRequest(http.MethodDelete, "/logs", nil))
+	if response.Code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE = %d, want %d", response.Code, http.StatusMethodNotAllowed)
+	}
+
+	empty := MemoryOutput{}
+	response = httptest.NewRecorder()
+	empty.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/logs", nil))
+	if body := strings.TrimSpace(response.Body.String()); body != "[]" {
+		t.Errorf("body = %q, want an empty array", body)
+	}
+}
+
+func TestMemoryOutput_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  MemoryOutput
+		wantErr bool
+	}{
+		{name: "disabled without limits"},
+		{name: "max records", output: MemoryOutput{MaxRecords: 1000}},
+		{name: "max bytes", output: MemoryOutput{MaxBytes: 1 << 20}},
+		{name: "negative max bytes", output: MemoryOutput{MaxRecords: 1000, MaxBytes: -1}, wantErr: true},
+		{name: "text format", output: MemoryOutput{MaxRecords: 1000, OutputHandler: OutputHandler{Format: FormatText}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.output.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && tt.output.enabled() && tt.output.Level != "DEBUG" {
+				t.Errorf("Level = %q, want DEBUG by default", tt.output.Level)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package nmcslog
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"time"
+)
+
+const (
+	// DefaultNetworkTimeout is the default timeout of each write to the network output connection.
+	DefaultNetworkTimeout = 10 * time.Second
+)
+
+// TLSOptions defines the TLS settings of a network connection.
+type TLSOptions struct {
+	// Enable connects with TLS.
+	Enable bool `json:",omitempty" jsonschema:"title=Enable TLS,example=true,default=false"`
+	// CAFile is the PEM file of the certificate authorities verifying the server, defaults to the system pool.
+	CAFile string `json:",omitempty" jsonschema:"title=CA File,example=/etc/ssl/certs/relay-ca.pem"`
+	// ServerName verified against the server certificate, defaults to the host of the address.
+	ServerName string `json:",omitempty" jsonschema:"title=Server Name,example=relay.example.com"`
+	// InsecureSkipVerify disables the verification of the server certificate.
+	InsecureSkipVerify bool `json:",omitempty" jsonschema:"title=Insecure Skip Verify,default=false"`
+}
+
+// config returns the tls.Config of the options.
+func (to *TLSOptions) config() (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         to.ServerName,
+		InsecureSkipVerify: to.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if to.CAFile != "" {
+		pem, err := os.ReadFile(to.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA file: %w", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in CA file [%s]", to.CAFile)
+		}
+	}
+
+	return config, nil
+}
+
+// NetworkOutput defines the settings specific to the network output, writing the newline delimited records in the
+// configured format to a tcp, udp or unix socket, such as a log relay.
+type NetworkOutput struct {
+	OutputHandler
+	// Address of the relay as tcp://host:port, udp://host:port or unix:///path. If not provided, network logging will be disabled.
+	Address string `json:",omitempty" jsonschema:"title=Network Address,example=tcp://relay.example.com:5170,example=unix:///var/run/relay.sock"`
+	// TLS of the tcp connections.
+	TLS TLSOptions `json:",omitempty"`
+	// BufferSize is the max number of records buffered while the connection is down.
+	BufferSize int `json:",omitempty" jsonschema:"title=Buffer Size,example=50000,default=10000"`
+	// Overflow is the policy once the buffer is full, either DROP_NEWEST (default), DROP_OLDEST or BLOCK.
+	Overflow string `json:",omitempty" jsonschema:"title=Overflow Policy,enum=DROP_NEWEST,enum=DROP_OLDEST,enum=BLOCK,default=DROP_NEWEST"`
+	// InitialBackoff is the delay before the first reconnect, doubling for each following attempt with a random jitter.
+	InitialBackoff string `json:",omitempty" jsonschema:"title=Initial Backoff,example=1s,default=500ms"`
+	// MaxBackoff is the max delay between reconnects.
+	MaxBackoff string `json:",omitempty" jsonschema:"title=Max Backoff,example=1m,default=30s"`
+	// Timeout of each write as a Go duration.
+	Timeout string `json:",omitempty" jsonschema:"title=Timeout,example=30s,default=10s"`
+	writer  *streamWriter
+}
+
+func (no *NetworkOutput) enabled() bool {
+	return !no.Disable && no.Address != ""
+}
+
+func (no *NetworkOutput) Validate() (err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: validate config [network output]: %w", err)
+		}
+	}()
+
+	if !no.enabled() {
+		return nil
+	}
+	if no.Format == "" {
+		no.Format = FormatJSON
+	}
+	if err = no.OutputHandler.Validate(); err != nil {
+		return err
+	}
+	network, _, err := parseNetworkAddress(no.Address)
+	if err != nil {
+		return err
+	}
+	if no.TLS.Enable && network != "tcp" {
+		return fmt.Errorf("TLS is only supported over tcp, not [%s]", network)
+	}
+	if no.BufferSize < 0 {
+		return fmt.Errorf("invalid BufferSize [%d]", no.BufferSize)
+	}
+	if err = validateOverflow(no.Overflow); err != nil {
+		return err
+	}
+	for name, value := range map[string]string{
+		"InitialBackoff": no.InitialBackoff,
+		"MaxBackoff":     no.MaxBackoff,
+		"Timeout":        no.Timeout,
+	} {
+		if _, err = parseDuration(value, 0); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// parseNetworkAddress splits the address into the network and the host:port or socket path.
+func parseNetworkAddress(address string) (network, target string, err error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid address [%s]: %w", address, err)
+	}
+
+	switch u.Scheme {
+	case "tcp", "udp":
+		if u.Host == "" || u.Port() == "" {
+			return "", "", fmt.Errorf("invalid address [%s]: missing host:port", address)
+		}
+		return u.Scheme, u.Host, nil
+	case "unix":
+		if u.Path == "" {
+			return "", "", fmt.Errorf("invalid address [%s]: missing socket path", address)
+		}
+		return u.Scheme, u.Path, nil
+	default:
+		return "", "", fmt.Errorf("invalid address [%s]: must be tcp://, udp:// or unix://", address)
+	}
+}
+
+func (no *NetworkOutput) GetHandler() (handler slog.Handler, err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: get handler [network output]: %w", err)
+		}
+	}()
+
+	if !no.enabled() {
+		return nil, fmt.Errorf("[%s] %w", no.Address, ErrHandlerDisabled)
+	}
+
+	if no.writer == nil {
+		network, target, err := parseNetworkAddress(no.Address)
+		if err != nil {
+			return nil, err
+		}
+		settings, err := streamSettings(no.BufferSize, no.InitialBackoff, no.MaxBackoff)
+		if err != nil {
+			return nil, err
+		}
+		timeout, err := parseDuration(no.Timeout, DefaultNetworkTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		var tlsConfig *tls.Config
+		if no.TLS.Enable {
+			if tlsConfig, err = no.TLS.config(); err != nil {
+				return nil, err
+			}
+			if tlsConfig.ServerName == "" {
+				tlsConfig.ServerName, _, _ = net.SplitHostPort(target)
+			}
+		}
+
+		no.writer = newStreamWriter(no.Address, func() (io.WriteCloser, error) {
+			dialer := &net.Dialer{Timeout: DefaultDialTimeout}
+			if tlsConfig != nil {
+				return tls.DialWithDialer(dialer, network, target, tlsConfig)
+			}
+			return dialer.Dial(network, target)
+		}, settings, no.Overflow, timeout)
+	}
+
+	handler, err = no.OutputHandler.GetHandler(no.writer)
+	if err != nil {
+		return nil, fmt.Errorf("getting handler [network]: %w", err)
+	}
+
+	return handler, nil
+}
+
+// NetworkStats are the delivery statistics of the network output.
+type NetworkStats struct {
+	// Records is the number of records written to the connection.
+	Records uint64
+	// Reconnects is the number of failed connection attempts and writes, each followed by a reconnect.
+	Reconnects uint64
+	// DroppedRecords is the number of records dropped by the overflow policy or still buffered when closed.
+	DroppedRecords uint64
+}
+
+// Stats returns the delivery statistics of the output.
+func (no *NetworkOutput) Stats() NetworkStats {
+	if no.writer == nil {
+		return NetworkStats{}
+	}
+
+	return NetworkStats{
+		Records:        no.writer.records.Load(),
+		Reconnects:     no.writer.reopened.Load(),
+		DroppedRecords: no.writer.dropped.Load(),
+	}
+}
+
+// Close sends the buffered records if connected and closes the connection.
+func (no *NetworkOutput) Close() error {
+	if no.writer == nil {
+		return nil
+	}
+
+	return no.writer.Close()
+}
@@ -0,0 +1,216 @@
+package nmcslog
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRelay is a stream relay stand-in collecting the received lines.
+type fakeRelay struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (fr *fakeRelay) serve(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			scanner := bufio.NewScanner(conn)
+			for scanner.Scan() {
+				fr.mu.Lock()
+				fr.lines = append(fr.lines, scanner.Text())
+				fr.mu.Unlock()
+			}
+		}()
+	}
+}
+
+func (fr *fakeRelay) received() []string {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	return append([]string(nil), fr.lines...)
+}
+
+// waitFor polls the condition until it is met or the deadline is reached.
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before the deadline")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNetworkOutput_Reconnect(t *testing.T) {
+	// Reserve an address, the relay only starts listening once the output failed to connect.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	address := listener.Addr().String()
+	_ = listener.Close()
+
+	config := Config{
+		Console: ConsoleOutput{OutputHandler: OutputHandler{Disable: true}},
+		File:    FileOutput{OutputHandler: OutputHandler{Disable: true}},
+		Network: NetworkOutput{
+			OutputHandler:  OutputHandler{Format: FormatJSON},
+			Address:        "tcp://" + address,
+			InitialBackoff: "10ms",
+			MaxBackoff:     "50ms",
+		},
+	}
+	logger, err := GetConfiguredLogger(&config)
+	if err != nil {
+		t.Fatalf("GetConfiguredLogger() error = %v", err)
+	}
+
+	for i := range 3 {
+		logger.Info("relayed", "n", i)
+	}
+	waitFor(t, func() bool { return config.Network.Stats().Reconnects > 0 })
+
+	relay := &fakeRelay{}
+	if listener, err = net.Listen("tcp", address); err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer listener.Close()
+	go relay.serve(listener)
+
+	waitFor(t, func() bool { return config.Network.Stats().Records == 3 })
+	if err = config.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	waitFor(t, func() bool { return len(relay.received()) == 3 })
+
+	for i, line := range relay.received() {
+		record := map[string]any{}
+		if err = json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("line %d = %q: %v", i, line, err)
+		}
+		if record["msg"] != "relayed" || record["n"] != float64(i) {
+			t.Errorf("line %d = %v, want the records in order", i, record)
+		}
+	}
+	if stats := config.Network.Stats(); stats.DroppedRecords != 0 {
+		t.Errorf("Stats() = %+v, want no dropped records", stats)
+	}
+}
+
+func TestNetworkOutput_Unix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "relay.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer listener.Close()
+	relay := &fakeRelay{}
+	go relay.serve(listener)
+
+	config := Config{
+		Console: ConsoleOutput{OutputHandler: OutputHandler{Disable: true}},
+		File:    FileOutput{OutputHandler: OutputHandler{Disable: true}},
+		Network: NetworkOutput{
+			OutputHandler: OutputHandler{Format: FormatText},
+			Address:       "unix://" + path,
+		},
+	}
+	logger, err := GetConfiguredLogger(&config)
+	if err != nil {
+		t.Fatalf("GetConfiguredLogger() error = %v", err)
+	}
+
+	logger.Warn("over a unix socket", "user", "alice")
+	if err = config.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	waitFor(t, func() bool { return len(relay.received()) == 1 })
+
+	if line := relay.received()[0]; !strings.Contains(line, "over a unix socket") || !strings.Contains(line, "user=alice") {
+		t.Errorf("line = %q, want a text record", line)
+	}
+}
+
+func TestNetworkOutput_UDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() error = %v", err)
+	}
+	defer conn.Close()
+
+	config := Config{
+		Console: ConsoleOutput{OutputHandler: OutputHandler{Disable: true}},
+		File:    FileOutput{OutputHandler: OutputHandler{Disable: true}},
+		Network: NetworkOutput{
+			OutputHandler: OutputHandler{Format: FormatJSON},
+			Address:       "udp://" + conn.LocalAddr().String(),
+		},
+	}
+	logger, err := GetConfiguredLogger(&config)
+	if err != nil {
+		t.Fatalf("GetConfiguredLogger() error = %v", err)
+	}
+
+	logger.Info("first")
+	logger.Info("second")
+	if err = config.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 65536)
+	for _, want := range []string{"first", "second"} {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("ReadFrom() error = %v", err)
+		}
+		record := map[string]any{}
+		if err = json.Unmarshal(buf[:n], &record); err != nil || record["msg"] != want {
+			t.Errorf("datagram = %q, want a single %q record", buf[:n], want)
+		}
+	}
+}
+
+func TestNetworkOutput_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  NetworkOutput
+		wantErr bool
+	}{
+		{name: "disabled without address", output: NetworkOutput{Overflow: "NEVER"}},
+		{name: "tcp", output: NetworkOutput{Address: "tcp://relay:5170"}},
+		{name: "udp msgpack", output: NetworkOutput{Address: "udp://relay:5170", OutputHandler: OutputHandler{Format: FormatMsgPack}}},
+		{name: "unix", output: NetworkOutput{Address: "unix:///var/run/relay.sock", Overflow: "block"}},
+		{name: "tls", output: NetworkOutput{Address: "tcp://relay:5170", TLS: TLSOptions{Enable: true}}},
+		{name: "tls over udp", output: NetworkOutput{Address: "udp://relay:5170", TLS: TLSOptions{Enable: true}}, wantErr: true},
+		{name: "missing port", output: NetworkOutput{Address: "tcp://relay"}, wantErr: true},
+		{name: "missing path", output: NetworkOutput{Address: "unix://"}, wantErr: true},
+		{name: "unknown scheme", output: NetworkOutput{Address: "http://relay:5170"}, wantErr: true},
+		{name: "invalid overflow", output: NetworkOutput{Address: "tcp://relay:5170", Overflow: "NEVER"}, wantErr: true},
+		{name: "zero backoff", output: NetworkOutput{Address: "tcp://relay:5170", InitialBackoff: "0s"}, wantErr: true},
+		{name: "negative buffer", output: NetworkOutput{Address: "tcp://relay:5170", BufferSize: -1}, wantErr: true},
+		{name: "invalid backoff", output: NetworkOutput{Address: "tcp://relay:5170", MaxBackoff: "later"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.output.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
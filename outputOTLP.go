@@ -0,0 +1,259 @@
+package nmcslog
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// otlpLogsPath is the path of the OTLP/HTTP logs endpoint, added to an OTLP URL without a path.
+const otlpLogsPath = "/v1/logs"
+
+// OTLPOutput defines the settings specific to the OTLP/HTTP logs exporter, sending the records to an
+// OpenTelemetry collector. The records are always encoded as OTLP, so only the OTEL format is supported.
+type OTLPOutput struct {
+	OutputHandler
+	// URL of the collector, the logs path /v1/logs is added if the URL has no path.
+	// If not provided, OTLP logging will be disabled.
+	URL string `json:",omitempty" jsonschema:"title=OTLP URL,example=http://otel-collector:4318"`
+	// Headers added to each request, such as Authorization.
+	Headers map[string]string `json:",omitempty" jsonschema:"title=Headers"`
+	// Protobuf exports binary protobuf requests rather than JSON.
+	Protobuf bool `json:",omitempty" jsonschema:"title=Protobuf,example=true,default=false"`
+	// Gzip compresses the request bodies.
+	Gzip bool `json:",omitempty" jsonschema:"title=Gzip,example=true,default=false"`
+	// Timeout of each request as a Go duration.
+	Timeout string `json:",omitempty" jsonschema:"title=Timeout,example=30s,default=10s"`
+	// Batch defines how the records are grouped and retried.
+	Batch   BatchOptions `json:",omitempty"`
+	batcher *batcher[otlpLogRecord]
+}
+
+func (oo *OTLPOutput) enabled() bool {
+	return !oo.Disable && oo.URL != ""
+}
+
+func (oo *OTLPOutput) Validate() (err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: validate config [otlp output]: %w", err)
+		}
+	}()
+
+	if !oo.enabled() {
+		return nil
+	}
+	if oo.Format == "" {
+		oo.Format = FormatOTEL
+	}
+	if err = oo.OutputHandler.Validate(); err != nil {
+		return err
+	}
+	if oo.Format != FormatOTEL {
+		return fmt.Errorf("invalid format [%s], only %s is supported", oo.Format, FormatOTEL)
+	}
+	if err = validateHTTPURL(oo.URL); err != nil {
+		return err
+	}
+	if _, err = parseDuration(oo.Timeout, 0); err != nil {
+		return fmt.Errorf("invalid Timeout: %w", err)
+	}
+
+	return oo.Batch.Validate()
+}
+
+func (oo *OTLPOutput) GetHandler() (handler slog.Handler, err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: get handler [otlp output]: %w", err)
+		}
+	}()
+
+	if !oo.enabled() {
+		return nil, fmt.Errorf("[%s] %w", oo.URL, ErrHandlerDisabled)
+	}
+
+	if oo.batcher == nil {
+		settings, err := oo.Batch.settings()
+		if err != nil {
+			return nil, err
+		}
+		timeout, err := parseDuration(oo.Timeout, DefaultHTTPTimeout)
+		if err != nil {
+			return nil, err
+		}
+		logsURL, err := url.Parse(oo.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URL [%s]: %w", oo.URL, err)
+		}
+		if logsURL.Path == "" || logsURL.Path == "/" {
+			logsURL.Path = otlpLogsPath
+		}
+
+		resource := otelResource(oo.resource())
+		client := &http.Client{Timeout: timeout}
+		oo.batcher = newBatcher(settings, func(batch []otlpLogRecord) error {
+			data := otelLogsData(resource, batch...)
+			if oo.Protobuf {
+				return sendHTTP(client, http.MethodPost, logsURL.String(), "application/x-protobuf", oo.Headers, otlpProtobuf(data), oo.Gzip)
+			}
+			body, err := json.Marshal(data)
+			if err != nil {
+				return &batchError{err: err, permanent: true}
+			}
+			return sendHTTP(client, http.MethodPost, logsURL.String(), "application/json", oo.Headers, body, oo.Gzip)
+		})
+	}
+
+	handler, err = oo.entryHandler(otlpWriter{oo.batcher})
+	if err != nil {
+		return nil, fmt.Errorf("getting handler [otlp]: %w", err)
+	}
+
+	return handler, nil
+}
+
+// resource returns the resource attributes with service.name and host.name defaulting to the executable name and
+// the hostname, as the collectors and backends group the logs by them.
+func (oo *OTLPOutput) resource() map[string]string {
+	resource := maps.Clone(oo.Resource)
+	if resource == nil {
+		resource = map[string]string{}
+	}
+	if resource["service.name"] == "" {
+		resource["service.name"] = filepath.Base(os.Args[0])
+	}
+	if resource["host.name"] == "" {
+		if hostname, err := os.Hostname(); err == nil {
+			resource["host.name"] = hostname
+		}
+	}
+
+	return resource
+}
+
+// Stats returns the delivery statistics of the output.
+func (oo *OTLPOutput) Stats() BatchStats {
+	if oo.batcher == nil {
+		return BatchStats{}
+	}
+
+	return oo.batcher.stats()
+}
+
+// Close sends the pending records and stops the output.
+func (oo *OTLPOutput) Close() error {
+	if oo.batcher == nil {
+		return nil
+	}
+
+	return oo.batcher.Close()
+}
+
+// otlpWriter converts the entries into OTLP log records, the resource is added once per batch.
+type otlpWriter struct {
+	batcher *batcher[otlpLogRecord]
+}
+
+func (ow otlpWriter) writeEntry(e *entry) error {
+	return ow.batcher.add(otelLogRecord(e))
+}
+
+// otlpProtobuf returns the logs data as a protobuf ExportLogsServiceRequest.
+// https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/logs/v1/logs.proto
+func otlpProtobuf(data otlpLogsData) []byte {
+	var request []byte
+	for _, rl := range data.ResourceLogs {
+		var resource []byte
+		for _, kv := range rl.Resource.Attributes {
+			resource = appendProtoMessage(resource, 1, otlpProtoKeyValue(kv))
+		}
+		resourceLogs := appendProtoMessage(nil, 1, resource)
+
+		for _, sl := range rl.ScopeLogs {
+			scopeLogs := appendProtoMessage(nil, 1, appendProtoString(nil, 1, sl.Scope.Name))
+			for _, r := range sl.LogRecords {
+				scopeLogs = appendProtoMessage(scopeLogs, 2, otlpProtoLogRecord(r))
+			}
+			resourceLogs = appendProtoMessage(resourceLogs, 2, scopeLogs)
+		}
+		request = appendProtoMessage(request, 1, resourceLogs)
+	}
+
+	return request
+}
+
+func otlpProtoLogRecord(r otlpLogRecord) []byte {
+	timestamp, _ := strconv.ParseUint(r.TimeUnixNano, 10, 64)
+	observed, _ := strconv.ParseUint(r.ObservedTimeUnixNano, 10, 64)
+	traceID, _ := hex.DecodeString(r.TraceID)
+	spanID, _ := hex.DecodeString(r.SpanID)
+
+	b := appendProtoFixed64(nil, 1, timestamp)
+	b = appendProtoInt(b, 2, int64(r.SeverityNumber))
+	b = appendProtoString(b, 3, r.SeverityText)
+	b = appendProtoMessage(b, 5, otlpProtoAnyValue(r.Body))
+	for _, kv := range r.Attributes {
+		b = appendProtoMessage(b, 6, otlpProtoKeyValue(kv))
+	}
+	b = appendProtoFixed32(b, 8, r.Flags)
+	b = appendProtoBytes(b, 9, traceID)
+	b = appendProtoBytes(b, 10, spanID)
+
+	return appendProtoFixed64(b, 11, observed)
+}
+
+func otlpProtoKeyValue(kv otlpKeyValue) []byte {
+	b := appendProtoString(nil, 1, kv.Key)
+
+	return appendProtoMessage(b, 2, otlpProtoAnyValue(kv.Value))
+}
+
+// otlpProtoAnyValue encodes the oneof of the AnyValue, its field is written even for a zero value as the field
+// number selects the type.
+func otlpProtoAnyValue(v otlpAnyValue) []byte {
+	switch {
+	case v.StringValue != nil:
+		return appendProtoMessage(nil, 1, []byte(*v.StringValue))
+	case v.BoolValue != nil:
+		b := appendProtoTag(nil, 2, protoVarint)
+		if *v.BoolValue {
+			return append(b, 1)
+		}
+		return append(b, 0)
+	case v.IntValue != nil:
+		n, err := strconv.ParseInt(*v.IntValue, 10, 64)
+		if err != nil {
+			u, _ := strconv.ParseUint(*v.IntValue, 10, 64)
+			n = int64(u)
+		}
+		return binary.AppendUvarint(appendProtoTag(nil, 3, protoVarint), uint64(n))
+	case v.DoubleValue != nil:
+		return binary.LittleEndian.AppendUint64(appendProtoTag(nil, 4, protoI64), math.Float64bits(*v.DoubleValue))
+	case v.ArrayValue != nil:
+		var array []byte
+		for _, value := range v.ArrayValue.Values {
+			array = appendProtoMessage(array, 1, otlpProtoAnyValue(value))
+		}
+		return appendProtoMessage(nil, 5, array)
+	case v.KvlistValue != nil:
+		var list []byte
+		for _, kv := range v.KvlistValue.Values {
+			list = appendProtoMessage(list, 1, otlpProtoKeyValue(kv))
+		}
+		return appendProtoMessage(nil, 6, list)
+	case v.BytesValue != nil:
+		return appendProtoMessage(nil, 7, v.BytesValue)
+	}
+
+	return nil
+}
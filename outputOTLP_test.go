@@ -0,0 +1,320 @@
+package nmcslog
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// otlpExport is the part of an export request checked by the tests.
+type otlpExport struct {
+	resource map[string]string
+	bodies   []string
+	severity []int
+	attrs    []map[string]string
+}
+
+// fakeCollector decodes the JSON and protobuf export requests, throttling the first request with a Retry-After.
+type fakeCollector struct {
+	mu       sync.Mutex
+	exports  []otlpExport
+	requests int
+	throttle bool
+}
+
+func (fc *fakeCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != otlpLogsPath {
+		http.NotFound(w, r)
+		return
+	}
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.requests++
+	if fc.throttle && fc.requests == 1 {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body = gz
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var export otlpExport
+	switch r.Header.Get("Content-Type") {
+	case "application/json":
+		export, err = decodeOTLPJSON(data)
+	case "application/x-protobuf":
+		export, err = decodeOTLPProtobuf(data)
+	default:
+		http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fc.exports = append(fc.exports, export)
+	_, _ = io.WriteString(w, "{}")
+}
+
+func decodeOTLPJSON(data []byte) (otlpExport, error) {
+	request := otlpLogsData{}
+	if err := json.Unmarshal(data, &request); err != nil {
+		return otlpExport{}, err
+	}
+
+	export := otlpExport{resource: map[string]string{}}
+	for _, rl := range request.ResourceLogs {
+		for _, kv := range rl.Resource.Attributes {
+			export.resource[kv.Key] = *kv.Value.StringValue
+		}
+		for _, sl := range rl.ScopeLogs {
+			for _, record := range sl.LogRecords {
+				export.bodies = append(export.bodies, *record.Body.StringValue)
+				export.severity = append(export.severity, record.SeverityNumber)
+				attrs := map[string]string{}
+				for _, kv := range record.Attributes {
+					attrs[kv.Key] = otlpTestValue(kv.Value)
+				}
+				export.attrs = append(export.attrs, attrs)
+			}
+		}
+	}
+
+	return export, nil
+}
+
+func otlpTestValue(v otlpAnyValue) string {
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.IntValue != nil:
+		return *v.IntValue
+	case v.BoolValue != nil:
+		return strconv.FormatBool(*v.BoolValue)
+	case v.DoubleValue != nil:
+		return strconv.FormatFloat(*v.DoubleValue, 'g', -1, 64)
+	}
+
+	return ""
+}
+
+func decodeOTLPProtobuf(data []byte) (otlpExport, error) {
+	export := otlpExport{resource: map[string]string{}}
+	request, err := protoFields(data)
+	if err != nil {
+		return export, err
+	}
+	for _, rl := range request[1] {
+		resourceLogs, err := protoFields(rl)
+		if err != nil {
+			return export, err
+		}
+		resource, err := protoFields(resourceLogs[1][0])
+		if err != nil {
+			return export, err
+		}
+		for _, kv := range resource[1] {
+			key, value, err := protoTestKeyValue(kv)
+			if err != nil {
+				return export, err
+			}
+			export.resource[key] = value
+		}
+
+		for _, sl := range resourceLogs[2] {
+			scopeLogs, err := protoFields(sl)
+			if err != nil {
+				return export, err
+			}
+			for _, lr := range scopeLogs[2] {
+				record, err := protoFields(lr)
+				if err != nil {
+					return export, err
+				}
+				body, err := protoFields(record[5][0])
+				if err != nil {
+					return export, err
+				}
+				export.bodies = append(export.bodies, string(body[1][0]))
+				export.severity = append(export.severity, int(protoVarintValue(record, 2)))
+				attrs := map[string]string{}
+				for _, kv := range record[6] {
+					key, value, err := protoTestKeyValue(kv)
+					if err != nil {
+						return export, err
+					}
+					attrs[key] = value
+				}
+				export.attrs = append(export.attrs, attrs)
+			}
+		}
+	}
+
+	return export, nil
+}
+
+// protoTestKeyValue decodes a KeyValue with a scalar value into strings.
+func protoTestKeyValue(data []byte) (string, string, error) {
+	kv, err := protoFields(data)
+	if err != nil {
+		return "", "", err
+	}
+	value, err := protoFields(kv[2][0])
+	if err != nil {
+		return "", "", err
+	}
+
+	switch {
+	case value[1] != nil:
+		return string(kv[1][0]), string(value[1][0]), nil
+	case value[2] != nil:
+		return string(kv[1][0]), strconv.FormatBool(protoVarintValue(value, 2) == 1), nil
+	case value[3] != nil:
+		return string(kv[1][0]), strconv.FormatInt(int64(protoVarintValue(value, 3)), 10), nil
+	case value[4] != nil:
+		return string(kv[1][0]), strconv.FormatFloat(math.Float64frombits(binary.LittleEndian.Uint64(value[4][0])), 'g', -1, 64), nil
+	}
+
+	return string(kv[1][0]), "", nil
+}
+
+func TestOTLPOutput(t *testing.T) {
+	for _, protobuf := range []bool{false, true} {
+		t.Run("protobuf="+strconv.FormatBool(protobuf), func(t *testing.T) {
+			collector := &fakeCollector{}
+			server := httptest.NewServer(collector)
+			defer server.Close()
+
+			config := Config{
+				Console: ConsoleOutput{OutputHandler: OutputHandler{Disable: true}},
+				File:    FileOutput{OutputHandler: OutputHandler{Disable: true}},
+				OTLP: OTLPOutput{
+					OutputHandler: OutputHandler{Resource: map[string]string{"deployment.environment": "test"}},
+					URL:           server.URL,
+					Protobuf:      protobuf,
+					Gzip:          true,
+					Batch:         BatchOptions{Age: "1h"},
+				},
+			}
+			logger, err := GetConfiguredLogger(&config)
+			if err != nil {
+				t.Fatalf("GetConfiguredLogger() error = %v", err)
+			}
+
+			logger.Info("started", "port", 8080, "tls", false, "ratio", 0.5, "empty", "")
+			logger.Error("failed")
+			if err = config.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			collector.mu.Lock()
+			defer collector.mu.Unlock()
+
+			if len(collector.exports) != 1 {
+				t.Fatalf("exports = %d, want 1", len(collector.exports))
+			}
+			export := collector.exports[0]
+			hostname, _ := os.Hostname()
+			for key, want := range map[string]string{
+				"deployment.environment": "test",
+				"service.name":           filepath.Base(os.Args[0]),
+				"host.name":              hostname,
+			} {
+				if export.resource[key] != want {
+					t.Errorf("resource %s = %q, want %q", key, export.resource[key], want)
+				}
+			}
+			if len(export.bodies) != 2 || export.bodies[0] != "started" || export.bodies[1] != "failed" {
+				t.Fatalf("bodies = %v", export.bodies)
+			}
+			if export.severity[0] != 9 || export.severity[1] != 17 {
+				t.Errorf("severity = %v, want [9 17]", export.severity)
+			}
+			want := map[string]string{"port": "8080", "tls": "false", "ratio": "0.5", "empty": ""}
+			for key, value := range want {
+				if got, ok := export.attrs[0][key]; !ok || got != value {
+					t.Errorf("attribute %s = %q, want %q", key, got, value)
+				}
+			}
+		})
+	}
+}
+
+func TestOTLPOutput_RetryAfter(t *testing.T) {
+	collector := &fakeCollector{throttle: true}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	config := Config{
+		Console: ConsoleOutput{OutputHandler: OutputHandler{Disable: true}},
+		File:    FileOutput{OutputHandler: OutputHandler{Disable: true}},
+		OTLP: OTLPOutput{
+			URL:   server.URL + otlpLogsPath,
+			Batch: BatchOptions{Age: "1h", InitialBackoff: "1ms"},
+		},
+	}
+	logger, err := GetConfiguredLogger(&config)
+	if err != nil {
+		t.Fatalf("GetConfiguredLogger() error = %v", err)
+	}
+
+	start := time.Now()
+	logger.Info("throttled")
+	if err = config.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %s, want the Retry-After of 1s", elapsed)
+	}
+
+	want := BatchStats{Records: 1, Batches: 1, Retries: 1}
+	if stats := config.OTLP.Stats(); stats != want {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+}
+
+func TestOTLPOutput_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  OTLPOutput
+		wantErr bool
+	}{
+		{name: "disabled without URL", output: OTLPOutput{Timeout: "invalid"}},
+		{name: "defaults", output: OTLPOutput{URL: "http://localhost:4318"}},
+		{name: "json format", output: OTLPOutput{URL: "http://localhost:4318", OutputHandler: OutputHandler{Format: FormatJSON}}, wantErr: true},
+		{name: "invalid URL", output: OTLPOutput{URL: "localhost:4318"}, wantErr: true},
+		{name: "invalid timeout", output: OTLPOutput{URL: "http://localhost:4318", Timeout: "soon"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.output.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
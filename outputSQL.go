@@ -0,0 +1,381 @@
+package nmcslog
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultSQLTable is the default name of the table the records are inserted into.
+	DefaultSQLTable = "logs"
+	// DefaultSQLTimeout is the default timeout of each transaction.
+	DefaultSQLTimeout = 10 * time.Second
+	// DefaultSQLPruneInterval is the default time between the deletions of the rows older than the retention.
+	DefaultSQLPruneInterval = time.Hour
+)
+
+// sqlTableName are the table names accepted unquoted by the supported databases.
+var sqlTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// SQLOutput defines the settings specific to the database/sql output, inserting the records into a table with
+// the time, level, message, source and JSON attrs columns. The table is created if missing.
+//
+// The database driver must be registered by the application, such as modernc.org/sqlite, github.com/lib/pq or
+// github.com/go-sql-driver/mysql. The Postgres and MySQL drivers get their own column types and placeholders.
+type SQLOutput struct {
+	OutputHandler
+	// Driver is the name of the registered database/sql driver, such as sqlite, postgres, pgx or mysql. It is also
+	// required with DB to select the SQL dialect.
+	Driver string `json:",omitempty" jsonschema:"title=Driver,example=sqlite,example=postgres"`
+	// DSN is the data source name of the database. If neither DSN nor DB are provided, SQL logging will be disabled.
+	DSN string `json:",omitempty" jsonschema:"title=Data Source Name,example=/var/lib/api/logs.db"`
+	// DB is an open database of the Driver used instead of the DSN, it is left open when the output is closed.
+	DB *sql.DB `json:"-"`
+	// Table the records are inserted into.
+	Table string `json:",omitempty" jsonschema:"title=Table,example=api_logs,default=logs"`
+	// RetentionDays deletes the rows older than the given number of days, 0 keeps all the rows.
+	RetentionDays int `json:",omitempty" jsonschema:"title=Retention Days,example=30,default=0"`
+	// PruneInterval is the time between the deletions of the rows older than the retention, as a Go duration.
+	PruneInterval string `json:",omitempty" jsonschema:"title=Prune Interval,example=24h,default=1h"`
+	// Timeout of each transaction as a Go duration.
+	Timeout string `json:",omitempty" jsonschema:"title=Timeout,example=30s,default=10s"`
+	// Batch defines how the records are grouped into transactions and retried.
+	Batch   BatchOptions `json:",omitempty"`
+	store   *sqlStore
+	batcher *batcher[sqlRecord]
+}
+
+func (so *SQLOutput) enabled() bool {
+	return !so.Disable && (so.DSN != "" || so.DB != nil)
+}
+
+func (so *SQLOutput) Validate() (err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: validate config [sql output]: %w", err)
+		}
+	}()
+
+	if !so.enabled() {
+		return nil
+	}
+	if so.Format == "" {
+		so.Format = FormatJSON
+	}
+	if err = so.OutputHandler.Validate(); err != nil {
+		return err
+	}
+	if so.Format != FormatJSON {
+		return fmt.Errorf("invalid format [%s], only %s is supported", so.Format, FormatJSON)
+	}
+	if so.DB == nil && !slices.Contains(sql.Drivers(), so.Driver) {
+		return fmt.Errorf("unknown driver [%s], it must be imported by the application", so.Driver)
+	}
+	if so.DB != nil && so.Driver == "" {
+		return errors.New("missing Driver of the DB, it selects the SQL dialect")
+	}
+	if so.Table != "" && !sqlTableName.MatchString(so.Table) {
+		return fmt.Errorf("invalid Table [%s]", so.Table)
+	}
+	if so.RetentionDays < 0 {
+		return fmt.Errorf("invalid RetentionDays [%d]", so.RetentionDays)
+	}
+	for name, value := range map[string]string{"PruneInterval": so.PruneInterval, "Timeout": so.Timeout} {
+		if _, err = parseDuration(value, 0); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+
+	return so.Batch.Validate()
+}
+
+func (so *SQLOutput) GetHandler() (handler slog.Handler, err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: get handler [sql output]: %w", err)
+		}
+	}()
+
+	if !so.enabled() {
+		return nil, fmt.Errorf("[%s] %w", so.Driver, ErrHandlerDisabled)
+	}
+
+	if so.batcher == nil {
+		settings, err := so.Batch.settings()
+		if err != nil {
+			return nil, err
+		}
+		timeout, err := parseDuration(so.Timeout, DefaultSQLTimeout)
+		if err != nil {
+			return nil, err
+		}
+		pruneInterval, err := parseDuration(so.PruneInterval, DefaultSQLPruneInterval)
+		if err != nil {
+			return nil, err
+		}
+
+		store := &sqlStore{
+			db:            so.DB,
+			dialect:       sqlDialectOf(so.Driver),
+			table:         so.Table,
+			timeout:       timeout,
+			retention:     time.Duration(so.RetentionDays) * 24 * time.Hour,
+			pruneInterval: pruneInterval,
+		}
+		if store.table == "" {
+			store.table = DefaultSQLTable
+		}
+		if store.db == nil {
+			// sql.Open only validates the arguments, the connections are opened by the first transaction.
+			if store.db, err = sql.Open(so.Driver, so.DSN); err != nil {
+				return nil, fmt.Errorf("opening database: %w", err)
+			}
+			store.ownDB = true
+		}
+
+		so.store = store
+		so.batcher = newBatcher(settings, store.insert)
+	}
+
+	handler, err = so.entryHandler(&sqlWriter{batcher: so.batcher})
+	if err != nil {
+		return nil, fmt.Errorf("getting handler [sql]: %w", err)
+	}
+
+	return handler, nil
+}
+
+// SQLStats are the delivery statistics of the SQL output.
+type SQLStats struct {
+	BatchStats
+	// PrunedRows is the number of rows deleted because they were older than the retention.
+	PrunedRows uint64
+}
+
+// Stats returns the delivery statistics of the output, a batch being a transaction.
+func (so *SQLOutput) Stats() SQLStats {
+	if so.batcher == nil {
+		return SQLStats{}
+	}
+
+	return SQLStats{
+		BatchStats: so.batcher.stats(),
+		PrunedRows: so.store.pruned.Load(),
+	}
+}
+
+// Close inserts the pending records and closes the database, unless it was provided as DB.
+func (so *SQLOutput) Close() error {
+	if so.batcher == nil {
+		return nil
+	}
+
+	err := so.batcher.Close()
+	if so.store.ownDB {
+		err = errors.Join(err, so.store.db.Close())
+	}
+
+	return err
+}
+
+// sqlRecord is a row of the table.
+type sqlRecord struct {
+	time    time.Time
+	level   string
+	message string
+	source  sql.NullString
+	attrs   string
+}
+
+// sqlWriter converts the entries into rows.
+type sqlWriter struct {
+	batcher *batcher[sqlRecord]
+}
+
+func (sw *sqlWriter) writeEntry(e *entry) error {
+	attrs, err := json.Marshal(attrsMap(e.Attrs))
+	if err != nil {
+		return fmt.Errorf("encoding attrs: %w", err)
+	}
+
+	record := sqlRecord{
+		time:    e.Time.UTC(),
+		level:   levelName(e.Level),
+		message: e.Message,
+		attrs:   string(attrs),
+	}
+	if e.Source != nil {
+		record.source = sql.NullString{String: fmt.Sprintf("%s:%d", e.Source.File, e.Source.Line), Valid: true}
+	}
+
+	return sw.batcher.add(record)
+}
+
+// sqlDialect holds the differences between the databases in the statements of the SQL output.
+type sqlDialect struct {
+	// timeType is the column type of the record time.
+	timeType string
+	// numbered placeholders are $1, $2... rather than ?.
+	numbered bool
+	// indexQuery counts the indexes of the table with the given name, for the databases without CREATE INDEX IF NOT
+	// EXISTS.
+	indexQuery string
+}
+
+func sqlDialectOf(driver string) sqlDialect {
+	switch driver {
+	case "postgres", "pgx", "cloudsqlpostgres":
+		return sqlDialect{timeType: "TIMESTAMPTZ", numbered: true}
+	case "mysql":
+		return sqlDialect{
+			timeType: "DATETIME(6)",
+			indexQuery: "SELECT COUNT(*) FROM information_schema.statistics " +
+				"WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?",
+		}
+	default:
+		return sqlDialect{timeType: "TIMESTAMP"}
+	}
+}
+
+func (sd sqlDialect) placeholder(i int) string {
+	if sd.numbered {
+		return fmt.Sprintf("$%d", i)
+	}
+
+	return "?"
+}
+
+func (sd sqlDialect) createTableStatement(table string) string {
+	return fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (time %s NOT NULL, level VARCHAR(16) NOT NULL, message TEXT NOT NULL, "+
+			"source TEXT, attrs TEXT NOT NULL)", table, sd.timeType,
+	)
+}
+
+// createIndexStatement returns the statement creating the time index used to prune the table, it must only be run
+// if the index is missing when the dialect has an indexQuery.
+func (sd sqlDialect) createIndexStatement(table string) string {
+	if sd.indexQuery != "" {
+		return fmt.Sprintf("CREATE INDEX %s_time ON %s (time)", table, table)
+	}
+
+	return fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_time ON %s (time)", table, table)
+}
+
+func (sd sqlDialect) insertStatement(table string) string {
+	placeholders := make([]string, 5)
+	for i := range placeholders {
+		placeholders[i] = sd.placeholder(i + 1)
+	}
+
+	return fmt.Sprintf("INSERT INTO %s (time, level, message, source, attrs) VALUES (%s)",
+		table, strings.Join(placeholders, ", "))
+}
+
+func (sd sqlDialect) pruneStatement(table string) string {
+	return fmt.Sprintf("DELETE FROM %s WHERE time < %s", table, sd.placeholder(1))
+}
+
+// sqlStore inserts the batches of records in transactions, it is only used by the batcher goroutine.
+type sqlStore struct {
+	db            *sql.DB
+	ownDB         bool
+	dialect       sqlDialect
+	table         string
+	timeout       time.Duration
+	retention     time.Duration
+	pruneInterval time.Duration
+
+	created   bool
+	lastPrune time.Time
+	pruned    atomic.Uint64
+}
+
+// create creates the table and its time index if missing.
+func (ss *sqlStore) create(ctx context.Context) error {
+	if _, err := ss.db.ExecContext(ctx, ss.dialect.createTableStatement(ss.table)); err != nil {
+		return fmt.Errorf("creating table [%s]: %w", ss.table, err)
+	}
+
+	index := ss.table + "_time"
+	if ss.dialect.indexQuery != "" {
+		var count int
+		if err := ss.db.QueryRowContext(ctx, ss.dialect.indexQuery, ss.table, index).Scan(&count); err != nil {
+			return fmt.Errorf("looking up index [%s]: %w", index, err)
+		}
+		if count > 0 {
+			return nil
+		}
+	}
+	if _, err := ss.db.ExecContext(ctx, ss.dialect.createIndexStatement(ss.table)); err != nil {
+		return fmt.Errorf("creating index [%s]: %w", index, err)
+	}
+
+	return nil
+}
+
+func (ss *sqlStore) insert(batch []sqlRecord) error {
+	ctx, cancel := context.WithTimeout(context.Background(), ss.timeout)
+	defer cancel()
+
+	if !ss.created {
+		if err := ss.create(ctx); err != nil {
+			return err
+		}
+		ss.created = true
+	}
+
+	tx, err := ss.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	// Rollback is a no-op once the transaction is committed.
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.PrepareContext(ctx, ss.dialect.insertStatement(ss.table))
+	if err != nil {
+		return fmt.Errorf("preparing insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, r := range batch {
+		if _, err = stmt.ExecContext(ctx, r.time, r.level, r.message, r.source, r.attrs); err != nil {
+			return fmt.Errorf("inserting record: %w", err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+
+	if ss.retention > 0 && time.Since(ss.lastPrune) >= ss.pruneInterval {
+		ss.prune(ctx)
+	}
+
+	return nil
+}
+
+// prune deletes the rows older than the retention. A failure is logged to the DebugLogger rather than failing the
+// inserted batch, the deletion is tried again after the next interval.
+func (ss *sqlStore) prune(ctx context.Context) {
+	ss.lastPrune = time.Now()
+
+	result, err := ss.db.ExecContext(ctx, ss.dialect.pruneStatement(ss.table), ss.lastPrune.UTC().Add(-ss.retention))
+	if err == nil {
+		var rows int64
+		if rows, err = result.RowsAffected(); err == nil {
+			ss.pruned.Add(uint64(rows))
+			return
+		}
+	}
+
+	DebugLogger().Warn("sql output prune failed", "table", ss.table, "error", err)
+}
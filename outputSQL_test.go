@@ -0,0 +1,241 @@
+package nmcslog
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqlRow is a row of the table written by the SQL output.
+type sqlRow struct {
+	time    time.Time
+	level   string
+	message string
+	source  sql.NullString
+	attrs   map[string]any
+}
+
+func querySQLRows(t *testing.T, db *sql.DB, table string) []sqlRow {
+	t.Helper()
+
+	rows, err := db.Query("SELECT time, level, message, source, attrs FROM " + table + " ORDER BY time")
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	defer rows.Close()
+
+	var result []sqlRow
+	for rows.Next() {
+		var row sqlRow
+		var attrs string
+		if err = rows.Scan(&row.time, &row.level, &row.message, &row.source, &attrs); err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+		if err = json.Unmarshal([]byte(attrs), &row.attrs); err != nil {
+			t.Fatalf("attrs = %q: %v", attrs, err)
+		}
+		result = append(result, row)
+	}
+	if err = rows.Err(); err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+
+	return result
+}
+
+func TestSQLOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.db")
+	config := Config{
+		Console: ConsoleOutput{OutputHandler: OutputHandler{Disable: true}},
+		File:    FileOutput{OutputHandler: OutputHandler{Disable: true}},
+		SQL: SQLOutput{
+			OutputHandler: OutputHandler{IncludeSource: true},
+			Driver:        "sqlite",
+			DSN:           path,
+			Table:         "api_logs",
+		},
+	}
+	logger, err := GetConfiguredLogger(&config)
+	if err != nil {
+		t.Fatalf("GetConfiguredLogger() error = %v", err)
+	}
+
+	start := time.Now().Add(-time.Second)
+	logger.Info("request", "path", "/orders", slog.Group("user", "id", 7))
+	logger.With("component", "db").Warn("slow query", "ms", 250)
+	if err = config.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if stats := config.SQL.Stats(); stats.Records != 2 || stats.Batches != 1 {
+		t.Errorf("Stats() = %+v, want 2 records in a transaction", stats)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer db.Close()
+
+	rows := querySQLRows(t, db, "api_logs")
+	if len(rows) != 2 {
+		t.Fatalf("rows = %v, want 2", rows)
+	}
+	if row := rows[0]; row.level != "INFO" || row.message != "request" || row.time.Before(start) ||
+		row.attrs["path"] != "/orders" || row.attrs["user"].(map[string]any)["id"] != float64(7) {
+		t.Errorf("row = %+v", row)
+	}
+	if row := rows[1]; row.level != "WARN" || row.attrs["component"] != "db" || row.attrs["ms"] != float64(250) {
+		t.Errorf("row = %+v", row)
+	}
+	if source := rows[0].source; !source.Valid || !strings.Contains(source.String, "outputSQL_test.go:") {
+		t.Errorf("source = %v, want the file and line", source)
+	}
+}
+
+func TestSQLOutput_Prune(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "logs.db"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer db.Close()
+
+	log := func(output SQLOutput, message string) SQLOutput {
+		config := Config{
+			Console: ConsoleOutput{OutputHandler: OutputHandler{Disable: true}},
+			File:    FileOutput{OutputHandler: OutputHandler{Disable: true}},
+			SQL:     output,
+		}
+		logger, err := GetConfiguredLogger(&config)
+		if err != nil {
+			t.Fatalf("GetConfiguredLogger() error = %v", err)
+		}
+		logger.Info(message)
+		if err = config.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		return config.SQL
+	}
+
+	log(SQLOutput{DB: db, Driver: "sqlite"}, "expired")
+	if _, err = db.Exec("UPDATE logs SET time = ?", time.Now().UTC().AddDate(0, 0, -3)); err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	output := log(SQLOutput{DB: db, Driver: "sqlite", RetentionDays: 2}, "kept")
+
+	// The provided DB is still open.
+	rows := querySQLRows(t, db, "logs")
+	if len(rows) != 1 || rows[0].message != "kept" {
+		t.Errorf("rows = %+v, want the expired row deleted", rows)
+	}
+	if stats := output.Stats(); stats.PrunedRows != 1 {
+		t.Errorf("Stats() = %+v, want 1 pruned row", stats)
+	}
+}
+
+func TestSQLDialect(t *testing.T) {
+	tests := []struct {
+		driver     string
+		wantCreate string
+		wantIndex  string
+		wantInsert string
+		wantPrune  string
+	}{
+		{
+			driver:     "sqlite",
+			wantCreate: "CREATE TABLE IF NOT EXISTS logs (time TIMESTAMP NOT NULL,",
+			wantIndex:  "CREATE INDEX IF NOT EXISTS logs_time ON logs (time)",
+			wantInsert: "INSERT INTO logs (time, level, message, source, attrs) VALUES (?, ?, ?, ?, ?)",
+			wantPrune:  "DELETE FROM logs WHERE time < ?",
+		},
+		{
+			driver:     "pgx",
+			wantCreate: "CREATE TABLE IF NOT EXISTS logs (time TIMESTAMPTZ NOT NULL,",
+			wantIndex:  "CREATE INDEX IF NOT EXISTS logs_time ON logs (time)",
+			wantInsert: "INSERT INTO logs (time, level, message, source, attrs) VALUES ($1, $2, $3, $4, $5)",
+			wantPrune:  "DELETE FROM logs WHERE time < $1",
+		},
+		{
+			driver:     "mysql",
+			wantCreate: "CREATE TABLE IF NOT EXISTS logs (time DATETIME(6) NOT NULL,",
+			wantIndex:  "CREATE INDEX logs_time ON logs (time)",
+			wantInsert: "INSERT INTO logs (time, level, message, source, attrs) VALUES (?, ?, ?, ?, ?)",
+			wantPrune:  "DELETE FROM logs WHERE time < ?",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.driver, func(t *testing.T) {
+			dialect := sqlDialectOf(tt.driver)
+			if got := dialect.createTableStatement("logs"); !strings.HasPrefix(got, tt.wantCreate) {
+				t.Errorf("createTableStatement() = %q, want prefix %q", got, tt.wantCreate)
+			}
+			if got := dialect.createIndexStatement("logs"); got != tt.wantIndex {
+				t.Errorf("createIndexStatement() = %q, want %q", got, tt.wantIndex)
+			}
+			if got := dialect.insertStatement("logs"); got != tt.wantInsert {
+				t.Errorf("insertStatement() = %q, want %q", got, tt.wantInsert)
+			}
+			if got := dialect.pruneStatement("logs"); got != tt.wantPrune {
+				t.Errorf("pruneStatement() = %q, want %q", got, tt.wantPrune)
+			}
+		})
+	}
+}
+
+func TestSQLStore_CreateIndexQuery(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "logs.db"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer db.Close()
+
+	// The index is only created when missing, as without CREATE INDEX IF NOT EXISTS.
+	store := &sqlStore{db: db, table: "logs", dialect: sqlDialect{
+		timeType:   "TIMESTAMP",
+		indexQuery: "SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND name = ?",
+	}}
+	for range 2 {
+		if err = store.create(context.Background()); err != nil {
+			t.Fatalf("create() error = %v", err)
+		}
+	}
+
+	var count int
+	if err = db.QueryRow(store.dialect.indexQuery, "logs", "logs_time").Scan(&count); err != nil || count != 1 {
+		t.Errorf("indexes = %d, error = %v, want the time index", count, err)
+	}
+}
+
+func TestSQLOutput_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  SQLOutput
+		wantErr bool
+	}{
+		{name: "disabled without DSN", output: SQLOutput{Driver: "unknown"}},
+		{name: "sqlite", output: SQLOutput{Driver: "sqlite", DSN: "logs.db", RetentionDays: 30}},
+		{name: "open database", output: SQLOutput{DB: &sql.DB{}, Driver: "mysql"}},
+		{name: "open database without driver", output: SQLOutput{DB: &sql.DB{}}, wantErr: true},
+		{name: "unknown driver", output: SQLOutput{Driver: "oracle", DSN: "logs"}, wantErr: true},
+		{name: "missing driver", output: SQLOutput{DSN: "logs.db"}, wantErr: true},
+		{name: "invalid table", output: SQLOutput{Driver: "sqlite", DSN: "logs.db", Table: "logs; DROP TABLE users"}, wantErr: true},
+		{name: "negative retention", output: SQLOutput{Driver: "sqlite", DSN: "logs.db", RetentionDays: -1}, wantErr: true},
+		{name: "invalid prune interval", output: SQLOutput{Driver: "sqlite", DSN: "logs.db", PruneInterval: "daily"}, wantErr: true},
+		{name: "text format", output: SQLOutput{Driver: "sqlite", DSN: "logs.db", OutputHandler: OutputHandler{Format: FormatText}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.output.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
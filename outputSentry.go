@@ -0,0 +1,427 @@
+package nmcslog
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultSentryBreadcrumbs is the default number of recent lower-level records sent as breadcrumbs of an event.
+	DefaultSentryBreadcrumbs = 50
+
+	sentryClient  = "nmcslog/1.0"
+	sentryVersion = "7"
+)
+
+// SentryOutput defines the settings specific to the Sentry output. ERROR and FATAL records are sent as error
+// events, while the lower-level records enabled by the LogLevel are kept as the breadcrumbs of the next events.
+type SentryOutput struct {
+	OutputHandler
+	// DSN of the Sentry project. If not provided, Sentry logging will be disabled.
+	DSN string `json:",omitempty" jsonschema:"title=DSN,example=https://public@o0.ingest.sentry.io/1"`
+	// Environment of the events, such as production.
+	Environment string `json:",omitempty" jsonschema:"title=Environment,example=production"`
+	// Release of the events, such as the version of the application.
+	Release string `json:",omitempty" jsonschema:"title=Release,example=api@1.2.3"`
+	// ServerName of the events, defaults to the host.name resource attribute or else the hostname.
+	ServerName string `json:",omitempty" jsonschema:"title=Server Name,example=web-01"`
+	// Tags are the attributes, by their dotted group path, sent as the searchable tags rather than the extra data.
+	Tags []string `json:",omitempty" jsonschema:"title=Tag Attributes,example=user.id"`
+	// Breadcrumbs is the number of recent lower-level records sent along with an event, -1 disables them.
+	Breadcrumbs int `json:",omitempty" jsonschema:"title=Breadcrumbs,example=100,default=50"`
+	// Timeout of each request as a Go duration.
+	Timeout string `json:",omitempty" jsonschema:"title=Timeout,example=30s,default=10s"`
+	// Batch defines how the events are queued and retried.
+	Batch   BatchOptions `json:",omitempty"`
+	batcher *batcher[[]byte]
+}
+
+func (so *SentryOutput) enabled() bool {
+	return !so.Disable && so.DSN != ""
+}
+
+func (so *SentryOutput) Validate() (err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: validate config [sentry output]: %w", err)
+		}
+	}()
+
+	if !so.enabled() {
+		return nil
+	}
+	// The events are always sent as JSON.
+	if so.Format == "" {
+		so.Format = FormatJSON
+	}
+	if err = so.OutputHandler.Validate(); err != nil {
+		return err
+	}
+	if so.Format != FormatJSON {
+		return fmt.Errorf("invalid format [%s], only %s is supported", so.Format, FormatJSON)
+	}
+	if _, err = parseSentryDSN(so.DSN); err != nil {
+		return err
+	}
+	if so.Breadcrumbs < -1 {
+		return fmt.Errorf("invalid Breadcrumbs [%d]", so.Breadcrumbs)
+	}
+	if _, err = parseDuration(so.Timeout, 0); err != nil {
+		return fmt.Errorf("invalid Timeout: %w", err)
+	}
+
+	return so.Batch.Validate()
+}
+
+func (so *SentryOutput) GetHandler() (handler slog.Handler, err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: get handler [sentry output]: %w", err)
+		}
+	}()
+
+	if !so.enabled() {
+		return nil, fmt.Errorf("[%s] %w", so.DSN, ErrHandlerDisabled)
+	}
+
+	dsn, err := parseSentryDSN(so.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	if so.batcher == nil {
+		settings, err := so.Batch.settings()
+		if err != nil {
+			return nil, err
+		}
+		timeout, err := parseDuration(so.Timeout, DefaultHTTPTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		client := &http.Client{Timeout: timeout}
+		headers := map[string]string{"X-Sentry-Auth": dsn.auth()}
+		so.batcher = newBatcher(settings, func(batch [][]byte) error {
+			// An envelope holds a single event, so the batch is sent one envelope at a time.
+			return sendEach(batch, func(envelope []byte) error {
+				return sendHTTP(client, http.MethodPost, dsn.envelopeURL, "application/x-sentry-envelope", headers, envelope, false)
+			})
+		})
+	}
+
+	writer := &sentryWriter{
+		dsn:         so.DSN,
+		environment: so.Environment,
+		release:     so.Release,
+		serverName:  so.ServerName,
+		tags:        so.Tags,
+		breadcrumbs: &sentryBreadcrumbs{size: so.Breadcrumbs},
+		batcher:     so.batcher,
+	}
+	if writer.serverName == "" {
+		writer.serverName = so.Resource["host.name"]
+		if writer.serverName == "" {
+			writer.serverName, _ = os.Hostname()
+		}
+	}
+	if so.Breadcrumbs == 0 {
+		writer.breadcrumbs.size = DefaultSentryBreadcrumbs
+	}
+
+	handler, err = so.entryHandler(writer)
+	if err != nil {
+		return nil, fmt.Errorf("getting handler [sentry]: %w", err)
+	}
+
+	return handler, nil
+}
+
+// Stats returns the delivery statistics of the output, counting the events.
+func (so *SentryOutput) Stats() BatchStats {
+	if so.batcher == nil {
+		return BatchStats{}
+	}
+
+	return so.batcher.stats()
+}
+
+// Close sends the pending events and stops the output.
+func (so *SentryOutput) Close() error {
+	if so.batcher == nil {
+		return nil
+	}
+
+	return so.batcher.Close()
+}
+
+// sentryDSN is a parsed DSN of the form {scheme}://{public_key}@{host}/{path}{project_id}.
+type sentryDSN struct {
+	publicKey   string
+	envelopeURL string
+}
+
+func parseSentryDSN(dsn string) (sentryDSN, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return sentryDSN{}, fmt.Errorf("invalid DSN [%s]: %w", dsn, err)
+	}
+	project := path.Base(u.Path)
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User.Username() == "" || project == "." || project == "/" {
+		return sentryDSN{}, fmt.Errorf("invalid DSN [%s]: must be {scheme}://{public_key}@{host}/{project_id}", dsn)
+	}
+
+	envelope := url.URL{
+		Scheme: u.Scheme,
+		Host:   u.Host,
+		Path:   path.Join(path.Dir(u.Path), "api", project, "envelope") + "/",
+	}
+
+	return sentryDSN{publicKey: u.User.Username(), envelopeURL: envelope.String()}, nil
+}
+
+// auth returns the X-Sentry-Auth header authenticating with the public key.
+func (sd sentryDSN) auth() string {
+	return fmt.Sprintf("Sentry sentry_version=%s, sentry_key=%s, sentry_client=%s", sentryVersion, sd.publicKey, sentryClient)
+}
+
+// sentryLevel maps the level onto the Sentry levels.
+func sentryLevel(level slog.Level) string {
+	switch {
+	case level >= LevelFatal:
+		return "fatal"
+	case level >= LevelError:
+		return "error"
+	case level >= LevelWarn:
+		return "warning"
+	case level >= LevelInfo:
+		return "info"
+	default:
+		return "debug"
+	}
+}
+
+// sentryBreadcrumb is a lower-level record leading up to an event.
+type sentryBreadcrumb struct {
+	Timestamp string         `json:"timestamp"`
+	Category  string         `json:"category"`
+	Level     string         `json:"level"`
+	Message   string         `json:"message"`
+	Data      map[string]any `json:"data,omitempty"`
+}
+
+// sentryBreadcrumbs keeps the most recent breadcrumbs, up to the size.
+type sentryBreadcrumbs struct {
+	mu     sync.Mutex
+	size   int
+	values []sentryBreadcrumb
+}
+
+func (sb *sentryBreadcrumbs) add(e *entry) {
+	if sb.size <= 0 {
+		return
+	}
+
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	if len(sb.values) >= sb.size {
+		sb.values = slices.Delete(sb.values, 0, len(sb.values)-sb.size+1)
+	}
+	breadcrumb := sentryBreadcrumb{
+		Timestamp: e.Time.UTC().Format(time.RFC3339Nano),
+		Category:  "log",
+		Level:     sentryLevel(e.Level),
+		Message:   e.Message,
+	}
+	if len(e.Attrs) > 0 {
+		breadcrumb.Data = attrsMap(e.Attrs)
+	}
+	sb.values = append(sb.values, breadcrumb)
+}
+
+func (sb *sentryBreadcrumbs) list() []sentryBreadcrumb {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	return slices.Clone(sb.values)
+}
+
+// sentryException is an error of an event, the frames are ordered from the outermost to the innermost call.
+type sentryException struct {
+	Type       string `json:"type,omitempty"`
+	Value      string `json:"value"`
+	Stacktrace *struct {
+		Frames []sentryFrame `json:"frames"`
+	} `json:"stacktrace,omitempty"`
+}
+
+type sentryFrame struct {
+	Function string `json:"function"`
+	Filename string `json:"filename"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+// sentryEvent is the part of the Sentry event payload filled from a record.
+// https://develop.sentry.dev/sdk/data-model/event-payloads/
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Platform    string            `json:"platform"`
+	Level       string            `json:"level"`
+	Logger      string            `json:"logger"`
+	Message     map[string]string `json:"message"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Extra       map[string]any    `json:"extra,omitempty"`
+	Exception   *struct {
+		Values []sentryException `json:"values"`
+	} `json:"exception,omitempty"`
+	Breadcrumbs *struct {
+		Values []sentryBreadcrumb `json:"values"`
+	} `json:"breadcrumbs,omitempty"`
+	Contexts map[string]any `json:"contexts,omitempty"`
+}
+
+// sentryWriter turns the ERROR and FATAL entries into envelopes and keeps the others as breadcrumbs.
+type sentryWriter struct {
+	dsn         string
+	environment string
+	release     string
+	serverName  string
+	tags        []string
+	breadcrumbs *sentryBreadcrumbs
+	batcher     *batcher[[]byte]
+}
+
+func (sw *sentryWriter) writeEntry(e *entry) error {
+	if e.Level < LevelError {
+		sw.breadcrumbs.add(e)
+		return nil
+	}
+
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	event := sentryEvent{
+		EventID:     hex.EncodeToString(id),
+		Timestamp:   e.Time.UTC().Format(time.RFC3339Nano),
+		Platform:    "go",
+		Level:       sentryLevel(e.Level),
+		Logger:      otelScopeName,
+		Message:     map[string]string{"formatted": e.Message},
+		ServerName:  sw.serverName,
+		Environment: sw.environment,
+		Release:     sw.release,
+	}
+
+	attrs := e.Attrs
+	for _, tag := range sw.tags {
+		remaining, value, found := takeAttr(attrs, tag)
+		if found {
+			attrs = remaining
+			if event.Tags == nil {
+				event.Tags = map[string]string{}
+			}
+			event.Tags[tag] = fmt.Sprint(attrValue(value))
+		}
+	}
+
+	extra := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		if details, ok := extractError(a); ok && event.Exception == nil {
+			event.Exception = &struct {
+				Values []sentryException `json:"values"`
+			}{Values: []sentryException{sentryExceptionOf(details)}}
+			continue
+		}
+		extra = append(extra, a)
+	}
+	if len(extra) > 0 {
+		event.Extra = attrsMap(extra)
+	}
+	if e.Source != nil {
+		if event.Extra == nil {
+			event.Extra = map[string]any{}
+		}
+		event.Extra[slog.SourceKey] = map[string]any{"function": e.Source.Function, "file": e.Source.File, "line": e.Source.Line}
+	}
+
+	if breadcrumbs := sw.breadcrumbs.list(); len(breadcrumbs) > 0 {
+		event.Breadcrumbs = &struct {
+			Values []sentryBreadcrumb `json:"values"`
+		}{Values: breadcrumbs}
+	}
+	if trace, ok := TraceFromContext(e.Context); ok {
+		event.Contexts = map[string]any{"trace": map[string]string{"trace_id": trace.TraceID, "span_id": trace.SpanID}}
+	}
+
+	envelope, err := sentryEnvelope(sw.dsn, event)
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+
+	return sw.batcher.add(envelope)
+}
+
+// sentryExceptionOf converts the error details, reversing the frames as Sentry expects the innermost call last.
+func sentryExceptionOf(details errorDetails) sentryException {
+	exception := sentryException{Type: details.Type, Value: details.Message}
+	if exception.Type == "" {
+		exception.Type = "error"
+	}
+	if len(details.Frames) == 0 {
+		return exception
+	}
+
+	exception.Stacktrace = &struct {
+		Frames []sentryFrame `json:"frames"`
+	}{}
+	for i := len(details.Frames) - 1; i >= 0; i-- {
+		frame := details.Frames[i]
+		exception.Stacktrace.Frames = append(exception.Stacktrace.Frames, sentryFrame{
+			Function: frame.Func,
+			Filename: frame.Source,
+			Lineno:   frame.Line,
+			InApp:    !strings.HasPrefix(frame.Func, "runtime."),
+		})
+	}
+
+	return exception
+}
+
+// sentryEnvelope returns the envelope holding the event as its single item.
+// https://develop.sentry.dev/sdk/envelopes/
+func sentryEnvelope(dsn string, event sentryEvent) ([]byte, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	header, err := json.Marshal(map[string]string{
+		"event_id": event.EventID,
+		"dsn":      dsn,
+		"sent_at":  time.Now().UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return nil, err
+	}
+	itemHeader, err := json.Marshal(map[string]any{"type": "event", "length": len(payload)})
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.Join([][]byte{header, itemHeader, payload}, []byte("\n")), nil
+}
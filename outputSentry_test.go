@@ -0,0 +1,167 @@
+package nmcslog
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mdobak/go-xerrors"
+)
+
+// fakeSentry is an envelope endpoint stand-in of project 42, collecting the events.
+type fakeSentry struct {
+	mu     sync.Mutex
+	auth   string
+	events []map[string]any
+}
+
+func (fs *fakeSentry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/sentry/api/42/envelope/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.auth = r.Header.Get("X-Sentry-Auth")
+
+	scanner := bufio.NewScanner(r.Body)
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) != 3 {
+		http.Error(w, "expected a single item", http.StatusBadRequest)
+		return
+	}
+
+	header, item, event := map[string]any{}, map[string]any{}, map[string]any{}
+	for i, v := range []*map[string]any{&header, &item, &event} {
+		if err := json.Unmarshal([]byte(lines[i]), v); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if item["type"] != "event" || item["length"] != float64(len(lines[2])) || header["event_id"] != event["event_id"] {
+		http.Error(w, "invalid envelope headers", http.StatusBadRequest)
+		return
+	}
+	fs.events = append(fs.events, event)
+	_ = json.NewEncoder(w).Encode(map[string]any{"id": event["event_id"]})
+}
+
+func TestSentryOutput(t *testing.T) {
+	sentry := &fakeSentry{}
+	server := httptest.NewServer(sentry)
+	defer server.Close()
+
+	config := Config{
+		Console: ConsoleOutput{OutputHandler: OutputHandler{Disable: true}},
+		File:    FileOutput{OutputHandler: OutputHandler{Disable: true}},
+		Sentry: SentryOutput{
+			OutputHandler: OutputHandler{LogLevel: LogLevel{Level: "DEBUG"}},
+			DSN:           strings.Replace(server.URL, "://", "://publickey@", 1) + "/sentry/42",
+			Environment:   "test",
+			Release:       "api@1.2.3",
+			ServerName:    "web-01",
+			Tags:          []string{"user.id"},
+			Breadcrumbs:   2,
+		},
+	}
+	logger, err := GetConfiguredLogger(&config)
+	if err != nil {
+		t.Fatalf("GetConfiguredLogger() error = %v", err)
+	}
+
+	logger.Debug("dropped from the breadcrumbs")
+	logger.Info("request", "path", "/orders")
+	logger.Warn("slow query")
+	logger.Error("checkout failed", "error", xerrors.New("card declined"), slog.Group("user", "id", 7), "cart", 3)
+	logger.Log(context.Background(), LevelFatal, "shutting down")
+	if err = config.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	sentry.mu.Lock()
+	defer sentry.mu.Unlock()
+
+	if !strings.Contains(sentry.auth, "sentry_key=publickey") || !strings.Contains(sentry.auth, "sentry_version=7") {
+		t.Errorf("X-Sentry-Auth = %q", sentry.auth)
+	}
+	if len(sentry.events) != 2 {
+		t.Fatalf("events = %v, want 2", sentry.events)
+	}
+
+	event := sentry.events[0]
+	for key, want := range map[string]any{
+		"level":       "error",
+		"environment": "test",
+		"release":     "api@1.2.3",
+		"server_name": "web-01",
+		"platform":    "go",
+	} {
+		if event[key] != want {
+			t.Errorf("%s = %v, want %v", key, event[key], want)
+		}
+	}
+	if event["message"].(map[string]any)["formatted"] != "checkout failed" {
+		t.Errorf("message = %v", event["message"])
+	}
+	if tags := event["tags"].(map[string]any); tags["user.id"] != "7" {
+		t.Errorf("tags = %v", tags)
+	}
+	if extra := event["extra"].(map[string]any); extra["cart"] != float64(3) || extra["error"] != nil || extra["user"] != nil {
+		t.Errorf("extra = %v", extra)
+	}
+
+	exceptions := event["exception"].(map[string]any)["values"].([]any)
+	exception := exceptions[0].(map[string]any)
+	frames := exception["stacktrace"].(map[string]any)["frames"].([]any)
+	last := frames[len(frames)-1].(map[string]any)
+	if exception["value"] != "card declined" || !strings.HasSuffix(last["function"].(string), "TestSentryOutput") {
+		t.Errorf("exception = %v, want the innermost frame last", exception)
+	}
+
+	breadcrumbs := event["breadcrumbs"].(map[string]any)["values"].([]any)
+	if len(breadcrumbs) != 2 ||
+		breadcrumbs[0].(map[string]any)["message"] != "request" ||
+		breadcrumbs[1].(map[string]any)["level"] != "warning" {
+		t.Errorf("breadcrumbs = %v, want the 2 most recent records", breadcrumbs)
+	}
+
+	if fatal := sentry.events[1]; fatal["level"] != "fatal" || fatal["exception"] != nil {
+		t.Errorf("event = %v, want a fatal event without exception", fatal)
+	}
+}
+
+func TestParseSentryDSN(t *testing.T) {
+	tests := []struct {
+		dsn     string
+		want    string
+		wantErr bool
+	}{
+		{dsn: "https://key@o1.ingest.sentry.io/42", want: "https://o1.ingest.sentry.io/api/42/envelope/"},
+		{dsn: "http://key@localhost:9000/sentry/7", want: "http://localhost:9000/sentry/api/7/envelope/"},
+		{dsn: "https://o1.ingest.sentry.io/42", wantErr: true},
+		{dsn: "https://key@o1.ingest.sentry.io/", wantErr: true},
+		{dsn: "ftp://key@host/1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dsn, func(t *testing.T) {
+			dsn, err := parseSentryDSN(tt.dsn)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSentryDSN() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if dsn.envelopeURL != tt.want {
+				t.Errorf("envelopeURL = %q, want %q", dsn.envelopeURL, tt.want)
+			}
+		})
+	}
+}
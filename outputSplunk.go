@@ -0,0 +1,362 @@
+package nmcslog
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// splunkEventPath is the path of the HEC event endpoint, added to a Splunk URL without a path.
+	splunkEventPath = "/services/collector/event"
+	// splunkAckPath is the path of the HEC indexer acknowledgement endpoint.
+	splunkAckPath = "/services/collector/ack"
+	// splunkChannelHeader identifies the channel of the requests, required when acknowledgements are enabled.
+	splunkChannelHeader = "X-Splunk-Request-Channel"
+
+	// DefaultSplunkAckTimeout is the default max time to wait for the indexer acknowledgement of a batch.
+	DefaultSplunkAckTimeout = 30 * time.Second
+	// splunkAckPollInterval is the first interval between the acknowledgement polls, doubling up to a second.
+	splunkAckPollInterval = 100 * time.Millisecond
+)
+
+// SplunkOutput defines the settings specific to the Splunk HTTP Event Collector output.
+type SplunkOutput struct {
+	OutputHandler
+	// URL of the HTTP Event Collector, the event endpoint path /services/collector/event is added if the URL has no path.
+	// If not provided, Splunk logging will be disabled.
+	URL string `json:",omitempty" jsonschema:"title=HEC URL,example=https://splunk.example.com:8088"`
+	// Token of the HTTP Event Collector.
+	Token string `json:",omitempty" jsonschema:"title=HEC Token"`
+	// Index the events are stored in, defaults to the default index of the token.
+	Index string `json:",omitempty" jsonschema:"title=Index,example=main"`
+	// Host of the events, defaults to the host.name resource attribute or else the hostname.
+	Host string `json:",omitempty" jsonschema:"title=Host,example=web-01"`
+	// Source of the events, defaults to the executable name.
+	Source string `json:",omitempty" jsonschema:"title=Source,example=api"`
+	// SourceType of the events, defaults to _json for the JSON based formats.
+	SourceType string `json:",omitempty" jsonschema:"title=Source Type,example=_json"`
+	// Fields are the attributes, by their dotted group path, sent as indexed fields rather than in the event.
+	Fields []string `json:",omitempty" jsonschema:"title=Indexed Field Attributes,example=user.id"`
+	// Ack waits for the indexer acknowledgement of each batch, retrying the batch if it is not acknowledged in time.
+	Ack bool `json:",omitempty" jsonschema:"title=Indexer Acknowledgement,example=true,default=false"`
+	// Channel is the GUID identifying the client for acknowledgements, defaults to a random GUID.
+	Channel string `json:",omitempty" jsonschema:"title=Channel,example=FE0ECFAD-13D5-401B-847D-77833BD77131"`
+	// AckTimeout is the max time to wait for the acknowledgement of a batch as a Go duration.
+	AckTimeout string `json:",omitempty" jsonschema:"title=Acknowledgement Timeout,example=1m,default=30s"`
+	// Headers added to each request.
+	Headers map[string]string `json:",omitempty" jsonschema:"title=Headers"`
+	// Gzip compresses the request bodies.
+	Gzip bool `json:",omitempty" jsonschema:"title=Gzip,example=true,default=false"`
+	// Timeout of each request as a Go duration.
+	Timeout string `json:",omitempty" jsonschema:"title=Timeout,example=30s,default=10s"`
+	// Batch defines how the records are grouped and retried.
+	Batch   BatchOptions `json:",omitempty"`
+	batcher *batcher[[]byte]
+}
+
+func (so *SplunkOutput) enabled() bool {
+	return !so.Disable && so.URL != ""
+}
+
+func (so *SplunkOutput) Validate() (err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: validate config [splunk output]: %w", err)
+		}
+	}()
+
+	if !so.enabled() {
+		return nil
+	}
+	if so.Format == "" {
+		so.Format = FormatJSON
+	}
+	if err = so.OutputHandler.Validate(); err != nil {
+		return err
+	}
+	if so.Format == FormatMsgPack {
+		return fmt.Errorf("invalid format [%s], only text based formats are supported", so.Format)
+	}
+	if err = validateHTTPURL(so.URL); err != nil {
+		return err
+	}
+	if so.Token == "" {
+		return fmt.Errorf("missing Token")
+	}
+	for name, value := range map[string]string{"AckTimeout": so.AckTimeout, "Timeout": so.Timeout} {
+		if _, err = parseDuration(value, 0); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+
+	return so.Batch.Validate()
+}
+
+func (so *SplunkOutput) GetHandler() (handler slog.Handler, err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: get handler [splunk output]: %w", err)
+		}
+	}()
+
+	if !so.enabled() {
+		return nil, fmt.Errorf("[%s] %w", so.URL, ErrHandlerDisabled)
+	}
+
+	encoder, err := so.formatEncoder()
+	if err != nil {
+		return nil, err
+	}
+
+	if so.batcher == nil {
+		sender, err := so.sender()
+		if err != nil {
+			return nil, err
+		}
+		settings, err := so.Batch.settings()
+		if err != nil {
+			return nil, err
+		}
+		so.batcher = newBatcher(settings, sender.send)
+	}
+
+	writer := &splunkWriter{
+		metadata: map[string]any{
+			"host":       so.Host,
+			"source":     so.Source,
+			"sourcetype": so.SourceType,
+		},
+		fields:     so.Fields,
+		encode:     encoder,
+		jsonEvents: so.Format.jsonLines(),
+		batcher:    so.batcher,
+	}
+	if so.Host == "" {
+		writer.metadata["host"] = so.Resource["host.name"]
+		if writer.metadata["host"] == "" {
+			writer.metadata["host"], _ = os.Hostname()
+		}
+	}
+	if so.Source == "" {
+		writer.metadata["source"] = filepath.Base(os.Args[0])
+	}
+	if so.SourceType == "" {
+		if writer.jsonEvents {
+			writer.metadata["sourcetype"] = "_json"
+		} else {
+			delete(writer.metadata, "sourcetype")
+		}
+	}
+	if so.Index != "" {
+		writer.metadata["index"] = so.Index
+	}
+
+	handler, err = so.entryHandler(writer)
+	if err != nil {
+		return nil, fmt.Errorf("getting handler [splunk]: %w", err)
+	}
+
+	return handler, nil
+}
+
+// sender returns the splunkSender with the endpoints, headers and timeouts of the output.
+func (so *SplunkOutput) sender() (*splunkSender, error) {
+	eventURL, err := url.Parse(so.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL [%s]: %w", so.URL, err)
+	}
+	if eventURL.Path == "" || eventURL.Path == "/" {
+		eventURL.Path = splunkEventPath
+	}
+	ackURL := *eventURL
+	ackURL.Path = splunkAckURLPath(eventURL.Path)
+	ackURL.RawPath = ""
+
+	timeout, err := parseDuration(so.Timeout, DefaultHTTPTimeout)
+	if err != nil {
+		return nil, err
+	}
+	ackTimeout, err := parseDuration(so.AckTimeout, DefaultSplunkAckTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	channel := so.Channel
+	if channel == "" {
+		channel = randomGUID()
+	}
+
+	headers := maps.Clone(so.Headers)
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	headers["Authorization"] = "Splunk " + so.Token
+	headers[splunkChannelHeader] = channel
+
+	return &splunkSender{
+		client:     &http.Client{Timeout: timeout},
+		eventURL:   eventURL.String(),
+		ackURL:     ackURL.String(),
+		headers:    headers,
+		compress:   so.Gzip,
+		ack:        so.Ack,
+		ackTimeout: ackTimeout,
+	}, nil
+}
+
+// Stats returns the delivery statistics of the output.
+func (so *SplunkOutput) Stats() BatchStats {
+	if so.batcher == nil {
+		return BatchStats{}
+	}
+
+	return so.batcher.stats()
+}
+
+// Close sends the pending records and stops the output.
+func (so *SplunkOutput) Close() error {
+	if so.batcher == nil {
+		return nil
+	}
+
+	return so.batcher.Close()
+}
+
+// splunkAckURLPath returns the path of the acknowledgement endpoint next to the event endpoint, keeping the prefix of
+// a proxy such as /splunk/services/collector/event. A path without the collector endpoints has its last segment
+// replaced by ack.
+func splunkAckURLPath(eventPath string) string {
+	if i := strings.LastIndex(eventPath, "/services/collector"); i >= 0 {
+		return eventPath[:i] + splunkAckPath
+	}
+
+	return path.Join(path.Dir(strings.TrimSuffix(eventPath, "/")), "ack")
+}
+
+// randomGUID returns a random version 4 UUID in uppercase, as Splunk shows its channels.
+func randomGUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%X-%X-%X-%X-%X", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// splunkWriter wraps the encoded entries in the HEC event envelope.
+type splunkWriter struct {
+	metadata   map[string]any
+	fields     []string
+	encode     entryEncoder
+	jsonEvents bool
+	batcher    *batcher[[]byte]
+}
+
+func (sw *splunkWriter) writeEntry(e *entry) error {
+	envelope := maps.Clone(sw.metadata)
+	envelope["time"] = json.Number(strconv.FormatFloat(float64(e.Time.UnixMicro())/1e6, 'f', 6, 64))
+
+	event := *e
+	fields := map[string]any{}
+	for _, path := range sw.fields {
+		attrs, value, found := takeAttr(event.Attrs, path)
+		if found {
+			event.Attrs = attrs
+			// The HEC only accepts strings as indexed field values.
+			fields[path] = fmt.Sprint(attrValue(value))
+		}
+	}
+	if len(fields) > 0 {
+		envelope["fields"] = fields
+	}
+
+	buf := &bytes.Buffer{}
+	if err := sw.encode(buf, &event); err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+	line := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+	if sw.jsonEvents {
+		envelope["event"] = json.RawMessage(line)
+	} else {
+		envelope["event"] = string(line)
+	}
+
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+
+	return sw.batcher.add(data)
+}
+
+// splunkSender sends the batches of events to the HEC, waiting for the indexer acknowledgement if enabled.
+type splunkSender struct {
+	client     *http.Client
+	eventURL   string
+	ackURL     string
+	headers    map[string]string
+	compress   bool
+	ack        bool
+	ackTimeout time.Duration
+}
+
+func (ss *splunkSender) send(batch [][]byte) error {
+	body := bytes.Join(batch, []byte("\n"))
+	if !ss.ack {
+		return sendHTTP(ss.client, http.MethodPost, ss.eventURL, "application/json", ss.headers, body, ss.compress)
+	}
+
+	response := &struct {
+		AckID *int64 `json:"ackId"`
+	}{}
+	if err := sendHTTPResponse(ss.client, http.MethodPost, ss.eventURL, "application/json", ss.headers, body, ss.compress, response); err != nil {
+		return err
+	}
+	if response.AckID == nil {
+		return &batchError{err: fmt.Errorf("no ackId in response, indexer acknowledgement is disabled for the token"), permanent: true}
+	}
+
+	return ss.waitForAck(*response.AckID)
+}
+
+// waitForAck polls the acknowledgement endpoint until the batch was indexed or the timeout expired.
+func (ss *splunkSender) waitForAck(ackID int64) error {
+	request, err := json.Marshal(map[string][]int64{"acks": {ackID}})
+	if err != nil {
+		return &batchError{err: err, permanent: true}
+	}
+
+	deadline := time.Now().Add(ss.ackTimeout)
+	interval := splunkAckPollInterval
+	for {
+		time.Sleep(interval)
+		interval = min(interval*2, time.Second)
+
+		response := &struct {
+			Acks map[string]bool `json:"acks"`
+		}{}
+		err = sendHTTPResponse(ss.client, http.MethodPost, ss.ackURL, "application/json", ss.headers, request, false, response)
+		if err == nil && response.Acks[strconv.FormatInt(ackID, 10)] {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			if err == nil {
+				err = fmt.Errorf("batch [%d] not acknowledged within %s", ackID, ss.ackTimeout)
+			}
+			// Retrying an unacknowledged batch may duplicate its events, which is preferred over losing them.
+			return &batchError{err: err}
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package nmcslog
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeHEC is an HTTP Event Collector stand-in acknowledging each batch on the second poll.
+type fakeHEC struct {
+	mu      sync.Mutex
+	events  []map[string]any
+	auth    string
+	channel string
+	polls   int
+	// prefix of the endpoints, as added by a proxy.
+	prefix string
+	// plain responds to the events with a body that is not JSON, as some proxies do.
+	plain bool
+}
+
+func (fh *fakeHEC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+	fh.auth = r.Header.Get("Authorization")
+	fh.channel = r.Header.Get(splunkChannelHeader)
+
+	switch strings.TrimPrefix(r.URL.Path, fh.prefix) {
+	case splunkEventPath:
+		decoder := json.NewDecoder(r.Body)
+		for {
+			event := map[string]any{}
+			if err := decoder.Decode(&event); err == io.EOF {
+				break
+			} else if err != nil {
+				http.Error(w, `{"text":"Invalid data format","code":6}`, http.StatusBadRequest)
+				return
+			}
+			if fields, ok := event["fields"].(map[string]any); ok {
+				for _, value := range fields {
+					if _, ok = value.(string); !ok {
+						http.Error(w, `{"text":"Error in handling indexed fields","code":15}`, http.StatusBadRequest)
+						return
+					}
+				}
+			}
+			fh.events = append(fh.events, event)
+		}
+		if fh.plain {
+			_, _ = io.WriteString(w, "OK")
+			return
+		}
+		_, _ = io.WriteString(w, `{"text":"Success","code":0,"ackId":7}`)
+	case splunkAckPath:
+		fh.polls++
+		_, _ = io.WriteString(w, `{"acks":{"7":`+strconv.FormatBool(fh.polls > 1)+`}}`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestSplunkOutput(t *testing.T) {
+	hec := &fakeHEC{}
+	server := httptest.NewServer(hec)
+	defer server.Close()
+
+	config := Config{
+		Console: ConsoleOutput{OutputHandler: OutputHandler{Disable: true}},
+		File:    FileOutput{OutputHandler: OutputHandler{Disable: true}},
+		Splunk: SplunkOutput{
+			URL:    server.URL,
+			Token:  "secret",
+			Index:  "main",
+			Host:   "web-01",
+			Source: "api",
+			Fields: []string{"user.id", "region"},
+			Ack:    true,
+			Batch:  BatchOptions{Age: "1h"},
+		},
+	}
+	logger, err := GetConfiguredLogger(&config)
+	if err != nil {
+		t.Fatalf("GetConfiguredLogger() error = %v", err)
+	}
+
+	logger.Info("login", "region", "eu", slog.Group("user", "id", 42, "name", "ada"))
+	logger.Warn("plain")
+	if err = config.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	hec.mu.Lock()
+	defer hec.mu.Unlock()
+
+	if hec.auth != "Splunk secret" {
+		t.Errorf("Authorization = %q", hec.auth)
+	}
+	if !regexp.MustCompile(`^[0-9A-F]{8}-[0-9A-F]{4}-4[0-9A-F]{3}-[89AB][0-9A-F]{3}-[0-9A-F]{12}$`).MatchString(hec.channel) {
+		t.Errorf("channel = %q, want a random GUID", hec.channel)
+	}
+	if hec.polls != 2 {
+		t.Errorf("ack polls = %d, want 2", hec.polls)
+	}
+	if len(hec.events) != 2 {
+		t.Fatalf("events = %v, want 2", hec.events)
+	}
+
+	login := hec.events[0]
+	for key, want := range map[string]any{"index": "main", "host": "web-01", "source": "api", "sourcetype": "_json"} {
+		if login[key] != want {
+			t.Errorf("%s = %v, want %v", key, login[key], want)
+		}
+	}
+	if _, ok := login["time"].(float64); !ok {
+		t.Errorf("time = %v, want epoch seconds", login["time"])
+	}
+	fields, _ := login["fields"].(map[string]any)
+	if fields["user.id"] != "42" || fields["region"] != "eu" {
+		t.Errorf("fields = %v", login["fields"])
+	}
+	event, _ := login["event"].(map[string]any)
+	if event["msg"] != "login" || event["region"] != nil || event["user"].(map[string]any)["name"] != "ada" {
+		t.Errorf("event = %v", login["event"])
+	}
+	if _, ok := hec.events[1]["fields"]; ok {
+		t.Errorf("fields = %v, want none", hec.events[1]["fields"])
+	}
+
+	want := BatchStats{Records: 2, Batches: 1}
+	if stats := config.Splunk.Stats(); stats != want {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+}
+
+func TestSplunkOutput_TextEvents(t *testing.T) {
+	hec := &fakeHEC{prefix: "/splunk"}
+	server := httptest.NewServer(hec)
+	defer server.Close()
+
+	config := Config{
+		Console: ConsoleOutput{OutputHandler: OutputHandler{Disable: true}},
+		File:    FileOutput{OutputHandler: OutputHandler{Disable: true}},
+		Splunk: SplunkOutput{
+			OutputHandler: OutputHandler{Format: FormatText},
+			URL:           server.URL + "/splunk" + splunkEventPath,
+			Token:         "secret",
+			Channel:       "FE0ECFAD-13D5-401B-847D-77833BD77131",
+			Ack:           true,
+			Batch:         BatchOptions{Age: "1h"},
+		},
+	}
+	logger, err := GetConfiguredLogger(&config)
+	if err != nil {
+		t.Fatalf("GetConfiguredLogger() error = %v", err)
+	}
+
+	logger.Info("hello", "key", "value")
+	if err = config.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	hec.mu.Lock()
+	defer hec.mu.Unlock()
+
+	if hec.channel != "FE0ECFAD-13D5-401B-847D-77833BD77131" || hec.polls != 2 {
+		t.Errorf("channel = %q, polls = %d", hec.channel, hec.polls)
+	}
+	if len(hec.events) != 1 {
+		t.Fatalf("events = %v, want 1", hec.events)
+	}
+	line, _ := hec.events[0]["event"].(string)
+	if !strings.Contains(line, "msg=hello") || !strings.Contains(line, "key=value") {
+		t.Errorf("event = %q", line)
+	}
+	if _, ok := hec.events[0]["sourcetype"]; ok {
+		t.Errorf("sourcetype = %v, want the token default", hec.events[0]["sourcetype"])
+	}
+}
+
+func TestSplunkOutput_WithoutAck(t *testing.T) {
+	hec := &fakeHEC{plain: true}
+	server := httptest.NewServer(hec)
+	defer server.Close()
+
+	output := SplunkOutput{URL: server.URL, Token: "secret", Batch: BatchOptions{Age: "1h"}}
+	if err := output.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	handler, err := output.GetHandler()
+	if err != nil {
+		t.Fatalf("GetHandler() error = %v", err)
+	}
+	slog.New(handler).Info("hello")
+	if err = output.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	want := BatchStats{Records: 1, Batches: 1}
+	if stats := output.Stats(); stats != want {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+}
+
+func TestSplunkAckURLPath(t *testing.T) {
+	for eventPath, want := range map[string]string{
+		splunkEventPath:                        splunkAckPath,
+		"/services/collector":                  splunkAckPath,
+		"/splunk/services/collector/event/1.0": "/splunk" + splunkAckPath,
+		"/hec/event/":                          "/hec/ack",
+	} {
+		if got := splunkAckURLPath(eventPath); got != want {
+			t.Errorf("splunkAckURLPath(%q) = %q, want %q", eventPath, got, want)
+		}
+	}
+}
+
+func TestSplunkOutput_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  SplunkOutput
+		wantErr bool
+	}{
+		{name: "disabled without URL", output: SplunkOutput{AckTimeout: "invalid"}},
+		{name: "defaults", output: SplunkOutput{URL: "https://splunk:8088", Token: "secret"}},
+		{name: "missing token", output: SplunkOutput{URL: "https://splunk:8088"}, wantErr: true},
+		{name: "invalid ack timeout", output: SplunkOutput{URL: "https://splunk:8088", Token: "secret", AckTimeout: "soon"}, wantErr: true},
+		{name: "msgpack format", output: SplunkOutput{URL: "https://splunk:8088", Token: "secret", OutputHandler: OutputHandler{Format: FormatMsgPack}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.output.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,416 @@
+package nmcslog
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// SyslogRFC5424 sends the records following the current syslog protocol of RFC 5424.
+	SyslogRFC5424 = "RFC5424"
+	// SyslogRFC3164 sends the records following the legacy BSD syslog protocol of RFC 3164.
+	SyslogRFC3164 = "RFC3164"
+
+	// SyslogFramingOctetCounting prefixes each message on a stream connection with its length, as defined by RFC 6587.
+	SyslogFramingOctetCounting = "OCTET-COUNTING"
+	// SyslogFramingNewline terminates each message on a stream connection with a newline, the newlines within the
+	// message are escaped as #012.
+	SyslogFramingNewline = "NEWLINE"
+
+	// DefaultSyslogFacility is the facility of the records if none is configured.
+	DefaultSyslogFacility = "user"
+	// DefaultSyslogStructuredDataID is the SD-ID of the attributes, 32473 is the enterprise number reserved for
+	// documentation by RFC 5612.
+	DefaultSyslogStructuredDataID = "attrs@32473"
+
+	syslogVersion    = "1"
+	syslogNil        = "-"
+	syslogTimeLayout = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// syslogFacilities maps the facility names onto their codes.
+var syslogFacilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"ntp":      12,
+	"security": 13,
+	"console":  14,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+var syslogParamEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// SyslogOutput defines the settings specific to the syslog output.
+type SyslogOutput struct {
+	OutputHandler
+	// Address of the syslog server as host:port, or the path of a local socket such as /dev/log.
+	// If not provided, syslog logging will be disabled.
+	Address string `json:",omitempty" jsonschema:"title=Syslog Address,example=syslog.example.com:514,example=/dev/log"`
+	// Network to send the messages over, either udp, tcp, unix or unixgram.
+	// Defaults to udp, or for a socket path to unixgram with a fallback to unix.
+	Network string `json:",omitempty" jsonschema:"title=Network,enum=udp,enum=tcp,enum=unix,enum=unixgram"`
+	// Protocol of the messages, either RFC5424 (default) or RFC3164.
+	Protocol string `json:",omitempty" jsonschema:"title=Protocol,enum=RFC5424,enum=RFC3164,default=RFC5424"`
+	// Framing of the messages on tcp and unix connections, either OCTET-COUNTING (default for tcp) or NEWLINE (default for unix).
+	Framing string `json:",omitempty" jsonschema:"title=Framing,enum=OCTET-COUNTING,enum=NEWLINE"`
+	// Facility of the records, such as daemon or local0.
+	Facility string `json:",omitempty" jsonschema:"title=Facility,example=local0,default=user"`
+	// AppName identifies the application, defaults to the executable name.
+	AppName string `json:",omitempty" jsonschema:"title=App Name,example=api"`
+	// Hostname of the messages, defaults to the host.name resource attribute or else the hostname.
+	Hostname string `json:",omitempty" jsonschema:"title=Hostname,example=web-01"`
+	// StructuredDataID is the SD-ID of the RFC5424 structured data element holding the attributes.
+	StructuredDataID string `json:",omitempty" jsonschema:"title=Structured Data ID,example=app@32473,default=attrs@32473"`
+	writer           *syslogWriter
+}
+
+func (so *SyslogOutput) enabled() bool {
+	return !so.Disable && so.Address != ""
+}
+
+func (so *SyslogOutput) Validate() (err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: validate config [syslog output]: %w", err)
+		}
+	}()
+
+	if !so.enabled() {
+		return nil
+	}
+	// The records are always written in the syslog protocol, with the attributes as text.
+	if so.Format == "" {
+		so.Format = FormatText
+	}
+	if err = so.OutputHandler.Validate(); err != nil {
+		return err
+	}
+	if so.Format != FormatText {
+		return fmt.Errorf("invalid format [%s], only %s is supported", so.Format, FormatText)
+	}
+	switch strings.ToLower(so.Network) {
+	case "", "udp", "tcp", "unix", "unixgram":
+	default:
+		return fmt.Errorf("invalid network [%s]", so.Network)
+	}
+	switch strings.ToUpper(so.Protocol) {
+	case "", SyslogRFC5424, SyslogRFC3164:
+	default:
+		return fmt.Errorf("invalid protocol [%s]", so.Protocol)
+	}
+	switch strings.ToUpper(so.Framing) {
+	case "", SyslogFramingOctetCounting, SyslogFramingNewline:
+	default:
+		return fmt.Errorf("invalid framing [%s]", so.Framing)
+	}
+	if _, err = so.facility(); err != nil {
+		return err
+	}
+	if so.StructuredDataID != "" && syslogName(so.StructuredDataID, 32) != so.StructuredDataID {
+		return fmt.Errorf("invalid StructuredDataID [%s]", so.StructuredDataID)
+	}
+
+	return nil
+}
+
+func (so *SyslogOutput) facility() (int, error) {
+	name := strings.ToLower(so.Facility)
+	if name == "" {
+		name = DefaultSyslogFacility
+	}
+
+	facility, exists := syslogFacilities[name]
+	if !exists {
+		return 0, fmt.Errorf("invalid facility [%s]", so.Facility)
+	}
+
+	return facility, nil
+}
+
+func (so *SyslogOutput) GetHandler() (handler slog.Handler, err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: get handler [syslog output]: %w", err)
+		}
+	}()
+
+	if !so.enabled() {
+		return nil, fmt.Errorf("[%s] %w", so.Address, ErrHandlerDisabled)
+	}
+
+	facility, err := so.facility()
+	if err != nil {
+		return nil, err
+	}
+
+	header := syslogHeader{
+		facility: facility,
+		hostname: so.Hostname,
+		appName:  so.AppName,
+		procID:   strconv.Itoa(os.Getpid()),
+		sdID:     so.StructuredDataID,
+	}
+	if header.hostname == "" {
+		header.hostname = so.Resource["host.name"]
+	}
+	if header.hostname == "" {
+		header.hostname, _ = os.Hostname()
+	}
+	if header.appName == "" {
+		header.appName = filepath.Base(os.Args[0])
+	}
+	if header.sdID == "" {
+		header.sdID = DefaultSyslogStructuredDataID
+	}
+
+	if so.writer == nil {
+		so.writer = &syslogWriter{
+			network: strings.ToLower(so.Network),
+			address: so.Address,
+			framing: strings.ToUpper(so.Framing),
+		}
+		if so.writer.network == "" {
+			so.writer.network = "udp"
+			if filepath.IsAbs(so.Address) {
+				so.writer.network = "unixgram"
+				so.writer.streamFallback = true
+			}
+		}
+		if so.writer.framing == "" {
+			so.writer.framing = SyslogFramingNewline
+			if so.writer.network == "tcp" {
+				so.writer.framing = SyslogFramingOctetCounting
+			}
+		}
+	}
+
+	encoder := encodeRFC5424(header)
+	if strings.ToUpper(so.Protocol) == SyslogRFC3164 {
+		encoder = encodeRFC3164(header)
+	}
+
+	handler, err = so.entryHandler(newEncodedWriter(so.writer, encoder))
+	if err != nil {
+		return nil, fmt.Errorf("getting handler [syslog]: %w", err)
+	}
+
+	return handler, nil
+}
+
+// Close closes the connection to the syslog server.
+func (so *SyslogOutput) Close() error {
+	if so.writer == nil {
+		return nil
+	}
+
+	return so.writer.Close()
+}
+
+// syslogHeader holds the values of the message header that are the same for every record.
+type syslogHeader struct {
+	facility int
+	hostname string
+	appName  string
+	procID   string
+	sdID     string
+}
+
+func (sh syslogHeader) priority(level slog.Level) string {
+	return "<" + strconv.Itoa(sh.facility*8+syslogSeverity(level)) + ">"
+}
+
+// syslogName returns the value limited to printable US-ASCII without spaces, the characters not allowed in an
+// SD-NAME are replaced as well, and truncated to the max length.
+func syslogName(value string, maxLength int) string {
+	name := []byte(value)
+	for i, c := range name {
+		if c < 33 || c > 126 || c == '=' || c == ']' || c == '"' {
+			name[i] = '_'
+		}
+	}
+	if len(name) > maxLength {
+		name = name[:maxLength]
+	}
+
+	return string(name)
+}
+
+// syslogHeaderField returns the header field limited to printable US-ASCII and the max length, or the nil value if empty.
+func syslogHeaderField(value string, maxLength int) string {
+	if value == "" {
+		return syslogNil
+	}
+	field := []byte(value)
+	for i, c := range field {
+		if c < 33 || c > 126 {
+			field[i] = '_'
+		}
+	}
+	if len(field) > maxLength {
+		field = field[:maxLength]
+	}
+
+	return string(field)
+}
+
+// encodeRFC5424 returns an entryEncoder writing the entry as an RFC 5424 message, the attributes and the source
+// are added as the parameters of a single structured data element with the keys of nested groups joined by a dot.
+func encodeRFC5424(header syslogHeader) entryEncoder {
+	hostname := syslogHeaderField(header.hostname, 255)
+	appName := syslogHeaderField(header.appName, 48)
+	procID := syslogHeaderField(header.procID, 128)
+
+	return func(buf *bytes.Buffer, e *entry) error {
+		buf.WriteString(header.priority(e.Level))
+		buf.WriteString(syslogVersion)
+		buf.WriteByte(' ')
+		if e.Time.IsZero() {
+			buf.WriteString(syslogNil)
+		} else {
+			buf.WriteString(e.Time.Format(syslogTimeLayout))
+		}
+		buf.WriteString(" " + hostname + " " + appName + " " + procID + " " + syslogNil + " ")
+
+		var params []string
+		flattenAttrs(e.Attrs, ".", func(key string, value slog.Value) {
+			params = append(params, syslogName(key, 32)+`="`+syslogParamEscaper.Replace(fmt.Sprint(attrValue(value)))+`"`)
+		})
+		if e.Source != nil {
+			params = append(params, slog.SourceKey+`="`+syslogParamEscaper.Replace(e.Source.File+":"+strconv.Itoa(e.Source.Line))+`"`)
+		}
+		if len(params) == 0 {
+			buf.WriteString(syslogNil)
+		} else {
+			buf.WriteString("[" + header.sdID + " " + strings.Join(params, " ") + "]")
+		}
+
+		if e.Message != "" {
+			buf.WriteByte(' ')
+			buf.WriteString(e.Message)
+		}
+
+		return nil
+	}
+}
+
+// encodeRFC3164 returns an entryEncoder writing the entry as an RFC 3164 message, the attributes are appended to
+// the message as key=value pairs with the keys of nested groups joined by a dot.
+func encodeRFC3164(header syslogHeader) entryEncoder {
+	hostname := syslogHeaderField(header.hostname, 255)
+	tag := syslogHeaderField(header.appName, 32) + "[" + header.procID + "]:"
+
+	return func(buf *bytes.Buffer, e *entry) error {
+		buf.WriteString(header.priority(e.Level))
+		buf.WriteString(e.Time.Format(time.Stamp))
+		buf.WriteString(" " + hostname + " " + tag + " ")
+		buf.WriteString(e.Message)
+
+		flattenAttrs(e.Attrs, ".", func(key string, value slog.Value) {
+			buf.WriteString(" " + key + "=" + quoteValue(fmt.Sprint(attrValue(value))))
+		})
+		if e.Source != nil {
+			buf.WriteString(" " + slog.SourceKey + "=" + quoteValue(e.Source.File+":"+strconv.Itoa(e.Source.Line)))
+		}
+
+		return nil
+	}
+}
+
+// syslogWriter sends each written message to a syslog server, connecting on first use and reconnecting after a failure.
+type syslogWriter struct {
+	mu      sync.Mutex
+	network string
+	address string
+	framing string
+	// streamFallback retries a unix stream socket if the unixgram socket cannot be connected.
+	streamFallback bool
+	conn           net.Conn
+}
+
+func (sw *syslogWriter) Write(p []byte) (n int, err error) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	// Retry once on a new connection in case the previous one went stale.
+	for attempt := 0; attempt < 2; attempt++ {
+		if sw.conn == nil {
+			if sw.conn, err = sw.dial(); err != nil {
+				return 0, fmt.Errorf("connecting to syslog [%s://%s]: %w", sw.network, sw.address, err)
+			}
+		}
+
+		if _, err = sw.conn.Write(sw.frame(p)); err == nil {
+			return len(p), nil
+		}
+
+		_ = sw.conn.Close()
+		sw.conn = nil
+	}
+
+	return 0, fmt.Errorf("sending syslog message [%s://%s]: %w", sw.network, sw.address, err)
+}
+
+// dial connects to the syslog server, falling back to a stream socket if the local socket does not accept datagrams.
+func (sw *syslogWriter) dial() (net.Conn, error) {
+	conn, err := net.DialTimeout(sw.network, sw.address, DefaultDialTimeout)
+	if err != nil && sw.streamFallback {
+		return net.DialTimeout("unix", sw.address, DefaultDialTimeout)
+	}
+
+	return conn, err
+}
+
+// frame returns the message as sent over the connection, datagrams are sent as is.
+func (sw *syslogWriter) frame(message []byte) []byte {
+	switch sw.conn.LocalAddr().Network() {
+	case "udp", "unixgram":
+		return message
+	}
+
+	if sw.framing == SyslogFramingOctetCounting {
+		return append([]byte(strconv.Itoa(len(message))+" "), message...)
+	}
+
+	// A newline within the message would end the frame, it is escaped as rsyslog does for control characters.
+	message = bytes.ReplaceAll(message, []byte{'\n'}, []byte("#012"))
+
+	return append(message, '\n')
+}
+
+func (sw *syslogWriter) Close() error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	if sw.conn == nil {
+		return nil
+	}
+	err := sw.conn.Close()
+	sw.conn = nil
+
+	return err
+}
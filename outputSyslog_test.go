@@ -0,0 +1,264 @@
+package nmcslog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeSyslog(t *testing.T) {
+	header := syslogHeader{facility: 16, hostname: "web 01", appName: "api", procID: "42", sdID: DefaultSyslogStructuredDataID}
+	e := &entry{
+		Time:    time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.UTC),
+		Level:   LevelWarn,
+		Message: "disk almost full",
+		Source:  &slog.Source{File: "main.go", Line: 12},
+		Attrs: []slog.Attr{
+			slog.Group("disk", slog.String("path", `/var/"log"]`)),
+			slog.Int("free", 5),
+		},
+	}
+
+	tests := []struct {
+		name   string
+		encode entryEncoder
+		want   string
+	}{
+		{
+			name:   "RFC5424",
+			encode: encodeRFC5424(header),
+			want: `<132>1 2024-05-06T07:08:09.123456Z web_01 api 42 - ` +
+				`[attrs@32473 disk.path="/var/\"log\"\]" free="5" source="main.go:12"] disk almost full`,
+		},
+		{
+			name:   "RFC3164",
+			encode: encodeRFC3164(header),
+			want:   `<132>May  6 07:08:09 web_01 api[42]: disk almost full disk.path="/var/\"log\"]" free=5 source=main.go:12`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			if err := tt.encode(buf, e); err != nil {
+				t.Fatalf("encode() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("encode() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+
+	buf := &bytes.Buffer{}
+	_ = encodeRFC5424(header)(buf, &entry{Level: LevelTrace})
+	if got := buf.String(); got != "<135>1 - web_01 api 42 - -" {
+		t.Errorf("encode() without time and attributes = %q", got)
+	}
+}
+
+func TestSyslogOutput_UDP(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	defer listener.Close()
+
+	config := Config{
+		Console: ConsoleOutput{OutputHandler: OutputHandler{Disable: true}},
+		File:    FileOutput{OutputHandler: OutputHandler{Disable: true}},
+		Syslog: SyslogOutput{
+			OutputHandler: OutputHandler{LogLevel: LogLevel{Level: "TRACE"}},
+			Address:       listener.LocalAddr().String(),
+			Facility:      "daemon",
+			AppName:       "worker",
+			Hostname:      "host",
+		},
+	}
+	logger, err := GetConfiguredLogger(&config)
+	if err != nil {
+		t.Fatalf("GetConfiguredLogger() error = %v", err)
+	}
+	defer config.Close()
+
+	logger.Log(context.Background(), LevelFatal, "giving up", "attempts", 3)
+
+	buf := make([]byte, 65535)
+	_ = listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := listener.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("reading datagram: %v", err)
+	}
+
+	// daemon (3) * 8 + critical (2)
+	want := "<26>1 "
+	if got := string(buf[:n]); !strings.HasPrefix(got, want) ||
+		!strings.HasSuffix(got, ` host worker `+strconv.Itoa(os.Getpid())+` - [attrs@32473 attempts="3"] giving up`) {
+		t.Errorf("message = %q", got)
+	}
+}
+
+func TestSyslogOutput_TCPReconnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	defer listener.Close()
+
+	messages := make(chan string, 4)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			// Read a single octet counted message per connection, then drop the connection.
+			reader := bufio.NewReader(conn)
+			length, err := reader.ReadString(' ')
+			if err == nil {
+				size, _ := strconv.Atoi(strings.TrimSpace(length))
+				message := make([]byte, size)
+				if _, err = io.ReadFull(reader, message); err == nil {
+					messages <- string(message)
+				}
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	output := SyslogOutput{
+		Address:  listener.Addr().String(),
+		Network:  "tcp",
+		Protocol: SyslogRFC3164,
+	}
+	if err := output.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	handler, err := output.GetHandler()
+	if err != nil {
+		t.Fatalf("GetHandler() error = %v", err)
+	}
+	defer output.Close()
+	logger := slog.New(handler)
+
+	for _, msg := range []string{"first", "second"} {
+		// The write following a dropped connection can succeed locally, retry until the server received it.
+		deadline := time.Now().Add(5 * time.Second)
+		for received := false; !received; {
+			logger.Info(msg)
+			select {
+			case got := <-messages:
+				if !strings.HasSuffix(got, ": "+msg) {
+					t.Errorf("message = %q, want the %s message", got, msg)
+				}
+				received = true
+			case <-time.After(100 * time.Millisecond):
+				if time.Now().After(deadline) {
+					t.Fatalf("%s message not received", msg)
+				}
+			}
+		}
+	}
+}
+
+func TestSyslogOutput_UnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	listener, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	defer listener.Close()
+
+	output := SyslogOutput{Address: path}
+	if err := output.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	handler, err := output.GetHandler()
+	if err != nil {
+		t.Fatalf("GetHandler() error = %v", err)
+	}
+	defer output.Close()
+	slog.New(handler).Error("failed", "code", 500)
+
+	buf := make([]byte, 65535)
+	_ = listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := listener.Read(buf)
+	if err != nil {
+		t.Fatalf("reading datagram: %v", err)
+	}
+	if got := string(buf[:n]); !strings.HasPrefix(got, "<11>1 ") || !strings.HasSuffix(got, `[attrs@32473 code="500"] failed`) {
+		t.Errorf("message = %q", got)
+	}
+}
+
+func TestSyslogOutput_NewlineFraming(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	defer listener.Close()
+
+	output := SyslogOutput{Network: "unix", Address: path}
+	if err := output.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	handler, err := output.GetHandler()
+	if err != nil {
+		t.Fatalf("GetHandler() error = %v", err)
+	}
+	defer output.Close()
+	logger := slog.New(handler)
+	logger.Error("first\nline", "stack", "a\nb")
+	logger.Error("second")
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("accepting: %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	reader := bufio.NewReader(conn)
+	for _, want := range []string{`stack="a#012b"] first#012line`, "- - second"} {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading frame: %v", err)
+		}
+		if !strings.HasPrefix(line, "<11>1 ") || !strings.HasSuffix(line, want+"\n") {
+			t.Errorf("frame = %q, want suffix %q", line, want)
+		}
+	}
+}
+
+func TestSyslogOutput_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  SyslogOutput
+		wantErr bool
+	}{
+		{name: "disabled without address", output: SyslogOutput{Facility: "invalid"}},
+		{name: "defaults", output: SyslogOutput{Address: "localhost:514"}},
+		{name: "invalid facility", output: SyslogOutput{Address: "localhost:514", Facility: "invalid"}, wantErr: true},
+		{name: "invalid network", output: SyslogOutput{Address: "localhost:514", Network: "sctp"}, wantErr: true},
+		{name: "invalid protocol", output: SyslogOutput{Address: "localhost:514", Protocol: "RFC1"}, wantErr: true},
+		{name: "invalid framing", output: SyslogOutput{Address: "localhost:514", Framing: "NULL"}, wantErr: true},
+		{name: "invalid SD-ID", output: SyslogOutput{Address: "localhost:514", StructuredDataID: "my id"}, wantErr: true},
+		{name: "invalid format", output: SyslogOutput{Address: "localhost:514", OutputHandler: OutputHandler{Format: FormatJSON}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.output.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package nmcslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultWebhookTemplate is the Template of the alert text if none is configured, using the Slack and
+	// Mattermost markdown.
+	DefaultWebhookTemplate = "*{{.Level}}* {{.Message}}{{range .Attrs}} `{{.Key}}={{.Value}}`{{end}}"
+	// DefaultWebhookDedupWindow is the default time similar alerts are suppressed after an alert was sent.
+	DefaultWebhookDedupWindow = 5 * time.Minute
+	// DefaultWebhookMaxPerMinute is the default max number of alerts sent per minute.
+	DefaultWebhookMaxPerMinute = 10
+)
+
+// WebhookOutput defines the settings specific to the chat webhook output, posting alerts to Slack or Mattermost
+// compatible incoming webhooks. The records are WARN and above by default, similar records are deduplicated and
+// the alerts are rate limited, the number of suppressed records is added to the next alert.
+type WebhookOutput struct {
+	OutputHandler
+	// URL of the incoming webhook. If not provided, webhook alerting will be disabled.
+	URL string `json:",omitempty" jsonschema:"title=Webhook URL,example=https://hooks.slack.com/services/T000/B000/XXXX"`
+	// Channel overrides the default channel of the webhook, if it allows to.
+	Channel string `json:",omitempty" jsonschema:"title=Channel,example=#alerts"`
+	// Username overrides the default name the alerts are posted as, if the webhook allows to.
+	Username string `json:",omitempty" jsonschema:"title=Username,example=api"`
+	// DedupWindow is the time records with the same level and message are suppressed after an alert, as a Go duration.
+	DedupWindow string `json:",omitempty" jsonschema:"title=Deduplication Window,example=15m,default=5m"`
+	// MaxPerMinute is the max number of alerts sent per minute, further alerts are suppressed.
+	MaxPerMinute int `json:",omitempty" jsonschema:"title=Max Alerts Per Minute,example=30,default=10"`
+	// Headers added to each request.
+	Headers map[string]string `json:",omitempty" jsonschema:"title=Headers"`
+	// Timeout of each request as a Go duration.
+	Timeout string `json:",omitempty" jsonschema:"title=Timeout,example=30s,default=10s"`
+	// Batch defines how the alerts are queued and retried.
+	Batch   BatchOptions `json:",omitempty"`
+	batcher *batcher[[]byte]
+	limiter *webhookLimiter
+}
+
+func (wo *WebhookOutput) enabled() bool {
+	return !wo.Disable && wo.URL != ""
+}
+
+func (wo *WebhookOutput) Validate() (err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: validate config [webhook output]: %w", err)
+		}
+	}()
+
+	if !wo.enabled() {
+		return nil
+	}
+	if wo.Level == "" {
+		wo.Level = "WARN"
+	}
+	if wo.Format == "" {
+		wo.Format = FormatTemplate
+		if wo.Template == "" {
+			wo.Template = DefaultWebhookTemplate
+		}
+	}
+	if err = wo.OutputHandler.Validate(); err != nil {
+		return err
+	}
+	if wo.Format == FormatMsgPack {
+		return fmt.Errorf("invalid format [%s], only text based formats are supported", wo.Format)
+	}
+	if err = validateHTTPURL(wo.URL); err != nil {
+		return err
+	}
+	if wo.MaxPerMinute < 0 {
+		return fmt.Errorf("invalid MaxPerMinute [%d]", wo.MaxPerMinute)
+	}
+	for name, value := range map[string]string{"DedupWindow": wo.DedupWindow, "Timeout": wo.Timeout} {
+		if _, err = parseDuration(value, 0); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+
+	return wo.Batch.Validate()
+}
+
+func (wo *WebhookOutput) GetHandler() (handler slog.Handler, err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("nmcslog: get handler [webhook output]: %w", err)
+		}
+	}()
+
+	if !wo.enabled() {
+		return nil, fmt.Errorf("[%s] %w", wo.URL, ErrHandlerDisabled)
+	}
+
+	encoder, err := wo.formatEncoder()
+	if err != nil {
+		return nil, err
+	}
+
+	if wo.batcher == nil {
+		settings, err := wo.Batch.settings()
+		if err != nil {
+			return nil, err
+		}
+		timeout, err := parseDuration(wo.Timeout, DefaultHTTPTimeout)
+		if err != nil {
+			return nil, err
+		}
+		window, err := parseDuration(wo.DedupWindow, DefaultWebhookDedupWindow)
+		if err != nil {
+			return nil, err
+		}
+
+		client := &http.Client{Timeout: timeout}
+		wo.batcher = newBatcher(settings, func(batch [][]byte) error {
+			// Each alert is posted as its own message.
+			return sendEach(batch, func(payload []byte) error {
+				return sendHTTP(client, http.MethodPost, wo.URL, "application/json", wo.Headers, payload, false)
+			})
+		})
+		wo.limiter = &webhookLimiter{
+			window:       window,
+			maxPerMinute: wo.MaxPerMinute,
+			keys:         map[string]*webhookKey{},
+		}
+		if wo.limiter.maxPerMinute == 0 {
+			wo.limiter.maxPerMinute = DefaultWebhookMaxPerMinute
+		}
+	}
+
+	writer := &webhookWriter{
+		output:  wo,
+		encode:  encoder,
+		limiter: wo.limiter,
+	}
+	handler, err = wo.entryHandler(writer)
+	if err != nil {
+		return nil, fmt.Errorf("getting handler [webhook]: %w", err)
+	}
+
+	return handler, nil
+}
+
+// payload returns the webhook message of the alert text.
+func (wo *WebhookOutput) payload(text string) ([]byte, error) {
+	message := map[string]string{"text": text}
+	if wo.Channel != "" {
+		message["channel"] = wo.Channel
+	}
+	if wo.Username != "" {
+		message["username"] = wo.Username
+	}
+
+	return json.Marshal(message)
+}
+
+// Stats returns the delivery statistics of the output, counting the alerts.
+func (wo *WebhookOutput) Stats() BatchStats {
+	if wo.batcher == nil {
+		return BatchStats{}
+	}
+
+	return wo.batcher.stats()
+}
+
+// Close posts a summary of the alerts suppressed since the last alert, sends the pending alerts and stops the output.
+func (wo *WebhookOutput) Close() error {
+	if wo.batcher == nil {
+		return nil
+	}
+
+	if similar, limited := wo.limiter.flush(); similar > 0 || limited > 0 {
+		if payload, err := wo.payload(webhookSuppressed(similar, limited)); err == nil {
+			_ = wo.batcher.add(payload)
+		}
+	}
+
+	return wo.batcher.Close()
+}
+
+// webhookSuppressed returns the summary of the records suppressed as similar alerts and by the rate limit.
+func webhookSuppressed(similar, limited int) string {
+	var parts []string
+	if similar > 0 {
+		parts = append(parts, webhookAlerts(similar, "similar alert")+" suppressed")
+	}
+	if limited > 0 {
+		parts = append(parts, webhookAlerts(limited, "alert")+" rate limited")
+	}
+
+	return "_" + strings.Join(parts, ", ") + "_"
+}
+
+// webhookAlerts returns the number followed by the noun, in the plural if needed.
+func webhookAlerts(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// webhookKey is the deduplication state of the records with the same level and message.
+type webhookKey struct {
+	since      time.Time
+	suppressed int
+}
+
+// webhookLimiter decides which records are sent as alerts, counting the suppressed ones.
+type webhookLimiter struct {
+	mu           sync.Mutex
+	window       time.Duration
+	maxPerMinute int
+	keys         map[string]*webhookKey
+	minute       time.Time
+	sent         int
+	// pending counts the suppressed records of the expired keys not reported yet.
+	pending int
+	// limited counts the records suppressed by the rate limit not reported yet.
+	limited int
+}
+
+// allow reports whether the record is sent, along with the number of records suppressed as similar alerts and by
+// the rate limit to report with it.
+func (wl *webhookLimiter) allow(level slog.Level, message string, now time.Time) (allowed bool, similar, limited int) {
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+
+	if now.Sub(wl.minute) >= time.Minute {
+		wl.minute, wl.sent = now, 0
+		// Keep the number of keys bounded by the unique records within the window.
+		for key, state := range wl.keys {
+			if now.Sub(state.since) >= wl.window {
+				wl.pending += state.suppressed
+				delete(wl.keys, key)
+			}
+		}
+	}
+
+	key := levelName(level) + "\x00" + message
+	state := wl.keys[key]
+	if state != nil && now.Sub(state.since) < wl.window {
+		state.suppressed++
+		return false, 0, 0
+	}
+	if wl.sent >= wl.maxPerMinute {
+		// The key is only recorded once its alert is sent, so a record first seen during a burst still gets an alert.
+		wl.limited++
+		return false, 0, 0
+	}
+
+	similar, limited = wl.pending, wl.limited
+	if state != nil {
+		similar += state.suppressed
+	}
+	wl.keys[key] = &webhookKey{since: now}
+	wl.sent++
+	wl.pending, wl.limited = 0, 0
+
+	return true, similar, limited
+}
+
+// flush returns and resets the number of suppressed records not reported yet.
+func (wl *webhookLimiter) flush() (similar, limited int) {
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+
+	similar = wl.pending
+	for _, state := range wl.keys {
+		similar += state.suppressed
+		state.suppressed = 0
+	}
+	limited = wl.limited
+	wl.pending, wl.limited = 0, 0
+
+	return similar, limited
+}
+
+// webhookWriter posts the entries allowed by the limiter as alerts.
+type webhookWriter struct {
+	output  *WebhookOutput
+	encode  entryEncoder
+	limiter *webhookLimiter
+}
+
+func (ww *webhookWriter) writeEntry(e *entry) error {
+	allowed, similar, limited := ww.limiter.allow(e.Level, e.Message, time.Now())
+	if !allowed {
+		return nil
+	}
+
+	buf := &bytes.Buffer{}
+	if err := ww.encode(buf, e); err != nil {
+		return fmt.Errorf("encoding alert: %w", err)
+	}
+	text := string(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+	if similar > 0 || limited > 0 {
+		text += "\n" + webhookSuppressed(similar, limited)
+	}
+
+	payload, err := ww.output.payload(text)
+	if err != nil {
+		return fmt.Errorf("encoding alert: %w", err)
+	}
+
+	return ww.output.batcher.add(payload)
+}
@@ -0,0 +1,198 @@
+package nmcslog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeWebhook is an incoming webhook stand-in collecting the messages.
+type fakeWebhook struct {
+	mu       sync.Mutex
+	messages []map[string]string
+}
+
+func (fw *fakeWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	message := map[string]string{}
+	if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	fw.messages = append(fw.messages, message)
+	_, _ = w.Write([]byte("ok"))
+}
+
+func TestWebhookOutput(t *testing.T) {
+	webhook := &fakeWebhook{}
+	server := httptest.NewServer(webhook)
+	defer server.Close()
+
+	config := Config{
+		Console: ConsoleOutput{OutputHandler: OutputHandler{Disable: true}},
+		File:    FileOutput{OutputHandler: OutputHandler{Disable: true}},
+		Webhook: WebhookOutput{
+			URL:         server.URL,
+			Channel:     "#alerts",
+			DedupWindow: "1h",
+		},
+	}
+	logger, err := GetConfiguredLogger(&config)
+	if err != nil {
+		t.Fatalf("GetConfiguredLogger() error = %v", err)
+	}
+
+	logger.Info("not an alert")
+	for range 3 {
+		logger.Error("database unreachable", "host", "db-01")
+	}
+	logger.Warn("disk almost full")
+	if err = config.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	webhook.mu.Lock()
+	defer webhook.mu.Unlock()
+
+	want := []string{
+		"*ERROR* database unreachable `host=db-01`",
+		"*WARN* disk almost full",
+		"_2 similar alerts suppressed_",
+	}
+	if len(webhook.messages) != len(want) {
+		t.Fatalf("messages = %v, want %d", webhook.messages, len(want))
+	}
+	for i, message := range webhook.messages {
+		if message["text"] != want[i] || message["channel"] != "#alerts" {
+			t.Errorf("message %d = %v, want text %q", i, message, want[i])
+		}
+	}
+}
+
+func TestWebhookLimiter(t *testing.T) {
+	start := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	type alert struct {
+		message     string
+		after       time.Duration
+		wantAllowed bool
+		wantSimilar int
+		wantLimited int
+	}
+	tests := []struct {
+		name             string
+		maxPerMinute     int
+		alerts           []alert
+		wantFlushSimilar int
+		wantFlushLimited int
+	}{
+		{
+			name:         "similar alerts after the window",
+			maxPerMinute: 10,
+			alerts: []alert{
+				{message: "a", wantAllowed: true},
+				{message: "a", after: time.Second},
+				{message: "a", after: 2 * time.Second},
+				{message: "a", after: 5 * time.Minute, wantAllowed: true, wantSimilar: 2},
+			},
+		},
+		{
+			name:         "rate limited",
+			maxPerMinute: 2,
+			alerts: []alert{
+				{message: "a", wantAllowed: true},
+				{message: "b", wantAllowed: true},
+				{message: "c"},
+				{message: "a"},
+				{message: "d"},
+				{message: "e", after: time.Minute, wantAllowed: true, wantLimited: 2},
+			},
+			wantFlushSimilar: 1,
+		},
+		{
+			name:         "first seen during a burst",
+			maxPerMinute: 1,
+			alerts: []alert{
+				{message: "a", wantAllowed: true},
+				{message: "b"},
+				{message: "b", after: time.Second},
+				{message: "b", after: time.Minute, wantAllowed: true, wantLimited: 2},
+				{message: "b", after: time.Minute + time.Second},
+			},
+			wantFlushSimilar: 1,
+		},
+		{
+			name:         "flushed on close",
+			maxPerMinute: 1,
+			alerts: []alert{
+				{message: "a", wantAllowed: true},
+				{message: "a"},
+				{message: "b"},
+			},
+			wantFlushSimilar: 1,
+			wantFlushLimited: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := &webhookLimiter{window: 5 * time.Minute, maxPerMinute: tt.maxPerMinute, keys: map[string]*webhookKey{}}
+			for i, a := range tt.alerts {
+				allowed, similar, limited := limiter.allow(LevelError, a.message, start.Add(a.after))
+				if allowed != a.wantAllowed || similar != a.wantSimilar || limited != a.wantLimited {
+					t.Errorf("alert %d allow() = %v, %d, %d, want %v, %d, %d",
+						i, allowed, similar, limited, a.wantAllowed, a.wantSimilar, a.wantLimited)
+				}
+			}
+			if similar, limited := limiter.flush(); similar != tt.wantFlushSimilar || limited != tt.wantFlushLimited {
+				t.Errorf("flush() = %d, %d, want %d, %d", similar, limited, tt.wantFlushSimilar, tt.wantFlushLimited)
+			}
+		})
+	}
+}
+
+func TestWebhookSuppressed(t *testing.T) {
+	for _, tt := range []struct {
+		similar, limited int
+		want             string
+	}{
+		{similar: 1, want: "_1 similar alert suppressed_"},
+		{limited: 3, want: "_3 alerts rate limited_"},
+		{similar: 2, limited: 1, want: "_2 similar alerts suppressed, 1 alert rate limited_"},
+	} {
+		if got := webhookSuppressed(tt.similar, tt.limited); got != tt.want {
+			t.Errorf("webhookSuppressed(%d, %d) = %q, want %q", tt.similar, tt.limited, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookOutput_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  WebhookOutput
+		wantErr bool
+	}{
+		{name: "disabled without URL", output: WebhookOutput{MaxPerMinute: -1}},
+		{name: "defaults", output: WebhookOutput{URL: "https://hooks.slack.com/services/T0/B0/X"}},
+		{name: "json format", output: WebhookOutput{URL: "https://chat.example.com/hooks/x", OutputHandler: OutputHandler{Format: FormatJSON}}},
+		{name: "invalid template", output: WebhookOutput{URL: "https://chat.example.com/hooks/x", OutputHandler: OutputHandler{Template: "{{.Missing"}}, wantErr: true},
+		{name: "negative cap", output: WebhookOutput{URL: "https://chat.example.com/hooks/x", MaxPerMinute: -1}, wantErr: true},
+		{name: "invalid window", output: WebhookOutput{URL: "https://chat.example.com/hooks/x", DedupWindow: "soon"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.output.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && tt.output.URL != "" && strings.ToUpper(tt.output.Level) != "WARN" {
+				t.Errorf("Level = %q, want WARN by default", tt.output.Level)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package nmcslog
+
+import "encoding/binary"
+
+// A minimal Protocol Buffers encoder covering the messages of the push and export APIs, so the network outputs do
+// not depend on generated code. Scalar fields holding the zero value are omitted as in proto3.
+// https://protobuf.dev/programming-guides/encoding/
+
+// Protocol Buffers wire types.
+const (
+	protoVarint = 0
+	protoI64    = 1
+	protoLen    = 2
+	protoI32    = 5
+)
+
+func appendProtoTag(b []byte, field int, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field)<<3|uint64(wireType))
+}
+
+func appendProtoUint(b []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = appendProtoTag(b, field, protoVarint)
+
+	return binary.AppendUvarint(b, v)
+}
+
+// appendProtoInt appends an int32 or int64 field, negative values are encoded as 10 byte varints.
+func appendProtoInt(b []byte, field int, v int64) []byte {
+	return appendProtoUint(b, field, uint64(v))
+}
+
+// appendProtoFixed64 appends a fixed64 field, also used for the timestamps of OTLP.
+func appendProtoFixed64(b []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = appendProtoTag(b, field, protoI64)
+
+	return binary.LittleEndian.AppendUint64(b, v)
+}
+
+// appendProtoFixed32 appends a fixed32 field, such as the flags of an OTLP log record.
+func appendProtoFixed32(b []byte, field int, v uint32) []byte {
+	if v == 0 {
+		return b
+	}
+	b = appendProtoTag(b, field, protoI32)
+
+	return binary.LittleEndian.AppendUint32(b, v)
+}
+
+func appendProtoBytes(b []byte, field int, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = appendProtoTag(b, field, protoLen)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+
+	return append(b, v...)
+}
+
+func appendProtoString(b []byte, field int, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = appendProtoTag(b, field, protoLen)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+
+	return append(b, v...)
+}
+
+// appendProtoMessage appends an embedded message field, unlike the scalar fields an empty message is still written
+// as its presence can be meaningful.
+func appendProtoMessage(b []byte, field int, message []byte) []byte {
+	b = appendProtoTag(b, field, protoLen)
+	b = binary.AppendUvarint(b, uint64(len(message)))
+
+	return append(b, message...)
+}
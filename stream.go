@@ -0,0 +1,244 @@
+package nmcslog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// OverflowDropNewest drops the records written while the buffer is full.
+	OverflowDropNewest = "DROP_NEWEST"
+	// OverflowDropOldest drops the oldest buffered record to make room for each record written while the buffer is full.
+	OverflowDropOldest = "DROP_OLDEST"
+	// OverflowBlock blocks the logging calls until the buffer has room again.
+	OverflowBlock = "BLOCK"
+)
+
+// validateOverflow checks the overflow policy of a stream output.
+func validateOverflow(policy string) error {
+	switch strings.ToUpper(policy) {
+	case "", OverflowDropNewest, OverflowDropOldest, OverflowBlock:
+		return nil
+	default:
+		return fmt.Errorf("invalid overflow policy [%s]", policy)
+	}
+}
+
+// streamSettings returns the buffer size and the backoff of a stream output with the defaults applied.
+func streamSettings(bufferSize int, initialBackoff, maxBackoff string) (s batchSettings, err error) {
+	s.bufferSize = bufferSize
+	if s.bufferSize == 0 {
+		s.bufferSize = DefaultBatchBufferSize
+	}
+	if s.initialBackoff, err = parseDuration(initialBackoff, DefaultInitialBackoff); err != nil {
+		return s, err
+	}
+	if s.maxBackoff, err = parseDuration(maxBackoff, DefaultMaxBackoff); err != nil {
+		return s, err
+	}
+
+	return s, nil
+}
+
+// streamWriter buffers the written records and writes them to a connection from a background goroutine, such as a
+// socket or the stdin of a command. A failed connection is reopened with an exponential backoff, keeping the records
+// buffered meanwhile.
+type streamWriter struct {
+	// name identifies the stream in the errors.
+	name string
+	// open returns a new connection, its writes are given the timeout as deadline if it supports them.
+	open     func() (io.WriteCloser, error)
+	settings batchSettings
+	overflow string
+	timeout  time.Duration
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending [][]byte
+	// inflight is the record taken from the buffer by the background goroutine, until it is delivered.
+	inflight    []byte
+	hasInflight bool
+	closed      bool
+	done        chan struct{}
+	stopped     chan struct{}
+	conn        io.WriteCloser
+
+	records  atomic.Uint64
+	reopened atomic.Uint64
+	dropped  atomic.Uint64
+}
+
+func newStreamWriter(
+	name string, open func() (io.WriteCloser, error), settings batchSettings, overflow string, timeout time.Duration,
+) *streamWriter {
+	sw := &streamWriter{
+		name:     name,
+		open:     open,
+		settings: settings,
+		overflow: strings.ToUpper(overflow),
+		timeout:  timeout,
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	if sw.overflow == "" {
+		sw.overflow = OverflowDropNewest
+	}
+	sw.cond = sync.NewCond(&sw.mu)
+	go sw.run()
+
+	return sw
+}
+
+func (sw *streamWriter) Write(p []byte) (int, error) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	for !sw.closed && len(sw.pending) >= sw.settings.bufferSize {
+		switch sw.overflow {
+		case OverflowBlock:
+			sw.cond.Wait()
+			continue
+		case OverflowDropOldest:
+			sw.pending = sw.pending[1:]
+			sw.dropped.Add(1)
+			continue
+		}
+		sw.dropped.Add(1)
+		return 0, fmt.Errorf("buffer of %d records full", sw.settings.bufferSize)
+	}
+	if sw.closed {
+		sw.dropped.Add(1)
+		return 0, fmt.Errorf("[%s] stream closed", sw.name)
+	}
+
+	sw.pending = append(sw.pending, bytes.Clone(p))
+	sw.cond.Broadcast()
+
+	return len(p), nil
+}
+
+// next waits for the next record and takes it from the buffer, so the overflow policy cannot drop it while it is
+// written. The same record is returned until it is marked as sent, it returns false once closed with an empty buffer.
+func (sw *streamWriter) next() ([]byte, bool) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	if sw.hasInflight {
+		return sw.inflight, true
+	}
+	for len(sw.pending) == 0 && !sw.closed {
+		sw.cond.Wait()
+	}
+	if len(sw.pending) == 0 {
+		return nil, false
+	}
+
+	sw.inflight, sw.hasInflight = sw.pending[0], true
+	sw.pending[0] = nil
+	sw.pending = sw.pending[1:]
+	if len(sw.pending) == 0 {
+		sw.pending = nil
+	}
+	sw.cond.Broadcast()
+
+	return sw.inflight, true
+}
+
+// sent releases the delivered record.
+func (sw *streamWriter) sent() {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	sw.inflight, sw.hasInflight = nil, false
+}
+
+func (sw *streamWriter) run() {
+	defer close(sw.stopped)
+
+	failures := 0
+	for {
+		record, ok := sw.next()
+		if !ok {
+			return
+		}
+
+		err := sw.send(record)
+		if err == nil {
+			failures = 0
+			sw.records.Add(1)
+			sw.sent()
+			continue
+		}
+
+		sw.reopened.Add(1)
+		select {
+		case <-sw.done:
+			// Closed while the connection is down, the remaining records are dropped.
+			return
+		case <-time.After(sw.settings.backoff(failures)):
+		}
+		failures++
+	}
+}
+
+// send writes the record, opening the connection first if needed. The connection is closed after a failure.
+func (sw *streamWriter) send(record []byte) (err error) {
+	if sw.conn == nil {
+		if sw.conn, err = sw.open(); err != nil {
+			return err
+		}
+	}
+
+	if conn, ok := sw.conn.(interface{ SetWriteDeadline(time.Time) error }); ok && sw.timeout > 0 {
+		err = conn.SetWriteDeadline(time.Now().Add(sw.timeout))
+	}
+	if err == nil {
+		_, err = sw.conn.Write(record)
+	}
+	if err != nil {
+		_ = sw.conn.Close()
+		sw.conn = nil
+	}
+
+	return err
+}
+
+// Close stops accepting records, writes the buffered ones unless the connection is down and closes the connection.
+func (sw *streamWriter) Close() error {
+	sw.mu.Lock()
+	if sw.closed {
+		sw.mu.Unlock()
+		return nil
+	}
+	sw.closed = true
+	sw.cond.Broadcast()
+	sw.mu.Unlock()
+
+	close(sw.done)
+	<-sw.stopped
+
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	var err error
+	if undelivered := len(sw.pending); undelivered > 0 || sw.hasInflight {
+		if sw.hasInflight {
+			undelivered++
+		}
+		sw.dropped.Add(uint64(undelivered))
+		err = fmt.Errorf("dropped %d buffered records [%s]", undelivered, sw.name)
+		sw.pending, sw.inflight, sw.hasInflight = nil, nil, false
+	}
+	if sw.conn != nil {
+		err = errors.Join(err, sw.conn.Close())
+		sw.conn = nil
+	}
+
+	return err
+}
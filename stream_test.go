@@ -0,0 +1,96 @@
+package nmcslog
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStreamWriter_Overflow(t *testing.T) {
+	tests := []struct {
+		overflow    string
+		wantPending []string
+		wantDropped uint64
+		wantErr     bool
+	}{
+		{overflow: OverflowDropNewest, wantPending: []string{"a", "b"}, wantDropped: 1, wantErr: true},
+		{overflow: OverflowDropOldest, wantPending: []string{"b", "c"}, wantDropped: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.overflow, func(t *testing.T) {
+			sw := &streamWriter{settings: batchSettings{bufferSize: 2}, overflow: tt.overflow}
+			sw.cond = sync.NewCond(&sw.mu)
+
+			var err error
+			for _, record := range []string{"a", "b", "c"} {
+				_, err = sw.Write([]byte(record))
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Write() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var pending []string
+			for _, record := range sw.pending {
+				pending = append(pending, string(record))
+			}
+			if strings.Join(pending, ",") != strings.Join(tt.wantPending, ",") || sw.dropped.Load() != tt.wantDropped {
+				t.Errorf("pending = %v, dropped %d, want %v, dropped %d", pending, sw.dropped.Load(), tt.wantPending, tt.wantDropped)
+			}
+		})
+	}
+
+	t.Run(OverflowBlock, func(t *testing.T) {
+		sw := &streamWriter{settings: batchSettings{bufferSize: 1}, overflow: OverflowBlock}
+		sw.cond = sync.NewCond(&sw.mu)
+
+		_, _ = sw.Write([]byte("a"))
+		written := make(chan error)
+		go func() {
+			_, err := sw.Write([]byte("b"))
+			written <- err
+		}()
+
+		select {
+		case <-written:
+			t.Fatal("Write() returned while the buffer is full")
+		case <-time.After(50 * time.Millisecond):
+		}
+		if record, _ := sw.next(); string(record) != "a" {
+			t.Fatalf("next() = %q, want a", record)
+		}
+		if err := <-written; err != nil || string(sw.pending[0]) != "b" {
+			t.Errorf("Write() error = %v, pending %q, want b", err, sw.pending)
+		}
+	})
+}
+
+func TestStreamWriter_DropOldestInflight(t *testing.T) {
+	sw := &streamWriter{settings: batchSettings{bufferSize: 2}, overflow: OverflowDropOldest}
+	sw.cond = sync.NewCond(&sw.mu)
+
+	var delivered []string
+	for _, record := range []string{"a", "b"} {
+		_, _ = sw.Write([]byte(record))
+	}
+	// a is being written while the buffer overflows, so b is the oldest record dropped.
+	inflight, _ := sw.next()
+	for _, record := range []string{"c", "d"} {
+		_, _ = sw.Write([]byte(record))
+	}
+	delivered = append(delivered, string(inflight))
+	sw.sent()
+
+	sw.closed = true
+	for {
+		record, ok := sw.next()
+		if !ok {
+			break
+		}
+		delivered = append(delivered, string(record))
+		sw.sent()
+	}
+	if strings.Join(delivered, ",") != "a,c,d" || sw.dropped.Load() != 1 {
+		t.Errorf("delivered = %v, dropped %d, want a,c,d and 1 dropped", delivered, sw.dropped.Load())
+	}
+}
@@ -0,0 +1,133 @@
+package nmcslog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+)
+
+// DefaultSubscriptionBufferSize is the default number of records buffered per in-process subscriber.
+const DefaultSubscriptionBufferSize = 256
+
+// subscriptionHubMu guards the creation of the subscription hubs, the Config being usable without a constructor.
+var subscriptionHubMu sync.Mutex
+
+// subscriptionHub returns the hub of the in-process subscribers, creating it on first use.
+func (c *Config) subscriptionHub() *subscriberHub[slog.Record] {
+	subscriptionHubMu.Lock()
+	defer subscriptionHubMu.Unlock()
+
+	if c.subscriptions == nil {
+		c.subscriptions = newSubscriberHub(encodeSubscription, slog.Record.Clone)
+	}
+
+	return c.subscriptions
+}
+
+// encodeSubscription rebuilds the record with the attributes of its logger, each subscriber getting its own copy as
+// adding attributes to a shared record is unsafe.
+func encodeSubscription(e *entry, _ *LogEntry) (slog.Record, error) {
+	record := slog.NewRecord(e.Time, e.Level, e.Message, e.PC)
+	record.AddAttrs(e.Attrs...)
+
+	return record, nil
+}
+
+// subscriptionTee wraps the handler of the outputs to also send the records to the in-process subscribers.
+func (c *Config) subscriptionTee(handler slog.Handler) slog.Handler {
+	hub := c.subscriptionHub()
+
+	return &subscriptionTee{Handler: handler, subscriptions: newEntryHandler(hub, nil, false), hub: hub}
+}
+
+// Subscribe returns a channel receiving the records matching the filter, so in-process components such as a UI or
+// a metrics aggregator can consume them. The records are delivered by the logger returned by GetHandlers, independently
+// of the levels of the outputs, and hold the attributes and groups of the logger they were logged with. A slow
+// subscriber misses the records once its buffer of bufSize records is full rather than blocking the logging calls,
+// bufSize defaults to DefaultSubscriptionBufferSize. The cancel function removes the subscriber and closes the
+// channel, it can be called more than once. Close closes the channels of all the subscribers.
+func (c *Config) Subscribe(filter RecordFilter, bufSize int) (records <-chan slog.Record, cancel func()) {
+	if bufSize <= 0 {
+		bufSize = DefaultSubscriptionBufferSize
+	}
+
+	hub := c.subscriptionHub()
+	subscriber := hub.subscribe(filter, bufSize)
+	if subscriber == nil {
+		closed := make(chan slog.Record)
+		close(closed)
+		return closed, func() {}
+	}
+	var once sync.Once
+
+	return subscriber.records, func() {
+		once.Do(func() { hub.unsubscribe(subscriber) })
+	}
+}
+
+// SubscriptionStats are the delivery statistics of an in-process subscriber.
+type SubscriptionStats struct {
+	// Filter is the filter the subscriber was added with.
+	Filter RecordFilter
+	// Records is the number of records sent to the subscriber.
+	Records uint64
+	// DroppedRecords is the number of records missed by the subscriber because its buffer was full.
+	DroppedRecords uint64
+}
+
+// SubscriptionStats returns the delivery statistics of the in-process subscribers, in the order they subscribed.
+func (c *Config) SubscriptionStats() []SubscriptionStats {
+	subscribers := c.subscriptionHub().list()
+	stats := make([]SubscriptionStats, 0, len(subscribers))
+	for _, subscriber := range subscribers {
+		stats = append(stats, SubscriptionStats{
+			Filter:         subscriber.filter,
+			Records:        subscriber.delivered.Load(),
+			DroppedRecords: subscriber.dropped.Load(),
+		})
+	}
+
+	return stats
+}
+
+// subscriptionTee passes the records to the handler of the outputs, and to the in-process subscribers while one of
+// them is interested in their level. Without subscribers, the records go straight through to the outputs.
+type subscriptionTee struct {
+	slog.Handler
+	subscriptions slog.Handler
+	hub           *subscriberHub[slog.Record]
+}
+
+func (st *subscriptionTee) Enabled(ctx context.Context, level slog.Level) bool {
+	return st.hub.enabled(level) || st.Handler.Enabled(ctx, level)
+}
+
+func (st *subscriptionTee) Handle(ctx context.Context, r slog.Record) error {
+	var err error
+	if st.hub.enabled(r.Level) {
+		// The subscribers get a copy as the handler of the outputs may add attributes to the record.
+		err = st.subscriptions.Handle(ctx, r.Clone())
+	}
+	if st.Handler.Enabled(ctx, r.Level) {
+		err = errors.Join(err, st.Handler.Handle(ctx, r))
+	}
+
+	return err
+}
+
+func (st *subscriptionTee) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &subscriptionTee{
+		Handler:       st.Handler.WithAttrs(attrs),
+		subscriptions: st.subscriptions.WithAttrs(attrs),
+		hub:           st.hub,
+	}
+}
+
+func (st *subscriptionTee) WithGroup(name string) slog.Handler {
+	return &subscriptionTee{
+		Handler:       st.Handler.WithGroup(name),
+		subscriptions: st.subscriptions.WithGroup(name),
+		hub:           st.hub,
+	}
+}
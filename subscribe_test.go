@@ -0,0 +1,186 @@
+package nmcslog
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	slogmulti "github.com/samber/slog-multi"
+)
+
+func newSubscribeTestConfig(t *testing.T) (*Config, *slog.Logger) {
+	t.Helper()
+
+	config := &Config{
+		Console: ConsoleOutput{OutputHandler: OutputHandler{LogLevel: LogLevel{Level: "ERROR"}, Format: FormatText}},
+		File:    FileOutput{OutputHandler: OutputHandler{Disable: true}},
+	}
+	logger, err := GetConfiguredLogger(config)
+	if err != nil {
+		t.Fatalf("GetConfiguredLogger() error = %v", err)
+	}
+	t.Cleanup(func() { _ = config.Close() })
+
+	return config, logger
+}
+
+// recordAttrs returns the attributes of the record, the keys of grouped attributes are joined with a dot.
+func recordAttrs(record slog.Record) map[string]string {
+	attrs := map[string]string{}
+	var add func(prefix string, a slog.Attr)
+	add = func(prefix string, a slog.Attr) {
+		if a.Value.Kind() == slog.KindGroup {
+			for _, member := range a.Value.Group() {
+				add(prefix+a.Key+".", member)
+			}
+			return
+		}
+		attrs[prefix+a.Key] = a.Value.String()
+	}
+	record.Attrs(func(a slog.Attr) bool {
+		add("", a)
+		return true
+	})
+
+	return attrs
+}
+
+func TestConfig_Subscribe(t *testing.T) {
+	config, logger := newSubscribeTestConfig(t)
+
+	ctx := context.Background()
+	if logger.Enabled(ctx, slog.LevelDebug) {
+		t.Error("Enabled() = true without subscribers")
+	}
+	records, cancel := config.Subscribe(RecordFilter{Attrs: map[string]string{"request.path": "/orders"}}, 0)
+	defer cancel()
+	if !logger.Enabled(ctx, slog.LevelDebug) {
+		t.Error("Enabled() = false, want the levels of the subscriber regardless of the outputs")
+	}
+
+	requestLogger := logger.With("service", "api").WithGroup("request")
+	requestLogger.Debug("cache miss", "path", "/cart")
+	requestLogger.Debug("cache miss", "path", "/orders", "key", "user:7")
+
+	select {
+	case record := <-records:
+		attrs := recordAttrs(record)
+		if record.Message != "cache miss" || record.Level != slog.LevelDebug || record.PC == 0 ||
+			attrs["service"] != "api" || attrs["request.key"] != "user:7" {
+			t.Errorf("record = %v %v, want the matching record with the logger attributes", record, attrs)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no record received")
+	}
+	select {
+	case record := <-records:
+		t.Errorf("record = %v, want no other record", record)
+	default:
+	}
+
+	stats := config.SubscriptionStats()
+	if len(stats) != 1 || stats[0].Records != 1 || stats[0].DroppedRecords != 0 {
+		t.Errorf("SubscriptionStats() = %+v", stats)
+	}
+}
+
+func TestConfig_Subscribe_SingleOutput(t *testing.T) {
+	config, logger := newSubscribeTestConfig(t)
+
+	// The lone console handler is used without a fanout, the subscribers only being added by the tee.
+	tee, ok := logger.Handler().(*subscriptionTee)
+	if !ok {
+		t.Fatalf("Handler() = %T, want the subscription tee", logger.Handler())
+	}
+	if _, ok = tee.Handler.(*slogmulti.FanoutHandler); ok {
+		t.Error("single output handler wrapped in a fanout")
+	}
+	if !logger.Enabled(context.Background(), slog.LevelError) || logger.Enabled(context.Background(), slog.LevelWarn) {
+		t.Error("Enabled() does not follow the console level without subscribers")
+	}
+
+	records, cancel := config.Subscribe(RecordFilter{}, 0)
+	defer cancel()
+	logger.With("service", "api").Warn("below the console level")
+	select {
+	case record := <-records:
+		if record.Message != "below the console level" || recordAttrs(record)["service"] != "api" {
+			t.Errorf("record = %v", record)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no record received")
+	}
+}
+
+func TestConfig_Subscribe_SlowSubscriber(t *testing.T) {
+	config, logger := newSubscribeTestConfig(t)
+
+	slow, cancelSlow := config.Subscribe(RecordFilter{Level: slog.LevelInfo}, 1)
+	defer cancelSlow()
+	fast, cancelFast := config.Subscribe(RecordFilter{}, 10)
+	defer cancelFast()
+	if !logger.Enabled(context.Background(), LevelTrace) {
+		t.Error("Enabled() = false, want the lowest level of the subscribers")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 3 {
+			logger.Info("not read")
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("logging blocked by the slow subscriber")
+	}
+
+	stats := config.SubscriptionStats()
+	if len(stats) != 2 || stats[0].Records != 1 || stats[0].DroppedRecords != 2 ||
+		stats[1].Records != 3 || stats[1].DroppedRecords != 0 {
+		t.Errorf("SubscriptionStats() = %+v", stats)
+	}
+	if len(slow) != 1 || len(fast) != 3 {
+		t.Errorf("buffered = %d and %d, want 1 and 3", len(slow), len(fast))
+	}
+}
+
+func TestConfig_Subscribe_Cancel(t *testing.T) {
+	config, logger := newSubscribeTestConfig(t)
+
+	records, cancel := config.Subscribe(RecordFilter{Level: slog.LevelWarn}, 0)
+	other, cancelOther := config.Subscribe(RecordFilter{}, 0)
+	defer cancelOther()
+
+	cancel()
+	cancel()
+	if _, ok := <-records; ok {
+		t.Error("channel open after cancel")
+	}
+	if stats := config.SubscriptionStats(); len(stats) != 1 || stats[0].Filter.Level != nil {
+		t.Errorf("SubscriptionStats() = %+v, want the other subscriber", stats)
+	}
+	logger.Warn("after cancel")
+
+	if err := config.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	var received int
+	for range other {
+		received++
+	}
+	if received != 1 {
+		t.Errorf("received %d records before Close, want 1", received)
+	}
+	if logger.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("Enabled() = true after Close")
+	}
+
+	closed, cancelClosed := config.Subscribe(RecordFilter{}, 0)
+	defer cancelClosed()
+	if _, ok := <-closed; ok {
+		t.Error("channel open when subscribing after Close")
+	}
+}
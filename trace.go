@@ -0,0 +1,40 @@
+package nmcslog
+
+import (
+	"context"
+)
+
+const (
+	traceContext ctxKey = "trace_context"
+)
+
+// TraceContext identifies the trace and span a log record belongs to.
+type TraceContext struct {
+	// TraceID is the hex encoded 16 byte trace ID.
+	TraceID string
+	// SpanID is the hex encoded 8 byte span ID.
+	SpanID string
+	// Sampled reports whether the trace is sampled.
+	Sampled bool
+}
+
+// TraceFromContext extracts the trace of a log record from its context, it is used by the formats and outputs that
+// support tracing. The default reads the trace stored by ContextWithTrace, replace it to integrate a tracing library
+// such as OpenTelemetry.
+var TraceFromContext = func(ctx context.Context) (TraceContext, bool) {
+	if ctx == nil {
+		return TraceContext{}, false
+	}
+	trace, ok := ctx.Value(traceContext).(TraceContext)
+	return trace, ok && trace.TraceID != ""
+}
+
+// ContextWithTrace adds the trace to the provided context so that it will be included in any Record created with
+// such context by the formats and outputs that support tracing.
+func ContextWithTrace(parent context.Context, trace TraceContext) context.Context {
+	if parent == nil {
+		parent = context.Background()
+	}
+
+	return context.WithValue(parent, traceContext, trace)
+}